@@ -22,6 +22,56 @@ push    - makes a new top level of stack and COPIES the previous top
         A POINTER TO THE OLD TOP
 
 pop     - pops off the top of the stack (doesn't return anything)
+        Each pop must match a push earlier in the same group (or
+        other block), and each push in a block must be popped
+        before its end. Pushes left at the end of the script are
+        warned about, since they are usually a forgotten pop.
+
+
+Groups
+------
+group name [knob]   - starts a named group of commands. The group is drawn
+                    in a copy of the current coordinate system, so its
+                    transformations compose with those of its parents but
+                    do not leak out of it. If a knob is given, the group
+                    is hidden in frames where the knob is 0.
+
+symmetry x|y|z [offset]
+                    - starts a block of commands that is drawn twice:
+                    as it is, and reflected across the plane where the
+                    coordinate is offset (0 if not given), as if by
+                    mirror. Each time is in a copy of the current
+                    coordinate system, like a group, so a symmetric
+                    model only needs one half to be written.
+
+end                 - ends the most recent group, symmetry block, or
+                    object.
+
+hide name           - stops the named group from being drawn.
+
+show name           - draws a hidden group again.
+
+object name         - starts a named object, whose commands up to the
+                    matching "end" are not drawn where they are, but
+                    every time the object is instanced.
+
+instance name       - draws the named object in the current coordinate
+                    system. The object's geometry is generated the first
+                    time it is instanced in each frame and reused by the
+                    rest of its instances, so an object can be drawn many
+                    times cheaply. Settings changed inside an object, such
+                    as rendermode, take effect when it is first instanced.
+
+
+Clipping
+--------
+clip plane a b c d  - clips everything drawn afterwards against the plane
+                    ax + by + cz + d = 0 in the current coordinate system,
+                    keeping the side that (a, b, c) points towards. Planes
+                    are discarded when the coordinate system is popped.
+
+clip clear          - removes the clipping planes of the current coordinate
+                    system.
 
 
 Transformations
@@ -34,10 +84,47 @@ All transformations will operate as follows:
    with this result.
 
 move x y z [knob]           - translate
-scale x y z [knob]          - scale
-rotate x|y|z degrees [knob] - rotate (note that you can only
+scale x y z [knob] [about px py pz]
+                            - scale
+rotate x|y|z degrees [knob] [about px py pz]
+                            - rotate (note that you can only
                             specify one axis, x, y, or z per
                             rotation instruction)
+rotate axis ax ay az degrees [knob] [about px py pz]
+                            - rotate about the vector (ax, ay, az)
+                            through the origin
+                            - with "about," rotations and scales
+                            are made about the pivot point
+                            (px, py, pz) instead of the origin,
+                            which stays in place
+rotateq ax ay az degrees [ax ay az degrees ...] [knob] [about px py pz]
+                            - rotate to keyframes, each given as a
+                            rotation about an axis, by interpolating
+                            quaternions so the rotation turns at a
+                            steady rate along a great circle instead
+                            of wobbling the way angles about x, y, and
+                            z do. The knob picks a place along the
+                            keyframes: 0 is the first, 1 the second,
+                            1.5 halfway from the second to the third,
+                            and so on. A single keyframe turns from no
+                            rotation at 0 to it at 1, and is used as
+                            is without a knob. Each step turns the
+                            shorter way, so keyframes must be less
+                            than 180 degrees apart.
+
+matrix m00 m01 m02 m03 m10 m11 m12 m13 m20 m21 m22 m23 m30 m31 m32 m33
+                            - multiply the top of the stack by a 4x4
+                            matrix, given one row at a time, for
+                            transformations made by other tools. Points
+                            are columns multiplied on the right, so the
+                            translation is m03, m13, m23, and the last
+                            row must be 0 0 0 1.
+
+mirror x|y|z [offset]       - reflect across the plane where the
+                            coordinate is offset (0 if not given).
+                            Polygons drawn in a reflected coordinate
+                            system keep facing outwards, so they are
+                            culled and lit the right way round.
 
 Image creation
 --------------
@@ -50,22 +137,156 @@ All image creation commands will operate as follows:
 3. Render the object.
 4. Throw away the point list (if this is applicable in your implementation).
 
-sphere [constants] x y z r [coord_system]
-
-torus [constants] x y z r0 r1  [coord_system]
-
-box [constants] x0 y0 z0 w h d [coord_system]
+If no constants are specified, the object is drawn as a wireframe in
+the color r g b, or the current color (white unless set with color) if
+no color is given.
+
+circle x y z r [coord_system] [r g b]
+                    - a circle around (x, y, z), facing the z axis
+
+ellipse x y z rx ry [facing nx ny nz] [coord_system] [r g b]
+                    - an ellipse around (x, y, z) with radii rx and ry,
+                    in the plane facing (nx, ny, nz), which is the z
+                    axis by default. For the z axis, rx lies along x and
+                    ry along y.
+
+arc x y z r start end [facing nx ny nz] [coord_system] [r g b]
+                    - part of a circle around (x, y, z), from the angle
+                    start to the angle end in degrees, counterclockwise
+                    around the direction it faces (the z axis by
+                    default). 0 degrees points along x when facing the z
+                    axis, and an end before the start draws clockwise.
+
+hermite x0 y0 x1 y1 rx0 ry0 rx1 ry1 [coord_system] [r g b]
+                    - a curve from (x0, y0) to (x1, y1), where
+                    (rx0, ry0) and (rx1, ry1) are the rates of change
+                    at each end
+
+bezier x0 y0 x1 y1 x2 y2 x3 y3 [coord_system] [r g b]
+                    - a cubic bezier curve from (x0, y0) to (x3, y3),
+                    with (x1, y1) and (x2, y2) as control points
+                    - both curves lie at z = 0 unless every point is
+                    given with a z component as well, such as
+                    hermite x0 y0 z0 x1 y1 z1 rx0 ry0 rz0 rx1 ry1 rz1,
+                    which makes them curves through space
+
+sphere [constants] x y z r [segments] [coord_system] [r g b]
+
+torus [constants] x y z r0 r1 [segments] [coord_system] [r g b]
+                    - spheres and tori are divided into segments around
+                    each of their circles, overriding quality and lod.
+
+box [constants] x0 y0 z0 w h d [coord_system] [r g b]
                     - x0 y0 z0 = one corner of the box
                     - w h d = width height and depth
 
-line [constants] x0 y0 z0 [coord_system0] x1 y1 z1 [coord_system1]
+line [constants] x0 y0 z0 [coord_system0] x1 y1 z1 [coord_system1] [r g b]
                     - NOTE: each endpoint of the line can be drawn
-                    in its own coordinate system.
-
-mesh [constants] :filename [coord_system]
+                    in its own coordinate system. Lines with constants
+                    have no surface to light, so they are drawn in the
+                    color of a surface facing the viewer.
+
+axes length [coord_system]
+                    - the x, y, and z axes from the origin, each length
+                    long, in red, green, and blue, to see which way is
+                    which while building a scene.
+
+grid size spacing [coord_system]
+                    - a gray grid on the ground (where y = 0), size
+                    across and centered on the origin, with a line every
+                    spacing along x and z. Axes drawn with it stay in
+                    front of its lines.
+
+profile name x0 y0 x1 y1 ...
+profile name circle r [sides]
+                    - defines a cross-section for sweep, in the plane
+                    across the path it is swept along. Two points make a
+                    flat ribbon seen from both sides, and more make a
+                    closed outline. A circle has 20 sides by default.
+
+sweep [constants] profile bezier|hermite points [coord_system] [r g b]
+                    - a surface made by moving the profile along a
+                    bezier or hermite curve, given by the same points as
+                    the bezier and hermite commands, with or without z.
+                    The profile is kept from twisting as the path bends,
+                    and the ends of the surface are left open.
+
+terrain [constants] x0 y0 z0 w d h roughness [detail] [coord_system] [r g b]
+                    - a random landscape made by the diamond-square
+                    algorithm, spread over w along x and d back along z
+                    from (x0, y0, z0), with its highest peak h above y0.
+                    - roughness is between 0 and 1, from smooth hills to
+                    jagged peaks.
+                    - detail is between 1 and 10, and makes a grid of
+                    2^detail squares on a side. It is 6 by default.
+                    - only the top of the terrain is drawn.
+
+metaballs [constants] x y z r s ... [resolution] [coord_system] [r g b]
+                    - blobs that melt into each other, given as any number
+                    of metaballs, each with a center, a radius, and a
+                    strength. A lone metaball of strength 1 is a sphere of
+                    radius r, and negative strengths carve into the
+                    metaballs around them.
+                    - the surface is polygonized by marching cubes, with
+                    resolution cubes along the longest side of its bounds.
+                    It is 32 by default, and at most 200.
+
+lsystem [constants] axiom rules... iterations angle length [radius] [coord_system] [r g b]
+                    - a plant or fractal drawn by a turtle. Each rule is
+                    given as symbol=replacement, such as "F=F[+F]F[-F]F",
+                    and every symbol with a rule is replaced at once on
+                    each iteration, starting from the axiom. The turtle
+                    then starts at the origin, heading up the y axis, and
+                    follows the symbols:
+                        F    move forward by length, drawing a line
+                        f    move forward by length without drawing
+                        + -  turn left or right by angle degrees
+                        & ^  pitch down or up
+                        \ /  roll left or right
+                        |    turn around
+                        [ ]  save and restore where the turtle is
+                    and ignores the rest.
+                    - with a radius, the lines are drawn as branches of
+                    that radius, which can be shaded with constants.
+                    - quote rules that start with + or -, or contain //.
+
+emitter name x y z rate [knob] [velocity vx vy vz] [spread degrees]
+        [jitter amount] [gravity gx gy gz] [life frames]
+                    - defines a source of particles at (x, y, z), which
+                    emits rate particles each frame, scaled by the knob in
+                    each frame if one is given. Particles fly off at the
+                    velocity, in distance per frame, turned by up to spread
+                    degrees in any direction and with speeds varied by up
+                    to the fraction jitter. Gravity adds to their velocity
+                    each frame, and they disappear after life frames, which
+                    is 30 by default.
+
+particles [constants] emitter [size] [coord_system] [r g b]
+                    - draws the particles of an emitter as they are in the
+                    frame, as points, or as spheres of radius size. Only
+                    spheres can be shaded with constants. The emitter moves
+                    with the coordinate system the particles are drawn in.
+
+seed n              - seeds the randomness that terrain and the particles
+                    of emitters are made from. Those after it are made the
+                    same way every time the script is rendered, and without
+                    it the seed is 0.
+
+displace size amount
+displace off        - moves the surface of spheres, tori, boxes, sweeps,
+                    terrain, and meshes drawn after it along their normals
+                    by perlin noise, with bumps about size apart rising
+                    and sinking by up to about amount. Displacing a sphere
+                    makes an asteroid or a planet. Only the corners of
+                    triangles move, so shapes need enough segments for
+                    their bumps to show.
+
+mesh [constants] :filename [coord_system] [r g b]
                     - load a mesh or set of edges (in some format that
                     you can specify) from a file into the pointlist
-                    and or edge list directly.
+                    and or edge list directly. The colon tells the
+                    filename apart from the constants, so it is only
+                    needed when constants are given.
 
 Knobs/Animation
 ---------------
@@ -75,62 +296,463 @@ basename name       - sets the base filename to save under.
                     if "name" is R, then tween or vary might
                     save the images r01.miff, r02.miff etc.
 
-set knobname value  - sets a knobs value (in the symbol table).
+set knobname value  - sets a knobs value (in the symbol table) in every
+                    frame, or every frame of the scene it is in. A later
+                    vary or tween changes the frames it covers.
+
+setknobs value      - sets every knob to value, like set.
 
 save_knobs knoblist - saves the current values of all knobs
-                    under the name "knoblist."
+                    under the name "knoblist." The current values are
+                    the ones most recently given to set and setknobs.
 
 tween start_frame end_frame knoblist0 knoblist1
                     - generates a number of frames using basename
                     as the base filename. It will start from
                     start_frame and end at end_frame and
                     interpolate the image using knoblist0 as
-                    the starting configuration and knoblist 2
-                    as the ending configuration.
+                    the starting configuration and knoblist1
+                    as the ending configuration. Knobs missing
+                    from one of the lists are 0 in it.
 
 frames num_frames   - How many frames to generate all together.
 
-vary knob start_frame end_frame start_val end_val
+fps frames_per_second
+                    - sets the frame rate of the animation (30 by
+                    default), which videos are encoded at and which
+                    sets the delay between gif frames. It must come
+                    before any duration, time in seconds, or audio.
+
+duration seconds    - sets the number of frames to generate from how
+                    long the animation lasts at its frame rate.
+
+scene name num_frames
+                    - starts a named shot lasting num_frames frames, which
+                    is placed on the timeline right after the previous
+                    scene and is only drawn during its own frames. Inside
+                    a scene, the frames given to vary are relative to the
+                    start of the scene. The scene is ended with "end," and
+                    the total number of frames grows to fit every scene.
+
+vary knob start_frame end_frame start_val end_val [x1 y1 x2 y2|curve]
                     - vary a knob from start_val to end_val over
                     the course of start_frame to end_frame
-setknobs value      - set all the knobs to value
+                    - the timing is linear unless four bezier control
+                    values are given, which shape it like the CSS
+                    cubic-bezier(x1, y1, x2, y2) function (x1 and x2 must
+                    be between 0 and 1), or a curve is named. The curves
+                    linear, ease, ease-in, ease-out, and ease-in-out are
+                    built in.
+                    - start_frame and end_frame can be given as times in
+                    seconds instead, such as 1.5s, which are rounded to
+                    the nearest frame. A time at the end of the
+                    animation is its last frame.
+
+vary_spring knob start_frame end_frame start_val end_val [stiffness [damping]]
+                    - like vary, but the knob is pulled to end_val by a
+                    spring, overshooting and swinging around it before it
+                    settles. stiffness is how many times the spring would
+                    swing back and forth by end_frame without damping (3
+                    by default), and damping is the fraction of the damping
+                    that stops it from overshooting at all (0.3 by
+                    default), where 0 swings forever.
+
+vary_bounce knob start_frame end_frame start_val end_val [elasticity]
+                    - like vary, but the knob falls from start_val onto
+                    end_val like a dropped ball, bouncing back towards
+                    start_val lower each time until it comes to rest by
+                    end_frame. elasticity is the fraction of its speed the
+                    knob keeps on each bounce, from 0 up to 1 (0.5 by
+                    default).
+
+vary_damp knob start_frame end_frame start_val end_val [damping]
+                    - like vary, but the knob sets off quickly and slows
+                    down under drag, stopping at end_val. Higher damping
+                    slows it down sooner (5 by default).
+
+curve name t v t v ...
+                    - defines a timing curve for vary that passes smoothly
+                    through each point, where t is the time from 0 (the
+                    start frame) to 1 (the end frame) and v is how far the
+                    knob is from start_val (0) to end_val (1). Times must
+                    increase, and at least two points are needed.
+
+curve name bezier x0 y0 z0 x1 y1 z1 x2 y2 z2 x3 y3 z3
+curve name hermite x0 y0 z0 x1 y1 z1 rx0 ry0 rz0 rx1 ry1 rz1
+                    - defines a 3D curve for camerapath, with the same
+                    points as the bezier and hermite shapes.
+
+knobdata knob file [column]
+                    - sets the knob to a series of values read from a CSV
+                    or JSON file, such as sensor readings or the output of
+                    a simulation. The values are spread evenly over the
+                    animation, from the first frame to the last, and
+                    interpolated between.
+                    - column is the name of the column, or its number
+                    counting from 1, and can be left out if the file has
+                    only one. CSV files may start with a row of column
+                    names. JSON files may be an array of numbers, an array
+                    of rows (arrays or objects), or an object of arrays.
+
+audio knob file.wav [low high]
+                    - sets the knob in each frame to the loudness of the
+                    wav file during that frame (at the animation's fps),
+                    scaled so that the loudest frame is 1. If low and high
+                    are given, only frequencies between low and high hertz
+                    are measured (0 leaves a side unbounded).
 
 
 Lighting
 --------
-light r g b x y z   - creates a "light" datastructure with rgb values
+light name r g b [knob] x [knob] y [knob] z [knob]
+                    - creates a "light" datastructure with rgb values
                     r,g,b at location x,y,z.
                     This is inserted into the symbol table.
+                    - the knob after b scales the color each frame, so the
+                    light can fade. The knob after each coordinate scales
+                    that coordinate, so the light can move.
 
 ambient r g b       - specifies how much ambient light is in the scene
 
-constants name kar kdr ksr kag kdg ksg kab kdb ksb [r] [g] [b]
+constants name kar kdr ksr kag kdg ksg kab kdb ksb [r g b [n [er eg eb [opacity [reflectivity]]]]] [normalmap file [size]] [texture kind [size [tr tg tb]]]
                     - saves a set of lighting components in the
                     symbol table under "name."
                     - r g b intensities can be specified. If not specified, they
-                    default to 0.
+                    default to 0, and each light's own color is used.
+                    - n is the shininess, the exponent of the specular
+                    reflection (8 by default). Higher values give smaller,
+                    sharper highlights.
+                    - er eg eb is light emitted by the surface itself,
+                    regardless of the lights (0 by default).
+                    - opacity is from 0 (invisible) to 1 (opaque, the
+                    default). Translucent shapes are blended over what is
+                    already drawn behind them, so draw them after the
+                    opaque shapes they cover.
+                    - reflectivity is from 0 (the default) to 1, and is
+                    how much of the environment map the surface mirrors
+                    in place of its own lighting, for chrome or water.
+                    - normalmap bends the lighting of the surface by a
+                    tangent-space normal map read from a png, jpeg, or
+                    binary ppm, adding detail without more triangles. A
+                    gray image is a bump map, where brighter is higher.
+                    The map is projected onto each shape from the side of a
+                    box it faces most, before the shape is transformed, and
+                    repeats every size units (the width of the image in
+                    pixels by default). Shapes with a normal map are lit
+                    per pixel even with flat shading.
+                    - texture varies the color of the surface by a pattern
+                    computed from the point of the shape before it is
+                    transformed, so it moves with the shape. kind is
+                    checker (cubes), stripes (slabs along x), marble (veins
+                    along x twisted by noise), or wood (rings around the y
+                    axis). The pattern repeats every size units (32 by
+                    default) and blends between the constants and a second
+                    color, whose ambient and diffuse reflection are tr tg tb
+                    times those of the constants (0.25 by default). Textured
+                    shapes are lit per pixel even with flat shading.
+
+envmap file
+envmap off          - sets the image of the surroundings that reflective
+                    shapes drawn afterwards mirror, read from a png, jpeg,
+                    or binary ppm file. Its shape gives its layout: a
+                    square image is a mirrored ball seen from the front,
+                    an image twice as wide as it is tall spans every
+                    direction (equirectangular), and a 4:3 image is a cube
+                    unfolded into a cross, with the left, front, right,
+                    and back faces across the middle and the up and down
+                    faces above and below the front. The front is the
+                    direction looking into the image.
+
+shading flat|phong  - set how shapes drawn afterwards with constants are
+                    lit. flat (the default) lights each polygon once;
+                    phong interpolates normals across each polygon and
+                    lights every pixel, smoothing edges sharper than 60
+                    degrees.
+
+exposure e [knob]   - multiplies the light reflected by shapes drawn
+                    afterwards with constants by e (1 by default) before
+                    it is tone mapped. The knob scales e each frame.
+
+tonemap clamp|reinhard|aces
+                    - sets how light brighter than the image can show is
+                    brought into range for shapes drawn afterwards with
+                    constants. clamp (the default) cuts it off, so bright
+                    areas blow out to flat color; reinhard and aces
+                    compress the highlights gradually.
+
+gamma on|off        - sets whether shapes drawn afterwards with constants
+                    are lit in linear space. When on, the colors of
+                    lights, ambient light, and emitted light are decoded
+                    from sRGB, and the result is encoded back to sRGB.
+                    Off by default.
+
+shadows on [resolution]|off
+                    - makes filled shapes cast shadows from every light
+                    onto shaded shapes. Shadows are rendered into a map
+                    of resolution x resolution pixels per light (1024 by
+                    default) before each frame is drawn. Shaded shapes
+                    are lit per pixel while shadows are on.
+
+ssao radius strength
+ssao off            - darkens creases and the places where shapes meet, by
+                    comparing the depth of each pixel with the pixels up
+                    to radius pixels around it once the image is drawn.
+                    Strength is how dark the most hidden pixels become,
+                    where 1 makes them black. Each layer is darkened by
+                    its own depths. Off by default.
+
+rendermode wireframe|solid|both
+                    - set whether polygons drawn afterwards are outlined,
+                    filled, or filled with outlines drawn over them.
+                    Outlines use the shape's color, and fills are lit by
+                    the shape's constants or use its color. By default,
+                    shapes with constants are filled and others outlined.
+
+culling on|off|front|back
+                    - set which faces of polygons drawn afterwards are
+                    skipped. on (the same as back, the default) skips
+                    polygons facing away from the viewer, front skips
+                    polygons facing the viewer, and off draws both, so
+                    the insides of open shapes are visible. Back faces
+                    that are drawn are lit from the viewer's side.
+
+winding ccw|cw      - set whether the vertices of polygons drawn afterwards
+                    are in counterclockwise (the default, and the order of
+                    the engine's own shapes) or clockwise order when
+                    facing the viewer, for meshes made the other way.
+
+
+Level of detail
+---------------
+lod pixels|off      - spheres and tori drawn afterwards are divided into
+                    segments about "pixels" long on screen, so small or
+                    distant objects use fewer triangles. "off" restores
+                    the fixed tessellation set by quality.
+
+quality segments    - spheres and tori drawn afterwards without lod are
+                    divided into this many segments (20 by default, and
+                    at least 6). More segments are smoother but slower.
 
-shading wireframe|flat|goroud|phong|raytrace
-                    - set the shading mode
+stamp [topleft|topright|bottomleft|bottomright] [scale]
+                    - stamps the frame number and its time (from fps) in
+                    a corner of every image saved afterwards, which is
+                    the top right corner by default. The text is scale
+                    times its normal size, so it can be read at high
+                    resolutions.
+
+linewidth pixels    - sets the width of lines, curves, and wireframes drawn
+                    afterwards, which is 1 (or the width given with
+                    -linewidth) by default. Lines wider than a pixel are
+                    drawn as quads with square caps.
+
+
+Viewport
+--------
+viewport origin [up|down]
+                    - sets the coordinate convention of everything drawn
+                    afterwards. origin is bottomleft (the default),
+                    topleft, or center, and the y axis points up (the
+                    default) or down the image. When y points down, z
+                    points into the image so the winding of polygons is
+                    unchanged.
+
+viewport x y w h    - draws everything afterwards into the rectangle of the
+                    image with its bottom left corner at pixel (x, y),
+                    scaling the whole image down (or up) to fit it.
+                    Nothing is drawn outside of the rectangle.
+
+projection ortho
+projection perspective fov [near far]
+                    - sets how everything drawn afterwards is projected
+                    onto the image. ortho (the default) drops depth.
+                    perspective looks at the center of the image with a
+                    vertical field of view of fov degrees, from where
+                    shapes at z = 0 keep their size. Only what is
+                    between near (1 by default) and far (unlimited by
+                    default) units in front of the eye is drawn, and
+                    zclip applies to projected depths.
+
+
+Depth
+-----
+zepsilon value      - a pixel only replaces another if it is at least
+                    value closer, so that coplanar surfaces don't fight.
+
+zoffset value       - add value to the depth of everything drawn afterwards
+                    (polygon offset), pulling it in front of coplanar
+                    surfaces.
+
+zclip near far      - clip everything drawn afterwards to the depths
+                    between the near and far planes, once every
+                    transformation and the viewport have been applied.
+                    Depth increases towards the viewer, so near must be
+                    greater than far. Lines and polygons are always
+                    clipped a little way outside the edges of the image
+                    as well.
 
 
 MISC
 ----
 //                  comment to the end of a line, just like c++
-save_coord_system name
-                    - Makes a copy of the top of the stack and
-                    saves it in the symbol table under "name".
 
-camera eye aim      - establishes a camera. Eye and aim are
-                    x y z triples.
+resolution w h      - renders images that are w pixels wide and h pixels
+                    tall instead of 500x500. The -width and -height
+                    options override it.
+
+include file        - parses the commands of another script as if they
+                    were written in place of the include. The path is
+                    relative to the including script. Strings, such as
+                    the filenames of save, mesh, and basename, can be
+                    surrounded by double quotes to include spaces, with
+                    \" and \\ for quotes and backslashes inside them
+                    (e.g. save "my scenes/ball.png").
+
+let name = value    - defines the variable "name". Anywhere a number is
+                    expected, a variable or an arithmetic expression of
+                    numbers, variables, + - * /, and parentheses can be
+                    used instead, written without spaces (e.g. r*2 or
+                    (cx-r)/2). Expressions are evaluated when the script
+                    is parsed, using the variables defined above them.
+                    Numbers can have an exponent (1.5e-3) and underscores
+                    between digits (1_000).
+                    Shapes that take optional constants can be given
+                    "nil" to draw without any.
+
+define name [param ...]
+...
+end                 - defines a macro: the commands up to the matching
+                    "end" are not run, but saved under "name."
+
+call name [arg ...] - runs the commands of a macro, with each parameter
+                    set to the value of the matching argument. Variables
+                    defined within the macro are discarded when it ends.
+savecs name         - Makes a copy of the top of the stack and
+                    saves it in the symbol table under "name".
+                    Shapes given a coord_system are drawn in the saved
+                    coordinate system instead of the top of the stack.
+
+camera ex [knob] ey [knob] ez [knob] ax [knob] ay [knob] az [knob]
+                    - everything drawn afterwards is seen from the eye at
+                    (ex, ey, ez), looking towards the aim at
+                    (ax, ay, az), which is put where projection looks (the
+                    center of the image, on the image plane for ortho).
+                    The camera never rolls. The knob after each coordinate
+                    scales that coordinate, so the camera can move. Lights
+                    stay where they are in the scene, and the sky turns
+                    with the camera.
+
+camerapath name [knob]
+                    - moves the eye of the camera along the 3D curve
+                    "name", keeping its aim. The knob is how far along
+                    the curve the eye is, from 0 (its start) to 1 (its
+                    end). Without a knob, the eye goes from the start of
+                    the curve in the first frame to its end in the last.
 
 
 save filename       - save the image in its current state under
                     the name "filename."
+                    - a filename ending in .svg saves the lines and
+                    polygons drawn so far as vector paths instead of
+                    pixels. Filled polygons are drawn in a single color,
+                    lit once at their center, and cover the paths drawn
+                    before them regardless of depth, so SVG suits line
+                    work and 2D drawings best.
+
+saveframe frame filename
+                    - save the image in its current state under the name
+                    "filename," but only while rendering the given frame
+                    of an animation, so single frames can be kept as
+                    stills. The image is saved in full color even when
+                    the animation becomes a gif, and the frame can be
+                    given in seconds, such as 1.5s.
+
+savedepth filename  - save the depth buffer of the image in its current
+                    state as a grayscale image under the name "filename."
+                    The closest pixel is white, the farthest dark gray,
+                    and pixels where nothing was drawn are black.
+
+layer name [normal|add|multiply|screen] [opacity]
+                    - draws subsequent objects onto the layer "name,"
+                    creating it if needed. Each layer has its own image
+                    and z buffer, and layers are blended over the "base"
+                    layer in the order they were created when the image
+                    is saved or displayed. Opacity defaults to 1.
+
+rendertarget name w h
+                    - starts a render target: the commands up to the
+                    matching "end" are drawn into an offscreen image w by h
+                    pixels instead of the image, which usetexture can then
+                    cover shapes with. The target is drawn with the
+                    settings in effect, but with its own layers and
+                    coordinate system stack, and nothing changed inside it
+                    carries on after it. Targets are drawn again every
+                    frame, so they can be animated.
+
+usetexture name
+usetexture off      - covers shapes drawn afterwards with constants with the
+                    image drawn by the render target "name," for screens,
+                    mirrors, and portals. The image is stretched over the
+                    whole shape, before it is transformed, on each side of
+                    a box that its surfaces face, and its color scales the
+                    ambient, diffuse, and emissive reflection of the
+                    constants. Give the constants an emissive color of 255
+                    255 255 to show the image unlit.
+
+snapshot name       - saves a copy of the image and of the coordinate
+                    system stack under "name."
+
+restore name        - replaces the image and the coordinate system stack
+                    with the snapshot "name," so a base scene can be
+                    drawn once and varied several ways.
 
 generate_rayfiles   - Instruct the interpreter to generate source
                     files for a ray tracer for each frame rendered.
 
 focal value         - set the focal length of the camera
 
-display             - display the current image on the screen
+display [frame]     - display the current image on the screen, or in the
+                    browser preview when running with -preview
+                    - an animation is only displayed while rendering the
+                    given frame, or the first frame if none is given,
+                    rather than once for every frame
+
+debug normals on|off
+debug bounds on|off - draws lines over the shapes drawn afterwards to show
+                    how they are put together: "normals" draws the normal
+                    of each triangle, 10 units long in yellow, from its
+                    middle the way it faces, and "bounds" draws the box
+                    around each shape along the axes in magenta. Normals
+                    that point into a shape were wound the wrong way.
+
+pick x y            - prints what covers the pixel x, y of the image so far,
+                    where 0, 0 is the bottom left corner: the shape and
+                    the line of the script that drew it, which of its
+                    triangles (or line segments, counting from 0) is
+                    there, and its depth. Translucent shapes are never
+                    picked, since they don't hide what is behind them.
+
+color r g b [a]|#rrggbb[aa]
+                    - sets the current color, which lines and shapes
+                    drawn afterwards without constants or a color of
+                    their own are drawn in. a is the alpha, from 0
+                    (invisible) to 255 (opaque, the default).
+
+background r g b    - sets the color of the image where nothing is drawn,
+                    which is black by default.
+
+skybox file         - fills the image where nothing is drawn with the
+                    surroundings of the scene, read from an environment
+                    map like envmap's (equirectangular, a cube cross, or a
+                    mirrored ball). The front of the map is seen through
+                    the field of view of a perspective projection, or 60
+                    degrees otherwise, and the panes of -quad each see the
+                    sky their own way. Replaces the background color.
+
+skygradient r g b r g b
+                    - fills the image where nothing is drawn with a
+                    gradient from the first color at the top to the second
+                    at the bottom, in place of the background color.
+
+clear               - erases everything drawn so far on every layer,
+                    leaving the background, but keeps the coordinate
+                    system stack.
@@ -0,0 +1,173 @@
+package geometry
+
+import "math"
+
+// GuardBand is how far geometry can reach past each side of the image, as a
+// fraction of its size, before it is clipped
+// Shapes that are partly on screen are rarely split, while coordinates far off
+// screen are cut down before they can overflow the rasterizer.
+const GuardBand = 1.0
+
+// Plane is a plane ax + by + cz + d = 0, where points with a positive distance
+// are kept when clipping
+type Plane [4]float64
+
+// Distance returns the signed distance of a point from the plane, scaled by the
+// length of its normal
+func (pl Plane) Distance(p []float64) float64 {
+	return pl[0]*p[0] + pl[1]*p[1] + pl[2]*p[2] + pl[3]
+}
+
+// TransformPlane returns a plane in local coordinates transformed by m
+func TransformPlane(pl Plane, m Mat4) Plane {
+	// A point on the plane is transformed like any other point
+	n := Vec3{pl[0], pl[1], pl[2]}
+	point := n.Scale(-pl[3] / n.Dot(n))
+	p := m.MulVec4(Vec4{point[0], point[1], point[2], 1}).XYZ()
+
+	// Normals are transformed by the inverse transpose of the linear part of m,
+	// which is its cofactor matrix divided by its determinant
+	a := func(r, c int) float64 {
+		return m.At(r%3, c%3)
+	}
+	var normal Vec3
+	var det float64
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			cofactor := a(i+1, j+1)*a(i+2, j+2) - a(i+1, j+2)*a(i+2, j+1)
+			normal[i] += cofactor * n[j]
+			if i == 0 {
+				det += a(0, j) * cofactor
+			}
+		}
+	}
+	if det < 0 {
+		normal = normal.Scale(-1)
+	}
+	return Plane{normal[0], normal[1], normal[2], -normal.Dot(p)}
+}
+
+// ViewPlanes returns the planes bounding what can be drawn onto an image: its
+// sides, widened by the guard band, and the near and far depths, where z
+// increases towards the viewer
+// Infinite depths aren't clipped against, and neither are the sides when
+// sides is false.
+func ViewPlanes(height, width int, near, far float64, sides bool) []Plane {
+	var planes []Plane
+	if sides {
+		w, h := float64(width), float64(height)
+		planes = append(planes,
+			Plane{1, 0, 0, w * GuardBand},
+			Plane{-1, 0, 0, w + w*GuardBand},
+			Plane{0, 1, 0, h * GuardBand},
+			Plane{0, -1, 0, h + h*GuardBand},
+		)
+	}
+	if !math.IsInf(near, 1) {
+		planes = append(planes, Plane{0, 0, -1, near})
+	}
+	if !math.IsInf(far, -1) {
+		planes = append(planes, Plane{0, 0, 1, -far})
+	}
+	return planes
+}
+
+// inside returns whether every point is on the kept side of every plane
+func inside(planes []Plane, points ...[]float64) bool {
+	for _, plane := range planes {
+		for _, p := range points {
+			if plane.Distance(p) < 0 {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// lerpPoint returns the point a fraction t of the way from p0 to p1
+func lerpPoint(p0, p1 []float64, t float64) []float64 {
+	p := make([]float64, len(p0))
+	for i := range p {
+		p[i] = p0[i] + (p1[i]-p0[i])*t
+	}
+	return p
+}
+
+// ClipEdges returns the edges of em clipped against every plane
+func ClipEdges(em *Matrix, planes []Plane) *Matrix {
+	if len(planes) == 0 {
+		return em
+	}
+	clipped := NewMatrix(em.Rows, 0)
+	for i := 0; i < em.Cols-1; i += 2 {
+		p0, p1 := em.GetColumn(i), em.GetColumn(i+1)
+		if inside(planes, p0, p1) {
+			clipped.AddColumn(p0)
+			clipped.AddColumn(p1)
+			continue
+		}
+		visible := true
+		for _, plane := range planes {
+			d0, d1 := plane.Distance(p0), plane.Distance(p1)
+			if d0 < 0 && d1 < 0 {
+				visible = false
+				break
+			}
+			if d0 < 0 {
+				p0 = lerpPoint(p0, p1, d0/(d0-d1))
+			} else if d1 < 0 {
+				p1 = lerpPoint(p0, p1, d0/(d0-d1))
+			}
+		}
+		if visible {
+			clipped.AddColumn(p0)
+			clipped.AddColumn(p1)
+		}
+	}
+	return clipped
+}
+
+// ClipPolygons returns the triangles of em clipped against every plane
+// Clipping a triangle against a plane leaves zero, one, or two triangles, whose
+// vertices keep the winding of the original triangle.
+func ClipPolygons(em *Matrix, planes []Plane) *Matrix {
+	if len(planes) == 0 {
+		return em
+	}
+	clipped := NewMatrix(em.Rows, 0)
+	for i := 0; i < em.Cols-2; i += 3 {
+		polygon := [][]float64{em.GetColumn(i), em.GetColumn(i + 1), em.GetColumn(i + 2)}
+		if inside(planes, polygon...) {
+			clipped.AddColumn(polygon[0])
+			clipped.AddColumn(polygon[1])
+			clipped.AddColumn(polygon[2])
+			continue
+		}
+		for _, plane := range planes {
+			polygon = clipPolygon(polygon, plane)
+		}
+		// Fan the clipped polygon back into triangles
+		for j := 1; j+1 < len(polygon); j++ {
+			clipped.AddColumn(polygon[0])
+			clipped.AddColumn(polygon[j])
+			clipped.AddColumn(polygon[j+1])
+		}
+	}
+	return clipped
+}
+
+// clipPolygon clips a convex polygon against a plane (Sutherland-Hodgman)
+func clipPolygon(polygon [][]float64, plane Plane) [][]float64 {
+	clipped := make([][]float64, 0, len(polygon)+1)
+	for i, current := range polygon {
+		next := polygon[(i+1)%len(polygon)]
+		dc, dn := plane.Distance(current), plane.Distance(next)
+		if dc >= 0 {
+			clipped = append(clipped, current)
+		}
+		if (dc >= 0) != (dn >= 0) {
+			clipped = append(clipped, lerpPoint(current, next, dc/(dc-dn)))
+		}
+	}
+	return clipped
+}
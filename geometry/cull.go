@@ -0,0 +1,80 @@
+package geometry
+
+import (
+	"fmt"
+)
+
+// CullMode defines which faces of polygons are skipped
+type CullMode int
+
+const (
+	// CullBack skips polygons facing away from the viewer
+	CullBack CullMode = iota
+	// CullFront skips polygons facing the viewer
+	CullFront
+	// CullNone draws polygons facing either way
+	CullNone
+)
+
+var cullModes = map[string]CullMode{
+	"on":    CullBack,
+	"back":  CullBack,
+	"front": CullFront,
+	"off":   CullNone,
+}
+
+// ParseCullMode returns the culling mode with the given name
+func ParseCullMode(name string) (CullMode, error) {
+	if mode, found := cullModes[name]; found {
+		return mode, nil
+	}
+	return CullBack, fmt.Errorf("unknown culling mode '%s'", name)
+}
+
+// Winding defines the order of the vertices of polygons that face the viewer
+type Winding int
+
+const (
+	// WindingCounterClockwise is the winding of the shapes the engine generates
+	WindingCounterClockwise Winding = iota
+	// WindingClockwise is the winding of meshes made the other way
+	WindingClockwise
+)
+
+var windings = map[string]Winding{
+	"ccw": WindingCounterClockwise,
+	"cw":  WindingClockwise,
+}
+
+// ParseWinding returns the winding with the given name
+func ParseWinding(name string) (Winding, error) {
+	if winding, found := windings[name]; found {
+		return winding, nil
+	}
+	return WindingCounterClockwise, fmt.Errorf("unknown winding '%s'", name)
+}
+
+// Orient returns the triangles of em that are drawn with a culling mode, with
+// the vertices of each in counterclockwise order as seen from the side that is
+// drawn
+// Drawn back faces are reversed so they are lit from the viewer's side.
+func Orient(em *Matrix, cull CullMode, winding Winding) *Matrix {
+	oriented := NewMatrix(em.Rows, 0)
+	for i := 0; i < em.Cols-2; i += 3 {
+		p0, p1, p2 := em.GetColumn(i), em.GetColumn(i+1), em.GetColumn(i+2)
+		if winding == WindingClockwise {
+			p1, p2 = p2, p1
+		}
+		front := Normal(Vec3Of(p0), Vec3Of(p1), Vec3Of(p2))[2] > 0
+		if (front && cull == CullFront) || (!front && cull == CullBack) {
+			continue
+		}
+		if !front {
+			p1, p2 = p2, p1
+		}
+		oriented.AddColumn(p0)
+		oriented.AddColumn(p1)
+		oriented.AddColumn(p2)
+	}
+	return oriented
+}
@@ -0,0 +1,142 @@
+package geometry
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// MaxLSystemLength keeps the string an l-system grows into from using up all
+// of memory
+const MaxLSystemLength = 1 << 20
+
+// BranchSides is the number of sides of the cylinders that l-systems are drawn
+// with
+const BranchSides = 8
+
+// LSystem rewrites every symbol of a string that has a rule at once, starting
+// from its axiom
+type LSystem struct {
+	axiom string
+	rules map[byte]string
+}
+
+// NewLSystem returns the l-system that grows from axiom by rules given as
+// "symbol=replacement"
+func NewLSystem(axiom string, rules []string) (*LSystem, error) {
+	l := &LSystem{axiom: axiom, rules: make(map[byte]string)}
+	for _, rule := range rules {
+		parts := strings.SplitN(rule, "=", 2)
+		if len(parts) != 2 || len(parts[0]) != 1 {
+			return nil, fmt.Errorf("invalid rule '%s', expected symbol=replacement", rule)
+		}
+		if _, found := l.rules[parts[0][0]]; found {
+			return nil, fmt.Errorf("symbol '%s' has more than one rule", parts[0])
+		}
+		l.rules[parts[0][0]] = parts[1]
+	}
+	return l, nil
+}
+
+// Expand returns the string the l-system grows into after rewriting its axiom
+// the given number of times
+func (l *LSystem) Expand(iterations int) (string, error) {
+	current := l.axiom
+	for i := 0; i < iterations; i++ {
+		var next strings.Builder
+		for j := 0; j < len(current); j++ {
+			if replacement, found := l.rules[current[j]]; found {
+				next.WriteString(replacement)
+			} else {
+				next.WriteByte(current[j])
+			}
+			if next.Len() > MaxLSystemLength {
+				return "", fmt.Errorf("l-system grows past %d symbols after %d iterations", MaxLSystemLength, i+1)
+			}
+		}
+		current = next.String()
+	}
+	return current, nil
+}
+
+// turtle is a position and orientation in space, which moves and turns as
+// the symbols of an l-system tell it to
+type turtle struct {
+	position          Vec3
+	heading, left, up Vec3
+}
+
+// TurtleSegments returns the lines a turtle draws as it interprets the
+// symbols of a string, starting at the origin heading up the y axis
+//
+//	F    move forward drawing a line
+//	f    move forward without drawing
+//	+ -  turn left or right by angle
+//	& ^  pitch down or up by angle
+//	\ /  roll left or right by angle
+//	|    turn around
+//	[ ]  save and restore the position and orientation
+//
+// Every other symbol is only used to grow the string, and is ignored.
+func TurtleSegments(symbols string, angle, length float64) ([][2]Vec3, error) {
+	// rotate turns a towards b by theta, keeping them perpendicular
+	rotate := func(a, b *Vec3, theta float64) {
+		cos, sin := math.Cos(theta), math.Sin(theta)
+		*a, *b = a.Scale(cos).Add(b.Scale(sin)), b.Scale(cos).Sub(a.Scale(sin))
+	}
+	theta := DegreesToRadians(angle)
+	t := turtle{heading: Vec3{0, 1, 0}, left: Vec3{-1, 0, 0}, up: Vec3{0, 0, 1}}
+	var stack []turtle
+	var segments [][2]Vec3
+	for i := 0; i < len(symbols); i++ {
+		switch symbols[i] {
+		case 'F':
+			next := t.position.Add(t.heading.Scale(length))
+			segments = append(segments, [2]Vec3{t.position, next})
+			t.position = next
+		case 'f':
+			t.position = t.position.Add(t.heading.Scale(length))
+		case '+':
+			rotate(&t.heading, &t.left, theta)
+		case '-':
+			rotate(&t.heading, &t.left, -theta)
+		case '&':
+			rotate(&t.heading, &t.up, -theta)
+		case '^':
+			rotate(&t.heading, &t.up, theta)
+		case '\\':
+			rotate(&t.left, &t.up, theta)
+		case '/':
+			rotate(&t.left, &t.up, -theta)
+		case '|':
+			rotate(&t.heading, &t.left, math.Pi)
+		case '[':
+			stack = append(stack, t)
+		case ']':
+			if len(stack) == 0 {
+				return nil, errors.New("l-system has a ']' without a '['")
+			}
+			t = stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+		}
+	}
+	return segments, nil
+}
+
+// AddBranches adds the segments drawn by a turtle to the matrix, as edges if
+// radius is 0 or as cylinders of that radius otherwise
+// The ends of cylinders are left open, since branches join other branches.
+func (m *Matrix) AddBranches(segments [][2]Vec3, radius float64) {
+	if radius == 0 {
+		for _, s := range segments {
+			m.AddEdge(s[0][0], s[0][1], s[0][2], s[1][0], s[1][1], s[1][2])
+		}
+		return
+	}
+	profile := CircleProfile(radius, BranchSides)
+	for _, s := range segments {
+		direction := s[1].Sub(s[0])
+		m.AddSweep(profile, HermiteCubic(s[0], s[1], direction, direction), 1)
+	}
+}
@@ -0,0 +1,170 @@
+// Package geometry has the vectors, matrices, and edge matrices of shapes that
+// scripts build, along with clipping, culling, and projection
+package geometry
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+)
+
+// Vec3 is a point, direction, or color in three dimensions
+type Vec3 [3]float64
+
+// Vec4 is a point in homogeneous coordinates
+type Vec4 [4]float64
+
+// Add returns the sum of two vectors
+func (a Vec3) Add(b Vec3) Vec3 {
+	return Vec3{a[0] + b[0], a[1] + b[1], a[2] + b[2]}
+}
+
+// Sub returns the difference of two vectors
+func (a Vec3) Sub(b Vec3) Vec3 {
+	return Vec3{a[0] - b[0], a[1] - b[1], a[2] - b[2]}
+}
+
+// Scale returns the vector multiplied by a factor
+func (a Vec3) Scale(factor float64) Vec3 {
+	return Vec3{a[0] * factor, a[1] * factor, a[2] * factor}
+}
+
+// Mul returns the product of each component of two vectors
+func (a Vec3) Mul(b Vec3) Vec3 {
+	return Vec3{a[0] * b[0], a[1] * b[1], a[2] * b[2]}
+}
+
+// Dot returns the dot product of two vectors
+func (a Vec3) Dot(b Vec3) float64 {
+	return a[0]*b[0] + a[1]*b[1] + a[2]*b[2]
+}
+
+// Cross returns the cross product of two vectors
+func (a Vec3) Cross(b Vec3) Vec3 {
+	return Vec3{
+		a[1]*b[2] - a[2]*b[1],
+		a[2]*b[0] - a[0]*b[2],
+		a[0]*b[1] - a[1]*b[0],
+	}
+}
+
+// Length returns the magnitude of the vector
+func (a Vec3) Length() float64 {
+	return math.Sqrt(a.Dot(a))
+}
+
+// Normalize returns the unit vector in the direction of the vector
+func (a Vec3) Normalize() Vec3 {
+	length := a.Length()
+	return Vec3{a[0] / length, a[1] / length, a[2] / length}
+}
+
+// Vec3Of returns the first three values of a slice, such as a column of an
+// edge matrix, as a Vec3
+func Vec3Of(p []float64) Vec3 {
+	return Vec3{p[0], p[1], p[2]}
+}
+
+// XYZ returns the x, y, and z coordinates of the point
+func (v Vec4) XYZ() Vec3 {
+	return Vec3{v[0], v[1], v[2]}
+}
+
+// Normal returns the normal of the triangle p0, p1, p2, facing the side from
+// which its vertices are counterclockwise
+func Normal(p0, p1, p2 Vec3) Vec3 {
+	return p1.Sub(p0).Cross(p2.Sub(p0))
+}
+
+// Mat4 is a 4x4 transformation matrix, stored by row
+type Mat4 [16]float64
+
+// Identity returns the identity matrix
+func Identity() Mat4 {
+	return Mat4{
+		1, 0, 0, 0,
+		0, 1, 0, 0,
+		0, 0, 1, 0,
+		0, 0, 0, 1,
+	}
+}
+
+// At returns the value at a row and column of the matrix
+func (m Mat4) At(r, c int) float64 {
+	return m[4*r+c]
+}
+
+// Mul returns the product of two matrices, which applies b and then m
+func (m Mat4) Mul(b Mat4) Mat4 {
+	var product Mat4
+	for r := 0; r < 4; r++ {
+		for c := 0; c < 4; c++ {
+			product[4*r+c] = m[4*r]*b[c] + m[4*r+1]*b[4+c] + m[4*r+2]*b[8+c] + m[4*r+3]*b[12+c]
+		}
+	}
+	return product
+}
+
+// MulVec4 returns a point transformed by the matrix
+func (m Mat4) MulVec4(v Vec4) Vec4 {
+	var product Vec4
+	for r := 0; r < 4; r++ {
+		product[r] = m[4*r]*v[0] + m[4*r+1]*v[1] + m[4*r+2]*v[2] + m[4*r+3]*v[3]
+	}
+	return product
+}
+
+// Apply returns an edge matrix with each of its points transformed by the
+// matrix
+func (m Mat4) Apply(em *Matrix) *Matrix {
+	product := NewMatrix(em.Rows, em.Cols)
+	x, y, z, w := em.Data[0], em.Data[1], em.Data[2], em.Data[3]
+	for r := 0; r < 4; r++ {
+		row := product.Data[r]
+		m0, m1, m2, m3 := m[4*r], m[4*r+1], m[4*r+2], m[4*r+3]
+		for c := range row {
+			row[c] = m0*x[c] + m1*y[c] + m2*z[c] + m3*w[c]
+		}
+	}
+	// Rows past the point itself are attributes carried along with it
+	for r := 4; r < em.Rows; r++ {
+		copy(product.Data[r], em.Data[r])
+	}
+	return product
+}
+
+// Mirrors returns whether the matrix reflects what it transforms, turning it
+// inside out, which is when the determinant of its linear part is negative
+func (m Mat4) Mirrors() bool {
+	det := m[0]*(m[5]*m[10]-m[6]*m[9]) -
+		m[1]*(m[4]*m[10]-m[6]*m[8]) +
+		m[2]*(m[4]*m[9]-m[5]*m[8])
+	return det < 0
+}
+
+func (m Mat4) String() string {
+	var buffer bytes.Buffer
+	buffer.WriteString("{\n")
+	for r := 0; r < 4; r++ {
+		for c := 0; c < 4; c++ {
+			buffer.WriteString(fmt.Sprintf("%.2f, ", m.At(r, c)))
+		}
+		buffer.WriteString("\n")
+	}
+	buffer.WriteString("}\n")
+	return buffer.String()
+}
+
+// Finite returns whether none of the values are infinite or NaN
+func Finite(values ...float64) bool {
+	for _, v := range values {
+		if math.IsInf(v, 0) || math.IsNaN(v) {
+			return false
+		}
+	}
+	return true
+}
+
+func Clamp(v, min, max float64) float64 {
+	return math.Max(min, math.Min(max, v))
+}
@@ -0,0 +1,569 @@
+package geometry
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+)
+
+const (
+	// StepSize is the number of steps to take when drawing 2D curves
+	StepSize float64 = (1.0 / 100.0)
+	//CircularStepSize is the number of steps to take when drawing 3D curves
+	CircularStepSize float64 = (1.0 / 20.0)
+	// DefaultCircularSteps is the default number of segments around spheres and tori
+	DefaultCircularSteps = int(1.0 / CircularStepSize)
+)
+
+// Matrix represents a matrix
+type Matrix struct {
+	Data [][]float64
+	Rows int
+	Cols int
+}
+
+func (m Matrix) String() string {
+	var buffer bytes.Buffer
+	buffer.WriteString("{\n")
+	for i := 0; i < m.Rows; i++ {
+		for j := 0; j < m.Cols; j++ {
+			buffer.WriteString(fmt.Sprintf("%.2f, ", m.Data[i][j]))
+		}
+		buffer.WriteString("\n")
+	}
+	buffer.WriteString("}\n")
+	return buffer.String()
+}
+
+// NewMatrix returns a new Matrix with a given number of rows and columns
+func NewMatrix(rows, cols int) *Matrix {
+	data := make([][]float64, rows)
+	for i := 0; i < rows; i++ {
+		data[i] = make([]float64, cols)
+	}
+	return &Matrix{
+		Data: data,
+		Rows: rows,
+		Cols: cols,
+	}
+}
+
+// NewMatrixFromData returns a new Matrix with preset data
+func NewMatrixFromData(data [][]float64) *Matrix {
+	return &Matrix{
+		Data: data,
+		Rows: len(data),
+		Cols: len(data[0]),
+	}
+}
+
+// Copy returns a copy of a Matrix
+func (m *Matrix) Copy() *Matrix {
+	return NewMatrixFromData(m.Data)
+}
+
+// Get returns the value at a certain row and column in a Matrix
+func (m *Matrix) Get(r, c int) float64 {
+	return m.Data[r][c]
+}
+
+// GetColumn returns a column of the Matrix
+func (m *Matrix) GetColumn(c int) []float64 {
+	col := make([]float64, m.Rows)
+	for i := 0; i < m.Rows; i++ {
+		col[i] = m.Get(i, c)
+	}
+	return col
+}
+
+// GetMatrix returns a 2D array that represents the matrix
+func (m *Matrix) GetMatrix() [][]float64 {
+	return m.Data
+}
+
+// SetMatrix sets the data for a Matrix
+func (m *Matrix) SetMatrix(data [][]float64) {
+	m.Data = data
+	m.Rows = len(data)
+	m.Cols = len(data[0])
+}
+
+// Scale scales a matrix by a factor
+func (m *Matrix) Scale(n float64) *Matrix {
+	m2 := NewMatrix(m.Rows, m.Cols)
+	for i := 0; i < m.Rows; i++ {
+		for j := 0; j < m.Cols; j++ {
+			m2.Data[i][j] = m.Get(i, j) * n
+		}
+	}
+	return m2
+}
+
+// Multiply returns the product of two Matrices
+func (m *Matrix) Multiply(m2 *Matrix) (*Matrix, error) {
+	if m.Cols != m2.Rows {
+		return nil, fmt.Errorf("column/row mismatch: (%d x %d) * (%d x %d)", m.Rows, m.Cols, m2.Rows, m2.Cols)
+	}
+
+	product := NewMatrix(m.Rows, m2.Cols)
+	for i := 0; i < m.Rows; i++ {
+		for j := 0; j < m2.Cols; j++ {
+			var sum float64
+			for k := 0; k < m.Cols; k++ {
+				sum += m.Get(i, k) * m2.Get(k, j)
+			}
+			product.Data[i][j] = sum
+		}
+	}
+	return product, nil
+}
+
+// AddColumn adds a new column to the matrix
+func (m *Matrix) AddColumn(column []float64) error {
+	if len(column) != m.Rows {
+		return errors.New("incorrect number of rows")
+	}
+	for i, v := range column {
+		m.Data[i] = append(m.Data[i], v)
+	}
+	m.Cols++
+	return nil
+}
+
+// AddMatrix adds the columns of another matrix to the matrix
+func (m *Matrix) AddMatrix(other *Matrix) {
+	for i := range m.Data {
+		m.Data[i] = append(m.Data[i], other.Data[i]...)
+	}
+	m.Cols += other.Cols
+}
+
+// AddMesh adds the vertices listed in a mesh file to the matrix
+func (m *Matrix) AddMesh(filename string) error {
+	f, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// TODO: Legitimize
+		var x, y, z float64
+		num, _ := fmt.Sscanf(scanner.Text(), "vertex %f %f %f", &x, &y, &z)
+		if num == 3 {
+			m.AddPoint(x, y, z)
+		}
+	}
+	return scanner.Err()
+}
+
+// MakeTranslation returns a translation Matrix
+func MakeTranslation(x, y, z float64) Mat4 {
+	return Mat4{
+		1, 0, 0, x,
+		0, 1, 0, y,
+		0, 0, 1, z,
+		0, 0, 0, 1,
+	}
+}
+
+// MakeDilation returns a dilation matrix
+func MakeDilation(sx, sy, sz float64) Mat4 {
+	return Mat4{
+		sx, 0, 0, 0,
+		0, sy, 0, 0,
+		0, 0, sz, 0,
+		0, 0, 0, 1,
+	}
+}
+
+func DegreesToRadians(degrees float64) float64 {
+	return degrees * math.Pi / 180.0
+}
+
+// MakeRotX returns a rotation matrix for the X axis
+// Rotates clockwise when looking towards the origin
+func MakeRotX(theta float64) Mat4 {
+	return Mat4{
+		1, 0, 0, 0,
+		0, math.Cos(theta), -math.Sin(theta), 0,
+		0, math.Sin(theta), math.Cos(theta), 0,
+		0, 0, 0, 1,
+	}
+}
+
+// MakeRotY returns a rotation matrix for the Y axis
+// Rotates clockwise when looking towards the origin
+func MakeRotY(theta float64) Mat4 {
+	return Mat4{
+		math.Cos(theta), 0, math.Sin(theta), 0,
+		0, 1, 0, 0,
+		-math.Sin(theta), 0, math.Cos(theta), 0,
+		0, 0, 0, 1,
+	}
+}
+
+// MakeRotZ returns a rotation matrix for the Z axis
+// Rotates clockwise when looking towards the origin
+func MakeRotZ(theta float64) Mat4 {
+	return Mat4{
+		math.Cos(theta), -math.Sin(theta), 0, 0,
+		math.Sin(theta), math.Cos(theta), 0, 0,
+		0, 0, 1, 0,
+		0, 0, 0, 1,
+	}
+}
+
+// MakeRotArbitrary returns a rotation matrix about the axis (ax, ay, az)
+// through the origin, using Rodrigues' rotation formula
+// Rotates the same way as MakeRotX, MakeRotY, and MakeRotZ about the positive
+// x, y, and z axes.
+func MakeRotArbitrary(ax, ay, az, theta float64) (Mat4, error) {
+	length := math.Sqrt(ax*ax + ay*ay + az*az)
+	if length == 0 {
+		return Identity(), errors.New("rotation axis must not be zero")
+	}
+	x, y, z := ax/length, ay/length, az/length
+	c, s := math.Cos(theta), math.Sin(theta)
+	t := 1 - c
+	return Mat4{
+		t*x*x + c, t*x*y - s*z, t*x*z + s*y, 0,
+		t*x*y + s*z, t*y*y + c, t*y*z - s*x, 0,
+		t*x*z - s*y, t*y*z + s*x, t*z*z + c, 0,
+		0, 0, 0, 1,
+	}, nil
+}
+
+// MakeMirror returns a reflection matrix across the plane where the x, y, or z
+// coordinate is offset
+func MakeMirror(axis string, offset float64) (Mat4, error) {
+	reflection := Identity()
+	switch axis {
+	case "x":
+		reflection[0], reflection[3] = -1, 2*offset
+	case "y":
+		reflection[5], reflection[7] = -1, 2*offset
+	case "z":
+		reflection[10], reflection[11] = -1, 2*offset
+	default:
+		return Identity(), errors.New("axis must be \"x\", \"y\", or \"z\"")
+	}
+	return reflection, nil
+}
+
+// AddPoint adds a point to the matrix as a column
+func (m *Matrix) AddPoint(x, y, z float64) {
+	column := []float64{
+		x,
+		y,
+		z,
+		1.0}
+	m.AddColumn(column)
+}
+
+// AddEdge adds two points to the matrix
+func (m *Matrix) AddEdge(x0, y0, z0, x1, y1, z1 float64) {
+	m.AddPoint(x0, y0, z0)
+	m.AddPoint(x1, y1, z1)
+}
+
+// AddTriangle adds three points to the matrix
+func (m *Matrix) AddTriangle(x0, y0, z0, x1, y1, z1, x2, y2, z2 float64) {
+	m.AddPoint(x0, y0, z0)
+	m.AddPoint(x1, y1, z1)
+	m.AddPoint(x2, y2, z2)
+}
+
+// AddCircle adds a series of points defining a circle to the matrix
+func (m *Matrix) AddCircle(cx, cy, cz, radius float64) {
+	x0 := cx + radius
+	y0 := cy
+
+	for t := 0.0; t <= 1; t += StepSize {
+		theta := 2 * math.Pi * t
+		x1 := radius*math.Cos(theta) + cx
+		y1 := radius*math.Sin(theta) + cy
+		m.AddEdge(x0, y0, cz, x1, y1, cz)
+		x0 = x1
+		y0 = y1
+	}
+}
+
+// AddArc adds a series of points defining an elliptical arc to the matrix
+// The arc lies in the plane through center facing normal, and sweeps
+// counterclockwise around the normal from the start to the end angle in
+// degrees, where 0 degrees points along the rx radius.
+func (m *Matrix) AddArc(center Vec3, rx, ry, start, end float64, normal Vec3) {
+	u, v := PlaneAxes(normal)
+	sweep := end - start
+	steps := int(math.Max(1, math.Ceil(math.Abs(sweep)/360/StepSize)))
+	point := func(i int) Vec3 {
+		theta := (start + sweep*float64(i)/float64(steps)) * math.Pi / 180
+		return center.Add(u.Scale(rx * math.Cos(theta))).Add(v.Scale(ry * math.Sin(theta)))
+	}
+	p0 := point(0)
+	for i := 1; i <= steps; i++ {
+		p1 := point(i)
+		m.AddEdge(p0[0], p0[1], p0[2], p1[0], p1[1], p1[2])
+		p0 = p1
+	}
+}
+
+// PlaneAxes returns two perpendicular unit vectors spanning the plane facing
+// normal, which are the x and y axes when the normal is the z axis
+func PlaneAxes(normal Vec3) (Vec3, Vec3) {
+	n := normal.Normalize()
+	u := Vec3{0, 0, 1}.Cross(n)
+	if u.Length() < 1e-9 {
+		u = Vec3{1, 0, 0}
+	} else {
+		u = u.Normalize()
+	}
+	return u, n.Cross(u)
+}
+
+// Cubic is a curve through space whose coordinates are cubic polynomials of t,
+// from t = 0 to t = 1, given by the coefficients of the polynomial along each
+// axis
+type Cubic [3]*Matrix
+
+// HermiteCubic returns the hermite curve from p0 to p1, where r0 and r1 are the
+// rates of change at each end
+func HermiteCubic(p0, p1, r0, r1 Vec3) Cubic {
+	var c Cubic
+	for i := range c {
+		c[i] = generateHermiteCoefficients(p0[i], r0[i], p1[i], r1[i])
+	}
+	return c
+}
+
+// BezierCubic returns the cubic bezier curve from p0 to p3, with p1 and p2 as
+// control points
+func BezierCubic(p0, p1, p2, p3 Vec3) Cubic {
+	var c Cubic
+	for i := range c {
+		c[i] = generateBezierCoefficients(p0[i], p1[i], p2[i], p3[i])
+	}
+	return c
+}
+
+// At returns the point on the curve at t
+func (c Cubic) At(t float64) Vec3 {
+	tSquared := t * t
+	tCubed := tSquared * t
+	var p Vec3
+	for i, axis := range c {
+		p[i] = axis.Get(0, 0)*tCubed + axis.Get(1, 0)*tSquared + axis.Get(2, 0)*t + axis.Get(3, 0)
+	}
+	return p
+}
+
+// Tangent returns the derivative of the curve at t
+func (c Cubic) Tangent(t float64) Vec3 {
+	var d Vec3
+	for i, axis := range c {
+		d[i] = 3*axis.Get(0, 0)*t*t + 2*axis.Get(1, 0)*t + axis.Get(2, 0)
+	}
+	return d
+}
+
+// AddHermite adds a series of points defining a hermite curve from p0 to p1 to
+// the matrix, where r0 and r1 are the rates of change at each end
+func (m *Matrix) AddHermite(p0, p1, r0, r1 Vec3) {
+	m.addCubic(HermiteCubic(p0, p1, r0, r1), p0)
+}
+
+// addCubic adds a series of points along a cubic curve starting at start to
+// the matrix
+func (m *Matrix) addCubic(curve Cubic, start Vec3) {
+	p0 := start
+	for t := 0.0; t <= 1; t += StepSize {
+		p1 := curve.At(t)
+		m.AddEdge(p0[0], p0[1], p0[2], p1[0], p1[1], p1[2])
+		p0 = p1
+	}
+}
+
+func generateHermiteCoefficients(p0, m0, p1, m1 float64) *Matrix {
+	hermite := NewMatrixFromData([][]float64{
+		{2, -2, 1, 1},
+		{-3, 3, -2, -1},
+		{0, 0, 1, 0},
+		{1, 0, 0, 0},
+	})
+	coefficients := NewMatrixFromData([][]float64{
+		{p0},
+		{p1},
+		{m0},
+		{m1},
+	})
+	m, _ := hermite.Multiply(coefficients)
+	return m
+}
+
+// AddBezier adds a series of points defining a cubic bezier curve from p0 to
+// p3 to the matrix, with p1 and p2 as control points
+func (m *Matrix) AddBezier(p0, p1, p2, p3 Vec3) {
+	m.addCubic(BezierCubic(p0, p1, p2, p3), p0)
+}
+
+func generateBezierCoefficients(p0, p1, p2, p3 float64) *Matrix {
+	bezier := NewMatrixFromData([][]float64{
+		{-1, 3, -3, 1},
+		{3, -6, 3, 0},
+		{-3, 3, 0, 0},
+		{1, 0, 0, 0},
+	})
+	coefficients := NewMatrixFromData([][]float64{
+		{p0},
+		{p1},
+		{p2},
+		{p3},
+	})
+	m, _ := bezier.Multiply(coefficients)
+	return m
+}
+
+// AddBox adds a series of points defining a 3D box to the matrix
+func (m *Matrix) AddBox(x, y, z, width, height, depth float64) {
+	x1 := x + width
+	y1 := y - height
+	z1 := z - depth
+
+	// Front
+	m.AddTriangle(x, y, z, x1, y1, z, x1, y, z)
+	m.AddTriangle(x, y, z, x, y1, z, x1, y1, z)
+	// Back
+	m.AddTriangle(x1, y, z1, x, y1, z1, x, y, z1)
+	m.AddTriangle(x1, y, z1, x1, y1, z1, x, y1, z1)
+	// Top
+	m.AddTriangle(x, y, z1, x1, y, z, x1, y, z1)
+	m.AddTriangle(x, y, z1, x, y, z, x1, y, z)
+	// Bottom
+	m.AddTriangle(x, y1, z, x1, y1, z1, x1, y1, z)
+	m.AddTriangle(x, y1, z, x, y1, z1, x1, y1, z1)
+	// Left
+	m.AddTriangle(x, y, z1, x, y1, z, x, y, z)
+	m.AddTriangle(x, y, z1, x, y1, z1, x, y1, z)
+	// Right
+	m.AddTriangle(x1, y, z, x1, y1, z1, x1, y, z1)
+	m.AddTriangle(x1, y, z, x1, y1, z, x1, y1, z1)
+}
+
+// AddSphere adds a series of points defining a 3D sphere to the matrix
+// The sphere is divided into the given number of segments around each axis
+func (m *Matrix) AddSphere(cx, cy, cz, radius float64, segments int) {
+	points := NewMatrix(4, 0)
+	points.generateSphere(cx, cy, cz, radius, segments)
+	steps := segments + 1
+	endLatitude := steps - 1
+	endLongitude := steps - 1
+	modulus := points.Cols
+	for latitude := 0; latitude < endLatitude; latitude++ {
+		start := latitude * steps
+		nextStart := (start + steps) % modulus
+		for longitude := 0; longitude < endLongitude; longitude++ {
+			p0 := start + longitude
+			p1 := p0 + 1
+			p2 := nextStart + longitude
+			p3 := p2 + 1
+
+			if longitude > 0 {
+				m.AddTriangle(
+					points.Get(0, p0), points.Get(1, p0), points.Get(2, p0),
+					points.Get(0, p3), points.Get(1, p3), points.Get(2, p3),
+					points.Get(0, p2), points.Get(1, p2), points.Get(2, p2))
+			}
+			if longitude != endLongitude-1 {
+				m.AddTriangle(
+					points.Get(0, p3), points.Get(1, p3), points.Get(2, p3),
+					points.Get(0, p0), points.Get(1, p0), points.Get(2, p0),
+					points.Get(0, p1), points.Get(1, p1), points.Get(2, p1))
+			}
+		}
+	}
+}
+
+func (m *Matrix) generateSphere(cx, cy, cz, radius float64, segments int) {
+	steps := float64(segments)
+	for r := 0.0; r < steps; r++ {
+		phi := math.Pi * (2 * r / steps)
+		rCosPhi := radius * math.Cos(phi)
+		rSinPhi := radius * math.Sin(phi)
+		for c := 0.0; c <= steps; c++ {
+			theta := math.Pi * (c / steps)
+			cosTheta := math.Cos(theta)
+			sinTheta := math.Sin(theta)
+
+			x := cx + radius*cosTheta
+			y := cy + sinTheta*rCosPhi
+			z := cz + sinTheta*rSinPhi
+			m.AddPoint(x, y, z)
+		}
+	}
+}
+
+// AddParticles adds particles to the matrix, as points if size is 0 or as
+// spheres of that radius divided into the given number of segments otherwise
+func (m *Matrix) AddParticles(particles []Vec3, size float64, segments int) {
+	for _, p := range particles {
+		if size == 0 {
+			m.AddEdge(p[0], p[1], p[2], p[0], p[1], p[2])
+		} else {
+			m.AddSphere(p[0], p[1], p[2], size, segments)
+		}
+	}
+}
+
+// AddTorus adds a series of points defining a 3D torus to the matrix
+// The torus is divided into the given number of segments around each circle
+func (m *Matrix) AddTorus(cx, cy, cz, r1, r2 float64, segments int) {
+	points := NewMatrix(4, 0)
+	points.generateTorus(cx, cy, cz, r1, r2, segments)
+	steps := segments
+	endLatitude := steps
+	endLongitude := steps
+	modulus := points.Cols
+	for latitude := 0; latitude < endLatitude; latitude++ {
+		start := latitude * steps
+		for longitude := 0; longitude < endLongitude; longitude++ {
+			p0 := start + longitude
+			p1 := p0 + 1
+			if longitude == endLongitude-1 {
+				p1 = p0 - longitude
+			}
+			p2 := (p1 + steps) % modulus
+			p3 := (p0 + steps) % modulus
+			m.AddTriangle(
+				points.Get(0, p0), points.Get(1, p0), points.Get(2, p0),
+				points.Get(0, p3), points.Get(1, p3), points.Get(2, p3),
+				points.Get(0, p2), points.Get(1, p2), points.Get(2, p2))
+			m.AddTriangle(
+				points.Get(0, p0), points.Get(1, p0), points.Get(2, p0),
+				points.Get(0, p2), points.Get(1, p2), points.Get(2, p2),
+				points.Get(0, p1), points.Get(1, p1), points.Get(2, p1))
+		}
+	}
+}
+
+func (m *Matrix) generateTorus(cx, cy, cz, r1, r2 float64, segments int) {
+	steps := float64(segments)
+	for r := 0.0; r < steps; r++ {
+		phi := math.Pi * (2 * r / steps)
+		cosPhi := math.Cos(phi)
+		sinPhi := math.Sin(phi)
+		for c := 0.0; c < steps; c++ {
+			theta := math.Pi * (2 * c / steps)
+			cosTheta := math.Cos(theta)
+			sinTheta := math.Sin(theta)
+
+			x := cosPhi*(r1*cosTheta+r2) + cx
+			y := r1*sinTheta + cy
+			z := sinPhi*(r1*cosTheta+r2) + cz
+			m.AddPoint(x, y, z)
+		}
+	}
+}
@@ -0,0 +1,225 @@
+package geometry
+
+import (
+	"errors"
+	"math"
+)
+
+// DefaultMetaballResolution is the number of cubes that metaballs are divided
+// into along the longest side of their bounds, unless they give their own
+const DefaultMetaballResolution = 32
+
+// MaxMetaballResolution keeps metaballs from taking minutes to polygonize
+const MaxMetaballResolution = 200
+
+// Metaball is a ball of a field that blends into the metaballs around it
+// A lone metaball of strength 1 has the given radius, stronger ones are larger,
+// and negative ones carve into the metaballs around them.
+type Metaball struct {
+	Center   Vec3
+	Radius   float64
+	Strength float64
+}
+
+// metaballField returns the strength of the field of the metaballs at a point,
+// which is 1 on their surface and greater inside it
+func metaballField(balls []Metaball, p Vec3) float64 {
+	field := 0.0
+	for _, ball := range balls {
+		d := p.Sub(ball.Center)
+		field += ball.Strength * ball.Radius * ball.Radius / math.Max(d.Dot(d), 1e-12)
+	}
+	return field
+}
+
+// metaballGradient returns the direction the field of the metaballs grows
+// fastest in at a point, which points into their surface
+func metaballGradient(balls []Metaball, p Vec3) Vec3 {
+	var gradient Vec3
+	for _, ball := range balls {
+		d := p.Sub(ball.Center)
+		d2 := math.Max(d.Dot(d), 1e-12)
+		gradient = gradient.Add(d.Scale(-2 * ball.Strength * ball.Radius * ball.Radius / (d2 * d2)))
+	}
+	return gradient
+}
+
+// cubeEdges are the corners at each end of the edges of a cube, where corner i
+// is offset by its bits along x, y, and z
+var cubeEdges = [12][2]int{
+	{0, 1}, {2, 3}, {4, 5}, {6, 7}, // along x
+	{0, 2}, {1, 3}, {4, 6}, {5, 7}, // along y
+	{0, 4}, {1, 5}, {2, 6}, {3, 7}, // along z
+}
+
+// cubeFaces are the corners around each face of a cube, in order, along with
+// the edges between them
+var cubeFaces = [6]struct{ corners, edges [4]int }{
+	{[4]int{0, 2, 6, 4}, [4]int{4, 10, 6, 8}},  // x = 0
+	{[4]int{1, 3, 7, 5}, [4]int{5, 11, 7, 9}},  // x = 1
+	{[4]int{0, 1, 5, 4}, [4]int{0, 9, 2, 8}},   // y = 0
+	{[4]int{2, 3, 7, 6}, [4]int{1, 11, 3, 10}}, // y = 1
+	{[4]int{0, 1, 3, 2}, [4]int{0, 5, 1, 4}},   // z = 0
+	{[4]int{4, 5, 7, 6}, [4]int{2, 7, 3, 6}},   // z = 1
+}
+
+// AddMetaballs adds the triangles of the surface of metaballs to the matrix,
+// found by marching cubes over a grid with resolution cubes along the longest
+// side of their bounds
+// The polygons in each cube are traced around its faces instead of looked up in
+// a table, which settles the faces where the surface could cross either way by
+// the field at their center, so that neighboring cubes always agree.
+func (m *Matrix) AddMetaballs(balls []Metaball, resolution int) error {
+	if resolution < 2 || resolution > MaxMetaballResolution {
+		return errors.New("metaball resolution must be between 2 and 200")
+	}
+	// Alone, each of n metaballs is weaker than 1/n beyond the square root of
+	// n times its strength times its radius, so together they are too
+	low, high := Vec3{math.Inf(1), math.Inf(1), math.Inf(1)}, Vec3{math.Inf(-1), math.Inf(-1), math.Inf(-1)}
+	positive := 0
+	for _, ball := range balls {
+		if ball.Strength > 0 {
+			positive++
+		}
+	}
+	for _, ball := range balls {
+		if ball.Strength <= 0 {
+			continue
+		}
+		reach := ball.Radius * math.Sqrt(float64(positive)*ball.Strength)
+		for i := range low {
+			low[i] = math.Min(low[i], ball.Center[i]-reach)
+			high[i] = math.Max(high[i], ball.Center[i]+reach)
+		}
+	}
+	if positive == 0 {
+		return nil
+	}
+
+	size := math.Max(high[0]-low[0], math.Max(high[1]-low[1], high[2]-low[2]))
+	step := size / float64(resolution)
+	var cells [3]int
+	for i := range cells {
+		// Pad the bounds by a cube, so the surface is closed along them
+		cells[i] = int(math.Ceil((high[i]-low[i])/step)) + 2
+		low[i] -= step
+	}
+	point := func(x, y, z int) Vec3 {
+		return Vec3{low[0] + float64(x)*step, low[1] + float64(y)*step, low[2] + float64(z)*step}
+	}
+	index := func(x, y, z int) int {
+		return (z*(cells[1]+1)+y)*(cells[0]+1) + x
+	}
+	field := make([]float64, (cells[0]+1)*(cells[1]+1)*(cells[2]+1))
+	for z := 0; z <= cells[2]; z++ {
+		for y := 0; y <= cells[1]; y++ {
+			for x := 0; x <= cells[0]; x++ {
+				field[index(x, y, z)] = metaballField(balls, point(x, y, z))
+			}
+		}
+	}
+
+	var corners [8]Vec3
+	var values [8]float64
+	var crossings [12]Vec3
+	for z := 0; z < cells[2]; z++ {
+		for y := 0; y < cells[1]; y++ {
+			for x := 0; x < cells[0]; x++ {
+				inside := 0
+				for i := range corners {
+					cx, cy, cz := x+i&1, y+i>>1&1, z+i>>2&1
+					corners[i] = point(cx, cy, cz)
+					values[i] = field[index(cx, cy, cz)]
+					if values[i] >= 1 {
+						inside++
+					}
+				}
+				if inside == 0 || inside == 8 {
+					continue
+				}
+				for i, edge := range cubeEdges {
+					a, b := edge[0], edge[1]
+					if (values[a] >= 1) != (values[b] >= 1) {
+						t := (1 - values[a]) / (values[b] - values[a])
+						crossings[i] = corners[a].Add(corners[b].Sub(corners[a]).Scale(t))
+					}
+				}
+				for _, loop := range cubeLoops(values) {
+					for i := 1; i+1 < len(loop); i++ {
+						p0, p1, p2 := crossings[loop[0]], crossings[loop[i]], crossings[loop[i+1]]
+						// Face the triangle out of the surface, against the
+						// gradient of the field
+						center := p0.Add(p1).Add(p2).Scale(1.0 / 3)
+						if Normal(p0, p1, p2).Dot(metaballGradient(balls, center)) > 0 {
+							p1, p2 = p2, p1
+						}
+						m.addTriangle(p0, p1, p2)
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// cubeLoops returns the closed loops of edges that the surface crosses in a
+// cube with the field at each corner
+func cubeLoops(values [8]float64) [][]int {
+	// Each face the surface crosses links pairs of its edges, and each edge
+	// is shared by two faces, so the links form closed loops
+	var links [12][]int
+	link := func(a, b int) {
+		links[a] = append(links[a], b)
+		links[b] = append(links[b], a)
+	}
+	for _, face := range cubeFaces {
+		var crossed []int
+		for i, edge := range face.edges {
+			if (values[face.corners[i]] >= 1) != (values[face.corners[(i+1)%4]] >= 1) {
+				crossed = append(crossed, edge)
+			}
+		}
+		switch len(crossed) {
+		case 2:
+			link(crossed[0], crossed[1])
+		case 4:
+			// Opposite corners are inside, and the field at the center of
+			// the face decides whether they are joined or kept apart
+			center := 0.0
+			for _, corner := range face.corners {
+				center += values[corner] / 4
+			}
+			first := 0
+			if (values[face.corners[0]] >= 1) != (center >= 1) {
+				// Cut off the first corner, instead of the second
+				first = 1
+			}
+			link(face.edges[first], face.edges[(first+1)%4])
+			link(face.edges[(first+2)%4], face.edges[(first+3)%4])
+		}
+	}
+
+	var loops [][]int
+	var visited [12]bool
+	for start := range links {
+		if visited[start] || len(links[start]) == 0 {
+			continue
+		}
+		loop := []int{start}
+		visited[start] = true
+		for previous, current := -1, start; ; {
+			next := links[current][0]
+			if next == previous || visited[next] && next != start {
+				next = links[current][1]
+			}
+			if next == start {
+				break
+			}
+			loop = append(loop, next)
+			visited[next] = true
+			previous, current = current, next
+		}
+		loops = append(loops, loop)
+	}
+	return loops
+}
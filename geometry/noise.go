@@ -0,0 +1,142 @@
+package geometry
+
+import "math"
+
+// DisplaceOctaves is the number of octaves of noise that surfaces are
+// displaced by
+const DisplaceOctaves = 4
+
+// permutation is Ken Perlin's reference permutation of 0 to 255, repeated so
+// that it can be indexed past 255 without wrapping
+var permutation [512]int
+
+func init() {
+	reference := [256]int{
+		151, 160, 137, 91, 90, 15, 131, 13, 201, 95, 96, 53, 194, 233, 7, 225,
+		140, 36, 103, 30, 69, 142, 8, 99, 37, 240, 21, 10, 23, 190, 6, 148,
+		247, 120, 234, 75, 0, 26, 197, 62, 94, 252, 219, 203, 117, 35, 11, 32,
+		57, 177, 33, 88, 237, 149, 56, 87, 174, 20, 125, 136, 171, 168, 68, 175,
+		74, 165, 71, 134, 139, 48, 27, 166, 77, 146, 158, 231, 83, 111, 229, 122,
+		60, 211, 133, 230, 220, 105, 92, 41, 55, 46, 245, 40, 244, 102, 143, 54,
+		65, 25, 63, 161, 1, 216, 80, 73, 209, 76, 132, 187, 208, 89, 18, 169,
+		200, 196, 135, 130, 116, 188, 159, 86, 164, 100, 109, 198, 173, 186, 3, 64,
+		52, 217, 226, 250, 124, 123, 5, 202, 38, 147, 118, 126, 255, 82, 85, 212,
+		207, 206, 59, 227, 47, 16, 58, 17, 182, 189, 28, 42, 223, 183, 170, 213,
+		119, 248, 152, 2, 44, 154, 163, 70, 221, 153, 101, 155, 167, 43, 172, 9,
+		129, 22, 39, 253, 19, 98, 108, 110, 79, 113, 224, 232, 178, 185, 112, 104,
+		218, 246, 97, 228, 251, 34, 242, 193, 238, 210, 144, 12, 191, 179, 162, 241,
+		81, 51, 145, 235, 249, 14, 239, 107, 49, 192, 214, 31, 181, 199, 106, 157,
+		184, 84, 204, 176, 115, 121, 50, 45, 127, 4, 150, 254, 138, 236, 205, 93,
+		222, 114, 67, 29, 24, 72, 243, 141, 128, 195, 78, 66, 215, 61, 156, 180,
+	}
+	for i := range permutation {
+		permutation[i] = reference[i%256]
+	}
+}
+
+// Noise returns Perlin's improved gradient noise at a point, which varies
+// smoothly between about -1 and 1 with features about 1 apart
+func Noise(x, y, z float64) float64 {
+	fx, fy, fz := math.Floor(x), math.Floor(y), math.Floor(z)
+	// The unit cube around the point, and the point within it
+	X, Y, Z := int(fx)&255, int(fy)&255, int(fz)&255
+	x, y, z = x-fx, y-fy, z-fz
+	u, v, w := fade(x), fade(y), fade(z)
+
+	A := permutation[X] + Y
+	AA, AB := permutation[A]+Z, permutation[A+1]+Z
+	B := permutation[X+1] + Y
+	BA, BB := permutation[B]+Z, permutation[B+1]+Z
+
+	return Lerp(w,
+		Lerp(v,
+			Lerp(u, grad(permutation[AA], x, y, z), grad(permutation[BA], x-1, y, z)),
+			Lerp(u, grad(permutation[AB], x, y-1, z), grad(permutation[BB], x-1, y-1, z))),
+		Lerp(v,
+			Lerp(u, grad(permutation[AA+1], x, y, z-1), grad(permutation[BA+1], x-1, y, z-1)),
+			Lerp(u, grad(permutation[AB+1], x, y-1, z-1), grad(permutation[BB+1], x-1, y-1, z-1))))
+}
+
+// FractalNoise returns the sum of octaves of noise, each with twice the detail
+// and half the strength of the last, scaled back to between about -1 and 1
+func FractalNoise(x, y, z float64, octaves int) float64 {
+	sum, strength, total := 0.0, 1.0, 0.0
+	for i := 0; i < octaves; i++ {
+		sum += Noise(x, y, z) * strength
+		total += strength
+		x, y, z = 2*x, 2*y, 2*z
+		strength /= 2
+	}
+	return sum / total
+}
+
+// fade eases t between 0 and 1 so that noise is smooth across the edges of
+// cubes
+func fade(t float64) float64 {
+	return t * t * t * (t*(t*6-15) + 10)
+}
+
+func Lerp(t, a, b float64) float64 {
+	return a + t*(b-a)
+}
+
+// grad returns the dot product of the point with one of twelve gradients
+// picked by hash
+func grad(hash int, x, y, z float64) float64 {
+	h := hash & 15
+	u := y
+	if h < 8 {
+		u = x
+	}
+	v := z
+	if h < 4 {
+		v = y
+	} else if h == 12 || h == 14 {
+		v = x
+	}
+	if h&1 != 0 {
+		u = -u
+	}
+	if h&2 != 0 {
+		v = -v
+	}
+	return u + v
+}
+
+// Displace moves each corner of the triangles in the matrix along the normal
+// of the surface there by fractal noise, with features about size apart and
+// moving at most about amplitude
+// Corners at the same position move together, so the surface doesn't tear.
+func (m *Matrix) Displace(size, amplitude float64) {
+	key := func(p []float64) Vec3 {
+		// Round so that the seams of generated shapes are joined
+		return Vec3{math.Round(p[0]*1e3) / 1e3, math.Round(p[1]*1e3) / 1e3, math.Round(p[2]*1e3) / 1e3}
+	}
+	// Sum the normals of the triangles around each position, weighted by their
+	// area, skipping the degenerate ones at the poles of spheres
+	normals := make(map[Vec3]Vec3)
+	for i := 0; i+2 < m.Cols; i += 3 {
+		normal := Normal(Vec3Of(m.GetColumn(i)), Vec3Of(m.GetColumn(i+1)), Vec3Of(m.GetColumn(i+2)))
+		if normal.Length() == 0 || !Finite(normal[0], normal[1], normal[2]) {
+			continue
+		}
+		for j := i; j < i+3; j++ {
+			k := key(m.GetColumn(j))
+			normals[k] = normals[k].Add(normal)
+		}
+	}
+	offsets := make(map[Vec3]Vec3, len(normals))
+	for k, normal := range normals {
+		if normal.Length() == 0 {
+			continue
+		}
+		offset := FractalNoise(k[0]/size, k[1]/size, k[2]/size, DisplaceOctaves) * amplitude
+		offsets[k] = normal.Normalize().Scale(offset)
+	}
+	for i := 0; i < m.Cols; i++ {
+		offset := offsets[key(m.GetColumn(i))]
+		for j := 0; j < 3; j++ {
+			m.Data[j][i] += offset[j]
+		}
+	}
+}
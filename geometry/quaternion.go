@@ -0,0 +1,97 @@
+package geometry
+
+import (
+	"errors"
+	"math"
+)
+
+// Quaternion is a rotation, as w + xi + yj + zk of length 1
+// Unlike angles about the x, y, and z axes, quaternions can be interpolated
+// without the rotation wobbling or locking up part of the way.
+type Quaternion struct {
+	w, x, y, z float64
+}
+
+// IdentityQuaternion is no rotation at all
+var IdentityQuaternion = Quaternion{1, 0, 0, 0}
+
+// QuaternionAbout returns the rotation by theta radians about the axis
+// (ax, ay, az) through the origin, which turns the same way as
+// MakeRotArbitrary
+func QuaternionAbout(ax, ay, az, theta float64) (Quaternion, error) {
+	length := math.Sqrt(ax*ax + ay*ay + az*az)
+	if length == 0 {
+		return IdentityQuaternion, errors.New("rotation axis must not be zero")
+	}
+	s := math.Sin(theta/2) / length
+	return Quaternion{math.Cos(theta / 2), ax * s, ay * s, az * s}, nil
+}
+
+// Dot returns the dot product of two quaternions, which is the cosine of half
+// the angle between the rotations
+func (q Quaternion) Dot(r Quaternion) float64 {
+	return q.w*r.w + q.x*r.x + q.y*r.y + q.z*r.z
+}
+
+// Normalize returns the quaternion scaled to a length of 1
+func (q Quaternion) Normalize() Quaternion {
+	length := math.Sqrt(q.Dot(q))
+	if length == 0 {
+		return IdentityQuaternion
+	}
+	return Quaternion{q.w / length, q.x / length, q.y / length, q.z / length}
+}
+
+// Slerp returns the rotation t of the way from q to r, turning at a steady
+// rate along the shorter great circle between them
+// A t outside of 0 to 1 carries on turning past q or r.
+func (q Quaternion) Slerp(r Quaternion, t float64) Quaternion {
+	cos := q.Dot(r)
+	// q and -q are the same rotation, and only one of them is the short way
+	if cos < 0 {
+		r = Quaternion{-r.w, -r.x, -r.y, -r.z}
+		cos = -cos
+	}
+	a, b := 1-t, t
+	// Nearly equal rotations would divide by nearly zero, and interpolating
+	// them straight is just as good
+	if cos < 0.9995 {
+		theta := math.Acos(cos)
+		sin := math.Sin(theta)
+		a, b = math.Sin((1-t)*theta)/sin, math.Sin(t*theta)/sin
+	}
+	return Quaternion{
+		a*q.w + b*r.w,
+		a*q.x + b*r.x,
+		a*q.y + b*r.y,
+		a*q.z + b*r.z,
+	}.Normalize()
+}
+
+// Matrix returns the rotation matrix of the quaternion
+func (q Quaternion) Matrix() Mat4 {
+	w, x, y, z := q.w, q.x, q.y, q.z
+	return Mat4{
+		1 - 2*(y*y+z*z), 2 * (x*y - w*z), 2 * (x*z + w*y), 0,
+		2 * (x*y + w*z), 1 - 2*(x*x+z*z), 2 * (y*z - w*x), 0,
+		2 * (x*z - w*y), 2 * (y*z + w*x), 1 - 2*(x*x+y*y), 0,
+		0, 0, 0, 1,
+	}
+}
+
+// Keyframe returns the rotation at a place along a series of keyframes, where
+// each whole number is the next keyframe and fractions lie between them
+// A single keyframe is reached from no rotation at 0, like the other
+// transformations scaled by a knob.
+func Keyframe(keys []Quaternion, at float64) Quaternion {
+	if len(keys) == 1 {
+		return IdentityQuaternion.Slerp(keys[0], at)
+	}
+	i := int(math.Floor(at))
+	if i < 0 {
+		i = 0
+	} else if i > len(keys)-2 {
+		i = len(keys) - 2
+	}
+	return keys[i].Slerp(keys[i+1], Clamp(at-float64(i), 0, 1))
+}
@@ -1,4 +1,4 @@
-package main
+package geometry
 
 import (
 	"bytes"
@@ -6,20 +6,21 @@ import (
 
 // Stack is a stack of matrices
 type Stack struct {
-	stack []*Matrix
+	stack []Mat4
 }
 
 // NewStack returns a new stack
 func NewStack() *Stack {
 	return &Stack{
-		stack: make([]*Matrix, 0, 10),
+		stack: make([]Mat4, 0, 10),
 	}
 }
 
 // Pop returns and removes the top matrix in the stack
-func (s *Stack) Pop() *Matrix {
+// An empty stack pops the identity matrix.
+func (s *Stack) Pop() Mat4 {
 	if s.IsEmpty() {
-		return nil
+		return Identity()
 	}
 	length := len(s.stack)
 	ret := s.stack[length-1]
@@ -28,19 +29,29 @@ func (s *Stack) Pop() *Matrix {
 }
 
 // Push pushes a new matrix onto the stack
-func (s *Stack) Push(m *Matrix) {
+func (s *Stack) Push(m Mat4) {
 	s.stack = append(s.stack, m)
 }
 
 // Peek returns the top matrix in the stack
-func (s *Stack) Peek() *Matrix {
+// An empty stack has the identity matrix on top.
+func (s *Stack) Peek() Mat4 {
 	if s.IsEmpty() {
-		return nil
+		return Identity()
 	}
 	length := len(s.stack)
 	return s.stack[length-1]
 }
 
+// Copy returns a copy of the stack
+func (s *Stack) Copy() *Stack {
+	stack := make([]Mat4, len(s.stack), cap(s.stack))
+	copy(stack, s.stack)
+	return &Stack{
+		stack: stack,
+	}
+}
+
 // IsEmpty returns true if the stack is empty, false otherwise
 func (s *Stack) IsEmpty() bool {
 	return len(s.stack) == 0
@@ -0,0 +1,129 @@
+package geometry
+
+import (
+	"errors"
+	"math"
+)
+
+// SweepSteps is the number of segments a profile is swept along its path in
+const SweepSteps = 64
+
+// Profile is a cross-section that is swept along a path, given as points in
+// the plane across the path
+// Profiles of two points are open strips, and longer ones are closed outlines
+// wound counterclockwise.
+type Profile [][2]float64
+
+// NewProfile returns the profile through points given as x y pairs, winding
+// closed outlines counterclockwise so that they face outwards once swept
+func NewProfile(points []float64) (Profile, error) {
+	if len(points)%2 != 0 {
+		return nil, errors.New("profile points must be x y pairs")
+	}
+	if len(points) < 4 {
+		return nil, errors.New("a profile needs at least two points")
+	}
+	profile := make(Profile, len(points)/2)
+	for i := range profile {
+		profile[i] = [2]float64{points[2*i], points[2*i+1]}
+	}
+	// The shoelace formula gives the signed area, which is negative for
+	// clockwise outlines
+	area := 0.0
+	for i, p := range profile {
+		q := profile[(i+1)%len(profile)]
+		area += p[0]*q[1] - q[0]*p[1]
+	}
+	if len(profile) > 2 && area < 0 {
+		for i, j := 0, len(profile)-1; i < j; i, j = i+1, j-1 {
+			profile[i], profile[j] = profile[j], profile[i]
+		}
+	}
+	return profile, nil
+}
+
+// CircleProfile returns a profile approximating a circle with the given number
+// of sides
+func CircleProfile(radius float64, sides int) Profile {
+	profile := make(Profile, sides)
+	for i := range profile {
+		theta := 2 * math.Pi * float64(i) / float64(sides)
+		profile[i] = [2]float64{radius * math.Cos(theta), radius * math.Sin(theta)}
+	}
+	return profile
+}
+
+// AddSweep adds the triangles of a profile swept along a path to the matrix
+// The profile is carried along the path by rotation minimizing frames (found
+// by double reflection), so it doesn't twist, and its axes start out as the
+// axes of the plane across the start of the path. The ends are left open.
+func (m *Matrix) AddSweep(profile Profile, path Cubic, steps int) {
+	rings := make([][]Vec3, steps+1)
+	point := path.At(0)
+	tangent := sweepTangent(path, 0, Vec3{0, 0, 1})
+	u, _ := PlaneAxes(tangent)
+	for i := range rings {
+		if i > 0 {
+			t := float64(i) / float64(steps)
+			next := path.At(t)
+			nextTangent := sweepTangent(path, t, tangent)
+			// Reflect the frame across the plane between the two points, and
+			// then across the plane that brings the tangents together
+			if v1 := next.Sub(point); v1.Dot(v1) > 0 {
+				c1 := v1.Dot(v1)
+				u = u.Sub(v1.Scale(2 / c1 * v1.Dot(u)))
+				reflected := tangent.Sub(v1.Scale(2 / c1 * v1.Dot(tangent)))
+				if v2 := nextTangent.Sub(reflected); v2.Dot(v2) > 0 {
+					u = u.Sub(v2.Scale(2 / v2.Dot(v2) * v2.Dot(u)))
+				}
+			}
+			// Keep the frame perpendicular to the path despite rounding
+			u = u.Sub(nextTangent.Scale(u.Dot(nextTangent))).Normalize()
+			point, tangent = next, nextTangent
+		}
+		v := tangent.Cross(u)
+		ring := make([]Vec3, len(profile))
+		for j, q := range profile {
+			ring[j] = point.Add(u.Scale(q[0])).Add(v.Scale(q[1]))
+		}
+		rings[i] = ring
+	}
+
+	sides := len(profile)
+	if sides == 2 {
+		// An open strip is seen from both sides
+		sides = 1
+	}
+	for i := 0; i < steps; i++ {
+		for j := 0; j < sides; j++ {
+			k := (j + 1) % len(profile)
+			a, b, c, d := rings[i][j], rings[i][k], rings[i+1][k], rings[i+1][j]
+			m.addTriangle(a, b, c)
+			m.addTriangle(a, c, d)
+			if len(profile) == 2 {
+				m.addTriangle(a, c, b)
+				m.addTriangle(a, d, c)
+			}
+		}
+	}
+}
+
+// sweepTangent returns the direction of a path at t, or fallback where the
+// path doesn't move
+func sweepTangent(path Cubic, t float64, fallback Vec3) Vec3 {
+	tangent := path.Tangent(t)
+	if tangent.Length() < 1e-9 {
+		// The derivative vanishes where control points coincide, but the
+		// path still has a direction on either side
+		tangent = path.At(math.Min(t+1e-3, 1)).Sub(path.At(math.Max(t-1e-3, 0)))
+		if tangent.Length() < 1e-9 {
+			return fallback
+		}
+	}
+	return tangent.Normalize()
+}
+
+// addTriangle adds a triangle with the given corners to the matrix
+func (m *Matrix) addTriangle(p0, p1, p2 Vec3) {
+	m.AddTriangle(p0[0], p0[1], p0[2], p1[0], p1[1], p1[2], p2[0], p2[1], p2[2])
+}
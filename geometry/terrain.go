@@ -0,0 +1,116 @@
+package geometry
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+)
+
+// DefaultTerrainDetail is the detail of terrain that doesn't give its own
+const DefaultTerrainDetail = 6
+
+// MaxTerrainDetail keeps terrain from growing past a few million triangles
+const MaxTerrainDetail = 10
+
+// Heightmap is a square grid of heights between 0 and 1, indexed by row along
+// the depth of the terrain and then by column along its width
+type Heightmap [][]float64
+
+// NewHeightmap returns a heightmap of 2^detail + 1 points on a side made by the
+// diamond-square algorithm, drawing its displacements from random
+// Roughness is the fraction of the displacement kept from one level of detail
+// to the next, from smooth hills near 0 to jagged peaks near 1.
+func NewHeightmap(detail int, roughness float64, random *rand.Rand) (Heightmap, error) {
+	if detail < 1 || detail > MaxTerrainDetail {
+		return nil, errors.New("terrain detail must be between 1 and 10")
+	}
+	if roughness <= 0 || roughness > 1 {
+		return nil, errors.New("terrain roughness must be greater than 0 and at most 1")
+	}
+	size := 1<<uint(detail) + 1
+	heights := make(Heightmap, size)
+	for i := range heights {
+		heights[i] = make([]float64, size)
+	}
+	displace := func(scale float64) float64 {
+		return (2*random.Float64() - 1) * scale
+	}
+
+	last := size - 1
+	heights[0][0] = displace(1)
+	heights[0][last] = displace(1)
+	heights[last][0] = displace(1)
+	heights[last][last] = displace(1)
+	scale := roughness
+	for step := last; step > 1; step /= 2 {
+		half := step / 2
+		// Diamond step: the center of each square is the average of its
+		// corners
+		for row := half; row < size; row += step {
+			for col := half; col < size; col += step {
+				average := (heights[row-half][col-half] + heights[row-half][col+half] +
+					heights[row+half][col-half] + heights[row+half][col+half]) / 4
+				heights[row][col] = average + displace(scale)
+			}
+		}
+		// Square step: the middle of each edge is the average of its
+		// neighbors, of which there are only three along the border
+		for row := 0; row < size; row += half {
+			for col := (row/half%2 + 1) % 2 * half; col < size; col += step {
+				sum, count := 0.0, 0
+				for _, d := range [][2]int{{-half, 0}, {half, 0}, {0, -half}, {0, half}} {
+					r, c := row+d[0], col+d[1]
+					if r >= 0 && r < size && c >= 0 && c < size {
+						sum += heights[r][c]
+						count++
+					}
+				}
+				heights[row][col] = sum/float64(count) + displace(scale)
+			}
+		}
+		scale *= roughness
+	}
+
+	// Stretch the heights to fill 0 to 1, so the height of the terrain is the
+	// height of its highest peak
+	low, high := math.Inf(1), math.Inf(-1)
+	for _, row := range heights {
+		for _, h := range row {
+			low = math.Min(low, h)
+			high = math.Max(high, h)
+		}
+	}
+	for _, row := range heights {
+		for i := range row {
+			if high > low {
+				row[i] = (row[i] - low) / (high - low)
+			} else {
+				row[i] = 0
+			}
+		}
+	}
+	return heights, nil
+}
+
+// AddTerrain adds the triangles of a heightmap to the matrix, stretched over
+// width along x and depth back along z from (x, y, z), and rising up to height
+// above y
+// Only the top of the terrain is added, so it can't be seen from below.
+func (m *Matrix) AddTerrain(x, y, z, width, depth, height float64, heights Heightmap) {
+	cells := len(heights) - 1
+	point := func(row, col int) Vec3 {
+		return Vec3{
+			x + width*float64(col)/float64(cells),
+			y + height*heights[row][col],
+			z - depth*float64(row)/float64(cells),
+		}
+	}
+	for row := 0; row < cells; row++ {
+		for col := 0; col < cells; col++ {
+			front, frontRight := point(row, col), point(row, col+1)
+			back, backRight := point(row+1, col), point(row+1, col+1)
+			m.addTriangle(back, frontRight, backRight)
+			m.addTriangle(back, front, frontRight)
+		}
+	}
+}
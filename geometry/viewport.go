@@ -0,0 +1,152 @@
+package geometry
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// Origin defines where the origin of the coordinate system is on the image
+type Origin int
+
+const (
+	// OriginBottomLeft places the origin at the bottom left corner
+	OriginBottomLeft Origin = iota
+	// OriginTopLeft places the origin at the top left corner
+	OriginTopLeft
+	// OriginCenter places the origin at the center of the image
+	OriginCenter
+)
+
+var origins = map[string]Origin{
+	"bottomleft": OriginBottomLeft,
+	"topleft":    OriginTopLeft,
+	"center":     OriginCenter,
+}
+
+// ParseOrigin returns the origin with the given name
+func ParseOrigin(name string) (Origin, error) {
+	if origin, found := origins[name]; found {
+		return origin, nil
+	}
+	return OriginBottomLeft, fmt.Errorf("unknown origin '%s'", name)
+}
+
+// Viewport defines the coordinate convention of a script, which is mapped onto
+// the image (whose origin is the bottom left corner with y pointing up) as the
+// final transformation of everything drawn
+type Viewport struct {
+	Origin Origin
+	YDown  bool // whether y points down the image
+}
+
+// Matrix returns the transformation from the viewport's coordinates to image
+// coordinates
+// Pointing y down also points z into the image, so the coordinate system stays
+// right-handed and the winding of polygons (and so backface culling) is kept.
+func (v Viewport) Matrix(height, width int) Mat4 {
+	var x, y float64
+	switch v.Origin {
+	case OriginTopLeft:
+		y = float64(height - 1)
+	case OriginCenter:
+		x, y = float64(width)/2, float64(height)/2
+	}
+	m := MakeTranslation(x, y, 0)
+	if v.YDown {
+		m = m.Mul(MakeDilation(1, -1, -1))
+	}
+	return m
+}
+
+// Rect is the part of the image that everything is drawn into, with its
+// bottom left corner at (x, y) in pixels, where y points up the image
+// The zero Rect is the whole image.
+type Rect struct {
+	X, Y, W, H float64
+}
+
+// Projection maps image coordinates, where z increases towards the viewer,
+// onto the image plane at z = 0
+// The zero Projection is orthographic, which drops z and keeps one unit to a
+// pixel.
+type Projection struct {
+	Perspective bool
+	FOV         float64 // vertical field of view in degrees
+	Near, Far   float64 // distances from the eye that can be seen
+	Offset      float64 // how far right of the center of the image the eye is
+}
+
+// eye returns how far in front of the image plane the eye of a perspective
+// projection is, which is where the plane just fills the field of view, so
+// that shapes at z = 0 keep their size
+func (p Projection) eye(height int) float64 {
+	return float64(height) / 2 / math.Tan(DegreesToRadians(p.FOV)/2)
+}
+
+// Planes returns the planes bounding the depths a perspective projection can
+// see, which must be clipped against before projecting
+func (p Projection) Planes(height int) []Plane {
+	eye := p.eye(height)
+	planes := []Plane{{0, 0, -1, eye - p.Near}}
+	if !math.IsInf(p.Far, 1) {
+		planes = append(planes, Plane{0, 0, 1, p.Far - eye})
+	}
+	return planes
+}
+
+// Project returns a point in front of the eye of a perspective projection as
+// the eye sees it, looking straight at the image
+// Depths are projected too, so that they can still be interpolated linearly
+// across the image, and are almost unchanged near the image plane.
+func (p Projection) Project(point []float64, height, width int) []float64 {
+	eye := p.eye(height)
+	cx, cy := float64(width)/2+p.Offset, float64(height)/2
+	scale := eye / (eye - point[2])
+	projected := make([]float64, len(point))
+	copy(projected, point)
+	projected[0] = cx + (point[0]-cx)*scale
+	projected[1] = cy + (point[1]-cy)*scale
+	projected[2] = eye*scale - eye
+	return projected
+}
+
+// Camera is where everything is seen from, in image coordinates: from its eye,
+// looking towards its aim
+type Camera struct {
+	Eye, Aim Vec3
+}
+
+// View returns the transformation that moves what the camera sees to where the
+// projection sees it, with the eye of the camera at the eye of the projection
+// and the aim at the center of the image
+// Orthographic projections have no eye, so the aim is put on the image plane.
+// The top of the image stays as close to the y axis as it can, so the camera
+// never rolls. A camera looking straight down has -z at the top of the image,
+// and one looking straight up has z.
+func (c Camera) View(projection Projection, height, width int) (Mat4, error) {
+	forward := c.Aim.Sub(c.Eye)
+	distance := forward.Length()
+	if distance == 0 {
+		return Identity(), errors.New("the eye and aim of a camera must be different points")
+	}
+	forward = forward.Scale(1 / distance)
+	right := forward.Cross(Vec3{0, 1, 0})
+	if right.Length() < 1e-9 {
+		right = forward.Cross(Vec3{0, 0, forward[1]})
+	}
+	right = right.Normalize()
+	up := right.Cross(forward)
+	rotation := Mat4{
+		right[0], right[1], right[2], 0,
+		up[0], up[1], up[2], 0,
+		-forward[0], -forward[1], -forward[2], 0,
+		0, 0, 0, 1,
+	}
+	z := distance
+	if projection.Perspective {
+		z = projection.eye(height)
+	}
+	eye := MakeTranslation(float64(width)/2, float64(height)/2, z)
+	return eye.Mul(rotation).Mul(MakeTranslation(-c.Eye[0], -c.Eye[1], -c.Eye[2])), nil
+}
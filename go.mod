@@ -0,0 +1,7 @@
+module github.com/james9909/graphics-engine
+
+go 1.24
+
+require github.com/fsnotify/fsnotify v1.10.1
+
+require golang.org/x/sys v0.13.0 // indirect
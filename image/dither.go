@@ -0,0 +1,98 @@
+package image
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/james9909/graphics-engine/geometry"
+)
+
+// DitherMode defines how colors are dithered when reducing their depth
+type DitherMode int
+
+const (
+	// DitherNone quantizes each pixel to the nearest color
+	DitherNone DitherMode = iota
+	// DitherOrdered quantizes pixels against a 4x4 Bayer threshold matrix
+	DitherOrdered
+	// DitherFloydSteinberg diffuses the quantization error to neighboring pixels
+	DitherFloydSteinberg
+)
+
+// PaletteLevels is the number of levels per channel used for palette-limited
+// formats such as GIF, giving a 6x6x6 color cube
+const PaletteLevels = 6
+
+var bayer = [4][4]float64{
+	{0, 8, 2, 10},
+	{12, 4, 14, 6},
+	{3, 11, 1, 9},
+	{15, 7, 13, 5},
+}
+
+// ParseDitherMode returns the dither mode with the given name
+func ParseDitherMode(name string) (DitherMode, error) {
+	switch name {
+	case "", "none":
+		return DitherNone, nil
+	case "ordered":
+		return DitherOrdered, nil
+	case "floyd", "floyd-steinberg":
+		return DitherFloydSteinberg, nil
+	}
+	return DitherNone, fmt.Errorf("unknown dither mode %q", name)
+}
+
+// Dither returns a copy of the Image with each channel reduced to the given
+// number of levels
+func (image *Image) Dither(mode DitherMode, levels int) *Image {
+	image.Flush()
+	dithered := NewImage(image.Height, image.Width)
+	step := 255 / float64(levels-1)
+	quantize := func(v float64) (byte, float64) {
+		q := math.Round(geometry.Clamp(v, 0, 255)/step) * step
+		return byte(q), v - q
+	}
+
+	switch mode {
+	case DitherFloydSteinberg:
+		// Errors carried over to the current and next rows, per channel
+		current := make([][3]float64, image.Width+2)
+		next := make([][3]float64, image.Width+2)
+		for y := 0; y < image.Height; y++ {
+			for x := 0; x < image.Width; x++ {
+				c := image.Frame[y][x]
+				channels := [3]float64{float64(c.R), float64(c.G), float64(c.B)}
+				var out [3]byte
+				for i := range channels {
+					var e float64
+					out[i], e = quantize(channels[i] + current[x+1][i])
+					current[x+2][i] += e * 7 / 16
+					next[x][i] += e * 3 / 16
+					next[x+1][i] += e * 5 / 16
+					next[x+2][i] += e * 1 / 16
+				}
+				dithered.Frame[y][x] = Color{out[0], out[1], out[2], 255}
+			}
+			current, next = next, current
+			for i := range next {
+				next[i] = [3]float64{}
+			}
+		}
+	default:
+		for y := 0; y < image.Height; y++ {
+			for x := 0; x < image.Width; x++ {
+				threshold := 0.0
+				if mode == DitherOrdered {
+					threshold = ((bayer[y%4][x%4]+0.5)/16 - 0.5) * step
+				}
+				c := image.Frame[y][x]
+				r, _ := quantize(float64(c.R) + threshold)
+				g, _ := quantize(float64(c.G) + threshold)
+				b, _ := quantize(float64(c.B) + threshold)
+				dithered.Frame[y][x] = Color{r, g, b, 255}
+			}
+		}
+	}
+	return dithered
+}
@@ -0,0 +1,174 @@
+package image
+
+import (
+	"fmt"
+	"image"
+	_ "image/jpeg" // decodes jpeg images
+	_ "image/png"  // decodes png images
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/james9909/graphics-engine/geometry"
+)
+
+// EnvironmentLayout defines how the directions around a surface are laid out
+// in the image of an environment map
+type EnvironmentLayout int
+
+const (
+	// EnvironmentSphere is a mirrored ball seen from the front, in a square
+	// image
+	EnvironmentSphere EnvironmentLayout = iota
+	// EnvironmentEquirectangular spans every direction around in longitude
+	// and from straight down to straight up in latitude, in an image twice
+	// as wide as it is tall
+	EnvironmentEquirectangular
+	// EnvironmentCube is the six faces of a cube unfolded into a horizontal
+	// cross, in an image 4 faces wide and 3 tall: left, front, right, and
+	// back across the middle, with up above the front and down below it
+	EnvironmentCube
+)
+
+// EnvironmentMap is an image of the surroundings of a scene, which shiny
+// surfaces reflect
+// Looking into the image is looking towards the front of the environment.
+type EnvironmentMap struct {
+	layout        EnvironmentLayout
+	width, height int
+	pixels        []geometry.Vec3 // intensity of each channel from 0 to 255, bottom row first
+	linear        *EnvironmentMap // the map in linear space, for lighting computed in it
+}
+
+// ReadEnvironmentMap reads an environment map from a png, jpeg, or binary ppm
+// image, whose layout is given by its shape
+func ReadEnvironmentMap(filename string) (*EnvironmentMap, error) {
+	width, height, pixels, err := readPixels(filename)
+	if err != nil {
+		return nil, err
+	}
+	e := &EnvironmentMap{width: width, height: height, pixels: pixels}
+
+	switch {
+	case e.width == e.height:
+		e.layout = EnvironmentSphere
+	case e.width == 2*e.height:
+		e.layout = EnvironmentEquirectangular
+	case 3*e.width == 4*e.height:
+		e.layout = EnvironmentCube
+	default:
+		return nil, fmt.Errorf("%s: environment maps must be square (a mirrored ball), twice as wide as they are tall (equirectangular), or 4:3 (a cube cross), not %dx%d", filename, e.width, e.height)
+	}
+
+	linear := *e
+	linear.pixels = make([]geometry.Vec3, len(e.pixels))
+	for i, p := range e.pixels {
+		for j := range p {
+			linear.pixels[i][j] = 255 * srgbToLinear(p[j]/255)
+		}
+	}
+	e.linear = &linear
+	return e, nil
+}
+
+// readPixels returns the size of a png, jpeg, or binary ppm image and the
+// intensity of each channel of its pixels from 0 to 255, bottom row first
+func readPixels(filename string) (int, int, []geometry.Vec3, error) {
+	if strings.ToLower(filepath.Ext(filename)) == ".ppm" {
+		img, err := ReadPpm(filename)
+		if err != nil {
+			return 0, 0, nil, err
+		}
+		pixels := make([]geometry.Vec3, 0, img.Width*img.Height)
+		for _, row := range img.Frame {
+			for _, c := range row {
+				pixels = append(pixels, geometry.Vec3{float64(c.R), float64(c.G), float64(c.B)})
+			}
+		}
+		return img.Width, img.Height, pixels, nil
+	}
+
+	f, err := os.Open(filename)
+	if err != nil {
+		return 0, 0, nil, err
+	}
+	defer f.Close()
+	decoded, _, err := image.Decode(f)
+	if err != nil {
+		return 0, 0, nil, fmt.Errorf("%s: %v", filename, err)
+	}
+	bounds := decoded.Bounds()
+	pixels := make([]geometry.Vec3, 0, bounds.Dx()*bounds.Dy())
+	for y := bounds.Max.Y - 1; y >= bounds.Min.Y; y-- {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			r, g, b, _ := decoded.At(x, y).RGBA()
+			pixels = append(pixels, geometry.Vec3{float64(r) / 257, float64(g) / 257, float64(b) / 257})
+		}
+	}
+	return bounds.Dx(), bounds.Dy(), pixels, nil
+}
+
+// Sample returns the intensity of the environment seen in a direction, where
+// x points right, y up, and z out of the image
+func (e *EnvironmentMap) Sample(direction geometry.Vec3) geometry.Vec3 {
+	d := direction.Normalize()
+	w, h := float64(e.width), float64(e.height)
+	switch e.layout {
+	case EnvironmentEquirectangular:
+		longitude := math.Atan2(d[0], -d[2])
+		latitude := math.Asin(geometry.Clamp(d[1], -1, 1))
+		return e.bilinear((0.5+longitude/(2*math.Pi))*w, (0.5+latitude/math.Pi)*h, 0, 0, w, h)
+	case EnvironmentCube:
+		// Find the face the direction points through, and where it crosses
+		// the face from -1 to 1 as seen from inside the cube
+		var column, row int
+		var u, v float64
+		ax, ay, az := math.Abs(d[0]), math.Abs(d[1]), math.Abs(d[2])
+		switch {
+		case ax >= ay && ax >= az && d[0] > 0:
+			column, row, u, v = 2, 1, d[2]/ax, d[1]/ax
+		case ax >= ay && ax >= az:
+			column, row, u, v = 0, 1, -d[2]/ax, d[1]/ax
+		case ay >= az && d[1] > 0:
+			column, row, u, v = 1, 2, d[0]/ay, d[2]/ay
+		case ay >= az:
+			column, row, u, v = 1, 0, d[0]/ay, -d[2]/ay
+		case d[2] < 0:
+			column, row, u, v = 1, 1, d[0]/az, d[1]/az
+		default:
+			column, row, u, v = 3, 1, -d[0]/az, d[1]/az
+		}
+		size := w / 4
+		x0, y0 := float64(column)*size, float64(row)*size
+		return e.bilinear(x0+(u+1)/2*size, y0+(v+1)/2*size, x0, y0, x0+size, y0+size)
+	}
+	// A mirrored ball reflects the direction back towards the viewer at its
+	// center, and the direction away from them around its rim
+	m := 2 * math.Sqrt(d[0]*d[0]+d[1]*d[1]+(d[2]+1)*(d[2]+1))
+	if m == 0 {
+		return e.bilinear(w/2, 0, 0, 0, w, h)
+	}
+	return e.bilinear((d[0]/m+0.5)*w, (d[1]/m+0.5)*h, 0, 0, w, h)
+}
+
+// bilinear returns the intensity at a point of the image, blending the four
+// pixels around it without looking outside of the given bounds
+func (e *EnvironmentMap) bilinear(x, y, x0, y0, x1, y1 float64) geometry.Vec3 {
+	x = geometry.Clamp(x-0.5, x0, x1-1)
+	y = geometry.Clamp(y-0.5, y0, y1-1)
+	ix, iy := int(x), int(y)
+	fx, fy := x-float64(ix), y-float64(iy)
+	at := func(px, py int) geometry.Vec3 {
+		if float64(px) > x1-1 {
+			px = int(x1 - 1)
+		}
+		if float64(py) > y1-1 {
+			py = int(y1 - 1)
+		}
+		return e.pixels[py*e.width+px]
+	}
+	bottom := at(ix, iy).Scale(1 - fx).Add(at(ix+1, iy).Scale(fx))
+	top := at(ix, iy+1).Scale(1 - fx).Add(at(ix+1, iy+1).Scale(fx))
+	return bottom.Scale(1 - fy).Add(top.Scale(fy))
+}
@@ -0,0 +1,121 @@
+package image
+
+import (
+	"math"
+
+	"github.com/james9909/graphics-engine/geometry"
+)
+
+// GBuffer holds what was drawn at each pixel of an Image besides its color and
+// depth, which are written out as passes for compositing
+type GBuffer struct {
+	Normals    [][]geometry.Vec3 // normal of the surface at each pixel, or zero where there is none
+	IDs        [][]int           // ID of the shape at each pixel, or 0 where nothing is drawn
+	Primitives [][]int           // triangle or line segment of the shape at each pixel
+}
+
+// NewGBuffer returns an empty GBuffer
+func NewGBuffer(height, width int) *GBuffer {
+	g := &GBuffer{
+		Normals:    make([][]geometry.Vec3, height),
+		IDs:        make([][]int, height),
+		Primitives: make([][]int, height),
+	}
+	for y := range g.Normals {
+		g.Normals[y] = make([]geometry.Vec3, width)
+		g.IDs[y] = make([]int, width)
+		g.Primitives[y] = make([]int, width)
+	}
+	return g
+}
+
+// Copy returns a copy of the GBuffer
+func (g *GBuffer) Copy() *GBuffer {
+	copied := NewGBuffer(len(g.Normals), len(g.Normals[0]))
+	for y := range g.Normals {
+		copy(copied.Normals[y], g.Normals[y])
+		copy(copied.IDs[y], g.IDs[y])
+		copy(copied.Primitives[y], g.Primitives[y])
+	}
+	return copied
+}
+
+// clear empties the GBuffer, as if nothing was drawn
+func (g *GBuffer) clear() {
+	for y := range g.Normals {
+		for x := range g.Normals[y] {
+			g.Normals[y][x] = geometry.Vec3{}
+			g.IDs[y][x] = 0
+			g.Primitives[y][x] = 0
+		}
+	}
+}
+
+// EnableGBuffer makes the Image keep the normal and the ID of the shape drawn
+// at each pixel
+func (image *Image) EnableGBuffer() {
+	image.GBuffer = NewGBuffer(image.Height, image.Width)
+}
+
+// SetShapeID sets the ID that the G-buffer records for everything drawn
+// afterwards
+func (image *Image) SetShapeID(id int) {
+	image.shapeID = id
+}
+
+// Normals returns an image of the normals of the surfaces drawn, with each
+// axis from -1 to 1 in a channel from 0 to 255: x (right) in red, y (up) in
+// green, and z (out of the image) in blue
+// Pixels without a surface, such as lines, are black.
+func (image *Image) Normals() *Image {
+	image.Flush()
+	normals := NewImage(image.Height, image.Width)
+	for y, row := range image.GBuffer.Normals {
+		for x, n := range row {
+			if n == (geometry.Vec3{}) {
+				normals.Frame[y][x] = Black
+				continue
+			}
+			channel := func(v float64) byte {
+				return byte(math.Round((v + 1) / 2 * 255))
+			}
+			normals.Frame[y][x] = Color{channel(n[0]), channel(n[1]), channel(n[2]), 255}
+		}
+	}
+	return normals
+}
+
+// IDs returns an image of the shapes drawn, each in its own color, and black
+// where nothing is drawn
+// A shape's color only depends on its ID, so it stays the same across frames.
+func (image *Image) IDs() *Image {
+	image.Flush()
+	ids := NewImage(image.Height, image.Width)
+	for y, row := range image.GBuffer.IDs {
+		for x, id := range row {
+			ids.Frame[y][x] = idColor(id)
+		}
+	}
+	return ids
+}
+
+// idColor returns the color of a shape ID, stepping consecutive IDs around
+// the hues by the golden ratio so that neighbouring shapes are easy to tell
+// apart
+func idColor(id int) Color {
+	if id == 0 {
+		return Black
+	}
+	turns := float64(id) * (math.Sqrt(5) - 1) / 2
+	hue := (turns - math.Floor(turns)) * 6
+	// Every other trip around the hues is darker
+	value := 1.0
+	if int(turns)%2 == 1 {
+		value = 0.6
+	}
+	channel := func(n float64) byte {
+		k := math.Mod(n+hue, 6)
+		return byte(math.Round(value * (1 - 0.75*geometry.Clamp(math.Min(k, 4-k), 0, 1)) * 255))
+	}
+	return Color{channel(5), channel(3), channel(1), 255}
+}
@@ -0,0 +1,187 @@
+package image
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/gif"
+	"os"
+	"sort"
+)
+
+const (
+	// DefaultDelay is the delay between animation frames in hundredths of a second
+	DefaultDelay = 3
+	// GIFColors is the number of colors in the palette of each gif frame
+	GIFColors = 256
+)
+
+// MakeAnimation assembles the frames of an animation, named by formatString,
+// into a gif
+// delay is in hundredths of a second, and loop is the number of times the
+// animation repeats after playing once (0 repeats forever, -1 plays it once).
+func MakeAnimation(basename, formatString string, frames, delay, loop int) error {
+	animation := &gif.GIF{LoopCount: loop}
+	for frame := 0; frame < frames; frame++ {
+		img, err := ReadPpm(fmt.Sprintf(formatString, frame))
+		if err != nil {
+			return err
+		}
+		animation.Image = append(animation.Image, palettedFrame(img))
+		animation.Delay = append(animation.Delay, delay)
+	}
+
+	f, err := os.Create(fmt.Sprintf("%s.gif", basename))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return gif.EncodeAll(f, animation)
+}
+
+// SaveGIF saves a single Image as a gif
+func SaveGIF(img *Image, name string) error {
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return gif.Encode(f, palettedFrame(img), nil)
+}
+
+// palettedFrame converts an Image to a paletted image with its own palette,
+// mapping each pixel to the closest color in the palette
+func palettedFrame(img *Image) *image.Paletted {
+	palette := quantize(img, GIFColors)
+	paletted := image.NewPaletted(image.Rect(0, 0, img.Width, img.Height), palette)
+	indices := make(map[Color]uint8)
+	for y := 0; y < img.Height; y++ {
+		// Adjust y coordinate that the origin is the bottom left
+		row := img.Frame[img.Height-y-1]
+		for x, c := range row {
+			index, found := indices[c]
+			if !found {
+				index = uint8(palette.Index(color.RGBA{c.R, c.G, c.B, 255}))
+				indices[c] = index
+			}
+			paletted.Pix[y*paletted.Stride+x] = index
+		}
+	}
+	return paletted
+}
+
+// colorCount is a color and the number of pixels with that color
+type colorCount struct {
+	c     Color
+	count int
+}
+
+// quantize picks a palette of at most size colors for an Image by median cut
+// Images with no more than size colors get an exact palette.
+func quantize(img *Image, size int) color.Palette {
+	histogram := make(map[Color]int)
+	for _, row := range img.Frame {
+		for _, c := range row {
+			histogram[c]++
+		}
+	}
+	colors := make([]colorCount, 0, len(histogram))
+	for c, count := range histogram {
+		colors = append(colors, colorCount{c, count})
+	}
+	// Start from the same order every time, since maps are ranged over in
+	// any order and colors tied along a channel stay in the order they are in
+	sort.Slice(colors, func(i, j int) bool {
+		a, b := colors[i].c, colors[j].c
+		if a.R != b.R {
+			return a.R < b.R
+		}
+		if a.G != b.G {
+			return a.G < b.G
+		}
+		if a.B != b.B {
+			return a.B < b.B
+		}
+		return a.A < b.A
+	})
+
+	boxes := [][]colorCount{colors}
+	for len(boxes) < size {
+		// Split the box with the widest range of any channel
+		widest, channel, widestRange := -1, 0, 0
+		for i, box := range boxes {
+			for ch := 0; ch < 3; ch++ {
+				lo, hi := channelRange(box, ch)
+				if hi-lo > widestRange {
+					widest, channel, widestRange = i, ch, hi-lo
+				}
+			}
+		}
+		if widest == -1 {
+			// Every box has a single color
+			break
+		}
+
+		box := boxes[widest]
+		sort.SliceStable(box, func(i, j int) bool {
+			return channelValue(box[i].c, channel) < channelValue(box[j].c, channel)
+		})
+		total := 0
+		for _, entry := range box {
+			total += entry.count
+		}
+		// Split at the median pixel, keeping at least one color on each side
+		split, seen := 1, box[0].count
+		for split < len(box)-1 && seen < total/2 {
+			seen += box[split].count
+			split++
+		}
+		boxes[widest] = box[:split]
+		boxes = append(boxes, box[split:])
+	}
+
+	palette := make(color.Palette, len(boxes))
+	for i, box := range boxes {
+		var r, g, b, total int
+		for _, entry := range box {
+			r += int(entry.c.R) * entry.count
+			g += int(entry.c.G) * entry.count
+			b += int(entry.c.B) * entry.count
+			total += entry.count
+		}
+		palette[i] = color.RGBA{
+			uint8((r + total/2) / total),
+			uint8((g + total/2) / total),
+			uint8((b + total/2) / total),
+			255,
+		}
+	}
+	return palette
+}
+
+// channelRange returns the lowest and highest value of a channel in a box
+func channelRange(box []colorCount, channel int) (int, int) {
+	lo, hi := 255, 0
+	for _, entry := range box {
+		v := channelValue(entry.c, channel)
+		if v < lo {
+			lo = v
+		}
+		if v > hi {
+			hi = v
+		}
+	}
+	return lo, hi
+}
+
+// channelValue returns the red (0), green (1), or blue (2) channel of a color
+func channelValue(c Color, channel int) int {
+	switch channel {
+	case 0:
+		return int(c.R)
+	case 1:
+		return int(c.G)
+	default:
+		return int(c.B)
+	}
+}
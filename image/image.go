@@ -0,0 +1,635 @@
+// Package image rasterizes and shades triangles and lines into images with a
+// z buffer, and reads and writes them
+package image
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/james9909/graphics-engine/geometry"
+)
+
+const (
+	// DefaultHeight is the default height of an Image
+	DefaultHeight = 500
+	// DefaultWidth is the default width of an Image
+	DefaultWidth = 500
+	// MinDepthShade is the gray level of the farthest pixel in a depth image,
+	// keeping it apart from the black background
+	MinDepthShade = 32
+)
+
+var (
+	Black = Color{0, 0, 0, 255}
+	White = Color{255, 255, 255, 255}
+)
+
+type Color struct {
+	R byte
+	G byte
+	B byte
+	A byte // opacity, where 255 is opaque
+}
+
+// ParseHexColor returns the color written as #rrggbb, or #rrggbbaa with an
+// alpha channel
+func ParseHexColor(hex string) (Color, error) {
+	var r, g, b byte
+	a := byte(255)
+	var err error
+	switch {
+	case len(hex) == 7 && hex[0] == '#':
+		_, err = fmt.Sscanf(hex[1:], "%02x%02x%02x", &r, &g, &b)
+	case len(hex) == 9 && hex[0] == '#':
+		_, err = fmt.Sscanf(hex[1:], "%02x%02x%02x%02x", &r, &g, &b, &a)
+	default:
+		return Black, fmt.Errorf("invalid color '%s'", hex)
+	}
+	if err != nil {
+		return Black, fmt.Errorf("invalid color '%s'", hex)
+	}
+	return Color{r, g, b, a}, nil
+}
+
+// over returns the opaque color of c drawn over the opaque color d
+func (c Color) over(d Color) Color {
+	alpha := float64(c.A) / 255
+	mix := func(dst, src byte) byte {
+		return byte(geometry.Clamp(float64(dst)*(1-alpha)+float64(src)*alpha+0.5, 0, 255))
+	}
+	return Color{mix(d.R, c.R), mix(d.G, c.G), mix(d.B, c.B), 255}
+}
+
+// Image represents an image
+type Image struct {
+	Frame     [][]Color
+	ZBuffer   [][]float64
+	Height    int
+	Width     int
+	ZEpsilon  float64      // how much closer a pixel must be to replace another
+	ZOffset   float64      // depth added to everything drawn
+	paths     []vectorPath // lines and polygons drawn, for saving as vectors
+	clip      rect         // pixels that can be drawn on, all of them unless rasterizing a tile
+	bins      [][]drawOp   // drawing waiting to be rasterized in each tile, if the Image is tiled
+	pending   bool         // whether any tile has drawing waiting to be rasterized
+	GBuffer   *GBuffer     // normals and shape IDs of the pixels, if they are kept
+	shapeID   int          // ID of the shape being drawn, for the G-buffer
+	primitive int          // triangle or line segment of the shape being drawn, for the G-buffer
+}
+
+// NewImage returns a new Image with the given height and width
+func NewImage(height, width int) *Image {
+	frame := make([][]Color, height)
+	zBuffer := make([][]float64, height)
+	for i := 0; i < height; i++ {
+		frame[i] = make([]Color, width)
+		zBuffer[i] = make([]float64, width)
+		for j := 0; j < width; j++ {
+			zBuffer[i][j] = math.Inf(-1)
+		}
+	}
+	image := &Image{
+		Frame:   frame,
+		ZBuffer: zBuffer,
+		Height:  height,
+		Width:   width,
+		clip:    rect{0, 0, width, height},
+	}
+	return image
+}
+
+// Copy returns a copy of the Image
+func (image *Image) Copy() *Image {
+	image.Flush()
+	copied := NewImage(image.Height, image.Width)
+	for y := 0; y < image.Height; y++ {
+		copy(copied.Frame[y], image.Frame[y])
+		copy(copied.ZBuffer[y], image.ZBuffer[y])
+	}
+	copied.ZEpsilon = image.ZEpsilon
+	copied.ZOffset = image.ZOffset
+	copied.paths = append([]vectorPath(nil), image.paths...)
+	if image.GBuffer != nil {
+		copied.GBuffer = image.GBuffer.Copy()
+	}
+	copied.shapeID = image.shapeID
+	copied.primitive = image.primitive
+	return copied
+}
+
+// DrawLines draws all lines onto the Image with the given width in pixels
+func (image *Image) DrawLines(em *geometry.Matrix, c Color, width float64) error {
+	if em.Cols < 2 {
+		return errors.New("2 or more points are required for drawing")
+	}
+	for i := 0; i < em.Cols-1; i += 2 {
+		image.primitive = i / 2
+		p0 := em.GetColumn(i)
+		p1 := em.GetColumn(i + 1)
+		image.DrawThickLine(p0[0], p0[1], p0[2], p1[0], p1[1], p1[2], width, c)
+		image.addLine(p0[0], p0[1], p1[0], p1[1], c, width)
+	}
+	return nil
+}
+
+// DrawPolygons draws the edges of all polygons onto the Image with the given
+// width in pixels
+func (image *Image) DrawPolygons(em *geometry.Matrix, c Color, width float64) error {
+	if em.Cols < 3 {
+		return errors.New("3 or more points are required for drawing")
+	}
+	for i := 0; i < em.Cols-2; i += 3 {
+		image.primitive = i / 3
+		p0 := em.GetColumn(i)
+		p1 := em.GetColumn(i + 1)
+		p2 := em.GetColumn(i + 2)
+		if isVisible(p0, p1, p2) {
+			image.DrawThickLine(p0[0], p0[1], p0[2], p1[0], p1[1], p1[2], width, c)
+			image.DrawThickLine(p1[0], p1[1], p1[2], p2[0], p2[1], p2[2], width, c)
+			image.DrawThickLine(p2[0], p2[1], p2[2], p0[0], p0[1], p0[2], width, c)
+			image.addTriangle(p0, p1, p2, c, width)
+		}
+	}
+	return nil
+}
+
+// FillPolygons fills all polygons on the Image with a single color
+func (image *Image) FillPolygons(em *geometry.Matrix, c Color) error {
+	if em.Cols < 3 {
+		return errors.New("3 or more points are required for drawing")
+	}
+	if c.A < 255 {
+		em = backToFront(em)
+	}
+	for i := 0; i < em.Cols-2; i += 3 {
+		image.primitive = i / 3
+		p0 := em.GetColumn(i)
+		p1 := em.GetColumn(i + 1)
+		p2 := em.GetColumn(i + 2)
+		if isVisible(p0, p1, p2) {
+			image.Scanline(p0, p1, p2, c)
+			image.addTriangle(p0, p1, p2, c, 0)
+		}
+	}
+	return nil
+}
+
+// backToFront returns the triangles of em ordered from the farthest to the
+// closest, so that translucent triangles blend over the ones behind them
+func backToFront(em *geometry.Matrix) *geometry.Matrix {
+	order := make([]int, em.Cols/3)
+	depth := make([]float64, len(order))
+	for t := range order {
+		order[t] = t
+		depth[t] = em.Get(2, 3*t) + em.Get(2, 3*t+1) + em.Get(2, 3*t+2)
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return depth[order[i]] < depth[order[j]]
+	})
+	sorted := geometry.NewMatrix(em.Rows, 0)
+	for _, t := range order {
+		sorted.AddColumn(em.GetColumn(3 * t))
+		sorted.AddColumn(em.GetColumn(3*t + 1))
+		sorted.AddColumn(em.GetColumn(3*t + 2))
+	}
+	return sorted
+}
+
+// DrawShadedPolygons draws all polygons onto the Image using scanline conversion
+// If shadows is non-nil, each pixel is only lit by the lights that reach it.
+// The light reflected by each pixel is turned into a color by transform.
+func (image *Image) DrawShadedPolygons(em *geometry.Matrix, ambient geometry.Vec3, material Material, lights map[string]LightSource, mode ShadingMode, shadows map[string]*ShadowMap, transform ColorTransform) error {
+	if em.Cols < 3 {
+		return errors.New("3 or more points are required for drawing")
+	}
+	I_a, material, lights := transform.Prepare(ambient, material, lights)
+	alpha := byte(geometry.Clamp(material.Opacity*255+0.5, 0, 255))
+	if alpha < 255 {
+		em = backToFront(em)
+	}
+	var normals []geometry.Vec3
+	if mode == ShadingPhong {
+		normals = VertexNormals(em)
+	}
+	for i := 0; i < em.Cols-2; i += 3 {
+		image.primitive = i / 3
+		p0 := em.GetColumn(i)
+		p1 := em.GetColumn(i + 1)
+		p2 := em.GetColumn(i + 2)
+		if !isVisible(p0, p1, p2) {
+			continue
+		}
+		face := geometry.Normal(geometry.Vec3Of(p0), geometry.Vec3Of(p1), geometry.Vec3Of(p2))
+		// Normal maps and textures are laid over the points of the shape from
+		// before it was transformed, which follow the rows of the points
+		// themselves
+		surface := em.Rows >= 7
+		bumpy := material.NormalMap != nil && surface
+		textured := material.Texture != nil && surface
+		pictured := material.Image != nil && surface
+		if mode == ShadingPhong || shadows != nil || bumpy || textured || pictured {
+			// Lighting is evaluated per pixel, interpolating the position of
+			// the pixel and its normal
+			n0, n1, n2 := face, face, face
+			if mode == ShadingPhong {
+				n0, n1, n2 = normals[i], normals[i+1], normals[i+2]
+			}
+			var s0, s1, s2 geometry.Vec3
+			if surface {
+				s0, s1, s2 = geometry.Vec3Of(p0[4:]), geometry.Vec3Of(p1[4:]), geometry.Vec3Of(p2[4:])
+			}
+			var t, b geometry.Vec3
+			var uv0, uv1, uv2 [2]float64
+			if bumpy {
+				side := geometry.Normal(s0, s1, s2)
+				uv0[0], uv0[1] = boxCoordinates(s0, side)
+				uv1[0], uv1[1] = boxCoordinates(s1, side)
+				uv2[0], uv2[1] = boxCoordinates(s2, side)
+				t, b, bumpy = tangents(geometry.Vec3Of(p0), geometry.Vec3Of(p1), geometry.Vec3Of(p2), uv0, uv1, uv2)
+			}
+			var across func(p geometry.Vec3) (float64, float64)
+			if pictured {
+				across = stretch(geometry.Normal(s0, s1, s2), material.ImageBounds[0], material.ImageBounds[1])
+			}
+			shade := func(attrs []float64) Color {
+				lit := lights
+				if shadows != nil {
+					lit = litLights(lights, shadows, geometry.Vec3Of(attrs[3:]))
+				}
+				normal := geometry.Vec3Of(attrs).Normalize()
+				if bumpy {
+					normal = material.NormalMap.Perturb(normal, t, b, attrs[6], attrs[7])
+				}
+				m := material
+				if textured {
+					m = material.Texture.Apply(material, geometry.Vec3Of(attrs[8:]))
+				}
+				if pictured {
+					texel := transform.decodeAll(material.Image.Sample(across(geometry.Vec3Of(attrs[8:])))).Scale(1.0 / 255)
+					m.Ambient = m.Ambient.Mul(texel)
+					m.Diffuse = m.Diffuse.Mul(texel)
+					m.Emissive = m.Emissive.Mul(texel)
+				}
+				c := Lighting(normal, I_a, m, DefaultViewVector, lit)
+				return transform.Encode(c, alpha)
+			}
+			// Each vertex carries its normal, position, place on the normal
+			// map, and point on the shape
+			attributes := func(p []float64, n geometry.Vec3, uv [2]float64, s geometry.Vec3) []float64 {
+				attrs := append(n[:], p[:3]...)
+				attrs = append(attrs, uv[:]...)
+				return append(attrs, s[:]...)
+			}
+			image.fillTriangle(
+				newVertex(p0, attributes(p0, n0, uv0, s0)),
+				newVertex(p1, attributes(p1, n1, uv1, s1)),
+				newVertex(p2, attributes(p2, n2, uv2, s2)),
+				shade,
+			)
+			c := Lighting(n0.Add(n1).Add(n2).Normalize(), I_a, material, DefaultViewVector, lights)
+			image.addTriangle(p0, p1, p2, transform.Encode(c, alpha), 0)
+			continue
+		}
+		c := FlatShading(geometry.Vec3Of(p0), geometry.Vec3Of(p1), geometry.Vec3Of(p2), I_a, material, DefaultViewVector, lights)
+		image.Scanline(p0, p1, p2, transform.Encode(c, alpha))
+		image.addTriangle(p0, p1, p2, transform.Encode(c, alpha), 0)
+	}
+	return nil
+}
+
+// DrawLine draws a single line onto the Image
+func (image *Image) DrawLine(x0, y0 int, z0 float64, x1, y1 int, z1 float64, c Color) {
+	if x0 > x1 {
+		x0, x1 = x1, x0
+		y0, y1 = y1, y0
+		z0, z1 = z1, z0
+	}
+
+	A := 2 * float64(y1-y0)
+	B := 2 * -float64(x1-x0)
+	m := A / -B
+	if m >= 0 {
+		if m <= 1 {
+			// Draw octants 1 and 5
+			d := A + B/2
+			dz := (z1 - z0) / float64(x1-x0)
+			for x0 <= x1 {
+				image.set(x0, y0, z0, c)
+				if d > 0 {
+					y0++
+					d += B
+				}
+				x0++
+				d += A
+				z0 += dz
+			}
+		} else {
+			// Draw octants 2 and 6
+			d := A/2 + B
+			dz := (z1 - z0) / float64(y1-y0)
+			for y0 <= y1 {
+				image.set(x0, y0, z0, c)
+				if d < 0 {
+					x0++
+					d += A
+				}
+				y0++
+				d += B
+				z0 += dz
+			}
+		}
+	} else {
+		if m < -1 {
+			// Draw octants 3 and 7
+			d := A/2 - B
+			dz := (z1 - z0) / float64(y1-y0)
+			for y0 >= y1 {
+				image.set(x0, y0, z0, c)
+				if d > 0 {
+					x0++
+					d += A
+				}
+				y0--
+				d -= B
+				z0 += dz
+			}
+		} else {
+			d := A - B/2
+			dz := (z1 - z0) / float64(x1-x0)
+			for x0 <= x1 {
+				image.set(x0, y0, z0, c)
+				if d < 0 {
+					y0--
+					d -= B
+				}
+				x0++
+				d += A
+				z0 += dz
+			}
+		}
+	}
+}
+
+// Fill completely fills the Image with a single color
+func (image *Image) Fill(c Color) {
+	image.Flush()
+	for y := 0; y < image.Height; y++ {
+		for x := 0; x < image.Width; x++ {
+			image.Frame[y][x] = c
+		}
+	}
+}
+
+// Clear fills the Image with a single color and empties its z buffer, so that
+// nothing is considered drawn
+func (image *Image) Clear(c Color) {
+	image.discard()
+	image.Fill(c)
+	for y := 0; y < image.Height; y++ {
+		for x := 0; x < image.Width; x++ {
+			image.ZBuffer[y][x] = math.Inf(-1)
+		}
+	}
+	image.paths = nil
+	if image.GBuffer != nil {
+		image.GBuffer.clear()
+	}
+}
+
+// SetDepthEpsilon sets how much closer than the z buffer a pixel must be in
+// order to be drawn, so that the first of two coplanar surfaces wins consistently
+func (image *Image) SetDepthEpsilon(epsilon float64) {
+	image.ZEpsilon = epsilon
+}
+
+// SetDepthOffset sets a depth offset added to everything drawn afterwards,
+// pulling it in front of (or pushing it behind) coplanar surfaces
+func (image *Image) SetDepthOffset(offset float64) {
+	image.ZOffset = offset
+}
+
+// set plots a pixel if it is in front of what is already there, returning
+// whether it replaced it
+func (image *Image) set(x, y int, z float64, c Color) bool {
+	if (x < image.clip.minX || x >= image.clip.maxX) || (y < image.clip.minY || y >= image.clip.maxY) {
+		return false
+	}
+	z += image.ZOffset
+	if z > image.ZBuffer[y][x]+image.ZEpsilon {
+		if c.A < 255 {
+			// Translucent pixels are blended over whatever is behind them, and
+			// don't hide what is drawn behind them later
+			image.Frame[y][x] = c.over(image.Frame[y][x])
+			return false
+		}
+		// Plot so that the y coodinate is the row, and the x coordinate is the column
+		image.Frame[y][x] = c
+
+		// Update Z buffer
+		image.ZBuffer[y][x] = z
+		if image.GBuffer != nil {
+			image.GBuffer.Normals[y][x] = geometry.Vec3{}
+			image.GBuffer.IDs[y][x] = image.shapeID
+			image.GBuffer.Primitives[y][x] = image.primitive
+		}
+		return true
+	}
+	return false
+}
+
+// SavePpm will save the Image as a ppm
+func (image *Image) SavePpm(name string) error {
+	image.Flush()
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	writer := bufio.NewWriter(f)
+	fmt.Fprintln(writer, "P6", image.Width, image.Height, 255)
+	err = image.WriteRaw(writer)
+	if err != nil {
+		return err
+	}
+	return writer.Flush()
+}
+
+// ReadPpm reads an Image from a binary ppm with 8 bits per channel
+func ReadPpm(name string) (*Image, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	reader := bufio.NewReader(f)
+	var magic string
+	var width, height, max int
+	_, err = fmt.Fscan(reader, &magic, &width, &height, &max)
+	if err != nil {
+		return nil, err
+	}
+	if magic != "P6" || max != 255 {
+		return nil, fmt.Errorf("%s is not a 24-bit binary ppm", name)
+	}
+	// A single whitespace character separates the header from the pixels
+	if _, err = reader.ReadByte(); err != nil {
+		return nil, err
+	}
+
+	image := NewImage(height, width)
+	row := make([]byte, 3*width)
+	for y := 0; y < height; y++ {
+		if _, err = io.ReadFull(reader, row); err != nil {
+			return nil, err
+		}
+		// Adjust y coordinate that the origin is the bottom left
+		adjustedY := height - y - 1
+		for x := 0; x < width; x++ {
+			image.Frame[adjustedY][x] = Color{row[3*x], row[3*x+1], row[3*x+2], 255}
+		}
+	}
+	return image, nil
+}
+
+// WriteRaw writes the pixels of the Image as packed 24-bit RGB, top row first
+func (image *Image) WriteRaw(w io.Writer) error {
+	image.Flush()
+	row := make([]byte, 3*image.Width)
+	for y := 0; y < image.Height; y++ {
+		// Adjust y coordinate that the origin is the bottom left
+		adjustedY := image.Height - y - 1
+		for x := 0; x < image.Width; x++ {
+			color := image.Frame[adjustedY][x]
+			row[3*x], row[3*x+1], row[3*x+2] = color.R, color.G, color.B
+		}
+		if _, err := w.Write(row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Save will save an Image into a given format
+func (image *Image) Save(name string) error {
+	// Directories can have dots in their names too
+	extension := filepath.Ext(name)
+	name = strings.TrimSuffix(name, extension)
+	if extension == "" {
+		extension = ".png"
+	}
+
+	if extension == ".ppm" {
+		// save as ppm without converting
+		err := image.SavePpm(fmt.Sprint(name, ".ppm"))
+		return err
+	}
+	if extension == ".gif" {
+		return SaveGIF(image, fmt.Sprint(name, ".gif"))
+	}
+
+	ppm := fmt.Sprint(name, "-tmp.ppm")
+	err := image.SavePpm(ppm)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(ppm)
+	err = exec.Command("convert", ppm, fmt.Sprint(name, extension)).Run()
+	return err
+}
+
+// Depth returns the z-buffer as a grayscale Image, normalized so that the
+// closest pixel is white and the farthest is dark gray
+// Pixels where nothing was drawn are black.
+func (image *Image) Depth() *Image {
+	image.Flush()
+	near, far := math.Inf(-1), math.Inf(1)
+	for _, row := range image.ZBuffer {
+		for _, z := range row {
+			if !math.IsInf(z, -1) {
+				near = math.Max(near, z)
+				far = math.Min(far, z)
+			}
+		}
+	}
+	depth := NewImage(image.Height, image.Width)
+	for y, row := range image.ZBuffer {
+		for x, z := range row {
+			if math.IsInf(z, -1) {
+				continue
+			}
+			shade := 1.0
+			if near > far {
+				shade = (z - far) / (near - far)
+			}
+			v := byte(math.Round(MinDepthShade + shade*(255-MinDepthShade)))
+			depth.Frame[y][x] = Color{v, v, v, 255}
+		}
+	}
+	return depth
+}
+
+// Display displays the Image
+func (image *Image) Display() error {
+	filename := "tmp.ppm"
+	err := image.SavePpm(filename)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(filename)
+
+	err = exec.Command("display", filename).Run()
+	return err
+}
+
+func isVisible(p0, p1, p2 []float64) bool {
+	normal := geometry.Normal(geometry.Vec3Of(p0), geometry.Vec3Of(p1), geometry.Vec3Of(p2))
+	return normal[2] > 0
+}
+
+// Scanline fills a triangle with a single color
+func (image *Image) Scanline(p0, p1, p2 []float64, c Color) {
+	shade := func([]float64) Color {
+		return c
+	}
+	var normal []float64
+	if image.GBuffer != nil {
+		face := geometry.Normal(geometry.Vec3Of(p0), geometry.Vec3Of(p1), geometry.Vec3Of(p2))
+		normal = face[:]
+	}
+	image.fillTriangle(newVertex(p0, normal), newVertex(p1, normal), newVertex(p2, normal), shade)
+}
+
+// ColorModel returns the color model of the Image, so that it can be used as an
+// image.Image
+func (img *Image) ColorModel() color.Model {
+	return color.RGBAModel
+}
+
+// Bounds returns the bounds of the Image, whose top left corner is (0, 0) like
+// any other image.Image
+func (img *Image) Bounds() image.Rectangle {
+	return image.Rect(0, 0, img.Width, img.Height)
+}
+
+// At returns the color of a pixel, where y increases down the image like any
+// other image.Image
+func (img *Image) At(x, y int) color.Color {
+	img.Flush()
+	if x < 0 || x >= img.Width || y < 0 || y >= img.Height {
+		return color.RGBA{}
+	}
+	c := img.Frame[img.Height-y-1][x]
+	return color.RGBA{c.R, c.G, c.B, 255}
+}
@@ -0,0 +1,91 @@
+package image
+
+import (
+	"fmt"
+	"math"
+)
+
+// BaseLayer is the name of the layer that is drawn on by default
+const BaseLayer = "base"
+
+// BlendMode defines how a layer is combined with the layers below it
+type BlendMode int
+
+const (
+	// BlendNormal draws the layer over the layers below it
+	BlendNormal BlendMode = iota
+	// BlendAdd adds the layer to the layers below it
+	BlendAdd
+	// BlendMultiply multiplies the layer with the layers below it
+	BlendMultiply
+	// BlendScreen inverts, multiplies, and inverts again, brightening the result
+	BlendScreen
+)
+
+var blendModes = map[string]BlendMode{
+	"normal":   BlendNormal,
+	"add":      BlendAdd,
+	"multiply": BlendMultiply,
+	"screen":   BlendScreen,
+}
+
+// ParseBlendMode returns the blend mode with the given name
+func ParseBlendMode(name string) (BlendMode, error) {
+	if mode, found := blendModes[name]; found {
+		return mode, nil
+	}
+	return BlendNormal, fmt.Errorf("unknown blend mode '%s'", name)
+}
+
+// Layer is a named image that is composited with the other layers when saved
+type Layer struct {
+	Name    string
+	Image   *Image
+	Mode    BlendMode
+	Opacity float64
+}
+
+// blend combines a single channel of a layer (s) with the channel below it (d)
+func (mode BlendMode) blend(d, s byte, opacity float64) byte {
+	fd, fs := float64(d)/255, float64(s)/255
+	var result float64
+	switch mode {
+	case BlendAdd:
+		result = math.Min(fd+fs, 1)
+	case BlendMultiply:
+		result = fd * fs
+	case BlendScreen:
+		result = 1 - (1-fd)*(1-fs)
+	default:
+		result = fs
+	}
+	return byte(math.Round((fd + (result-fd)*opacity) * 255))
+}
+
+// Composite blends a layer onto the Image
+// Only pixels that were drawn on in the layer are blended.
+func (image *Image) Composite(layer *Layer) {
+	image.Flush()
+	layer.Image.Flush()
+	for y := 0; y < image.Height && y < layer.Image.Height; y++ {
+		for x := 0; x < image.Width && x < layer.Image.Width; x++ {
+			if !layer.Image.Covered(x, y) {
+				continue
+			}
+			d := image.Frame[y][x]
+			s := layer.Image.Frame[y][x]
+			image.Frame[y][x] = Color{
+				layer.Mode.blend(d.R, s.R, layer.Opacity),
+				layer.Mode.blend(d.G, s.G, layer.Opacity),
+				layer.Mode.blend(d.B, s.B, layer.Opacity),
+				255,
+			}
+		}
+	}
+}
+
+// Covered returns whether anything has been drawn at a pixel
+func (image *Image) Covered(x, y int) bool {
+	image.Flush()
+	return !math.IsInf(image.ZBuffer[y][x], -1)
+}
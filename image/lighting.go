@@ -0,0 +1,198 @@
+package image
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/james9909/graphics-engine/geometry"
+)
+
+var (
+	DefaultViewVector = geometry.Vec3{0, 0, 1}
+)
+
+// CreaseAngle is the largest angle in degrees between adjacent polygons that
+// are smoothed together by Phong shading
+const CreaseAngle = 60
+
+// ShadingMode defines how lighting is evaluated across a polygon
+type ShadingMode int
+
+const (
+	// ShadingFlat lights each polygon once using its face normal
+	ShadingFlat ShadingMode = iota
+	// ShadingPhong interpolates vertex normals across each polygon and lights
+	// every pixel
+	ShadingPhong
+)
+
+var shadingModes = map[string]ShadingMode{
+	"flat":  ShadingFlat,
+	"phong": ShadingPhong,
+}
+
+// ParseShadingMode returns the shading mode with the given name
+func ParseShadingMode(name string) (ShadingMode, error) {
+	if mode, found := shadingModes[name]; found {
+		return mode, nil
+	}
+	return ShadingFlat, fmt.Errorf("unknown shading mode '%s'", name)
+}
+
+type LightSource struct {
+	Location      geometry.Vec3
+	Color         Color
+	ColorKnob     string         // knob scaling the color, if any
+	LocationKnobs []string       // knob scaling each coordinate of the location, if any
+	linear        *geometry.Vec3 // color in linear space, when lighting is computed in it
+}
+
+// rgb returns the intensity of each channel of the light
+func (l LightSource) rgb() geometry.Vec3 {
+	if l.linear != nil {
+		return *l.linear
+	}
+	return geometry.Vec3{float64(l.Color.R), float64(l.Color.G), float64(l.Color.B)}
+}
+
+// DefaultShininess is the specular exponent of materials that don't specify
+// one
+const DefaultShininess = 8
+
+// Material holds the lighting constants of a surface
+type Material struct {
+	Ambient   geometry.Vec3 // ambient reflection of each channel
+	Diffuse   geometry.Vec3 // diffuse reflection of each channel
+	Specular  geometry.Vec3 // specular reflection of each channel
+	Intensity geometry.Vec3 // light intensity used instead of each light's color, if not zero
+	Shininess float64       // specular exponent, larger values giving smaller highlights
+	Emissive  geometry.Vec3 // light given off by the surface itself
+	Opacity   float64       // how much the surface hides what is behind it, from 0 to 1
+
+	Reflectivity float64         // how much of the environment the surface mirrors, from 0 to 1
+	Environment  *EnvironmentMap // surroundings the surface mirrors, if any
+
+	NormalMapFile string           // image of the directions the surface faces, if any
+	NormalMapSize float64          // distance the normal map repeats across, or 0 for its width in pixels
+	NormalMap     *NormalMap       // normal map read from NormalMapFile
+	Texture       *Texture         // pattern that varies the color of the surface, if any
+	Image         *Image           // image stretched over the surface, if any
+	ImageBounds   [2]geometry.Vec3 // smallest and largest untransformed points of the shape the image is stretched over
+
+	LightOrder []string // names of the lights in the order their light is added up, or nil for any order
+}
+
+func FlatShading(p0, p1, p2, I_a geometry.Vec3, m Material, view geometry.Vec3, lights map[string]LightSource) geometry.Vec3 {
+	return Lighting(geometry.Normal(p0, p1, p2), I_a, m, view, lights)
+}
+
+// Lighting returns the intensity of light reflected and emitted by a surface
+// with the given normal
+// Reflective surfaces mirror the environment in place of part of their own
+// lighting.
+func Lighting(normal, I_a geometry.Vec3, m Material, view geometry.Vec3, lights map[string]LightSource) geometry.Vec3 {
+	I := ambientLight(I_a, m.Ambient).Add(m.Emissive)
+	reflect := func(light LightSource) {
+		I = I.Add(diffuseLight(normal, m.Intensity, m.Diffuse, light))
+		I = I.Add(specularLight(normal, m.Intensity, m.Specular, m.Shininess, light, view))
+	}
+	if m.LightOrder != nil {
+		// Floating point addition isn't associative, so the order that maps
+		// are ranged over in can change the last bit of the result
+		for _, name := range m.LightOrder {
+			if light, found := lights[name]; found {
+				reflect(light)
+			}
+		}
+	} else {
+		for _, light := range lights {
+			reflect(light)
+		}
+	}
+	if m.Reflectivity > 0 && m.Environment != nil {
+		n, v := normal.Normalize(), view.Normalize()
+		mirrored := m.Environment.Sample(n.Scale(2 * n.Dot(v)).Sub(v))
+		I = I.Scale(1 - m.Reflectivity).Add(mirrored.Scale(m.Reflectivity))
+	}
+	return I
+}
+
+func ambientLight(I_a, K_a geometry.Vec3) geometry.Vec3 {
+	return I_a.Mul(K_a)
+}
+
+func diffuseLight(normal, I_i, K_d geometry.Vec3, light LightSource) geometry.Vec3 {
+	lightVector := light.Location.Normalize()
+	normal = normal.Normalize()
+	diffuseVector := lightVector.Dot(normal)
+
+	diffuse := I_i
+	if I_i == (geometry.Vec3{}) {
+		diffuse = light.rgb()
+	}
+
+	for i := range diffuse {
+		diffuse[i] = math.Max(diffuse[i]*K_d[i]*diffuseVector, 0)
+	}
+
+	return diffuse
+}
+
+func specularLight(normal, I_i, K_s geometry.Vec3, shininess float64, light LightSource, view geometry.Vec3) geometry.Vec3 {
+	lightVector := light.Location.Normalize()
+	normal = normal.Normalize()
+	dot := lightVector.Dot(normal)
+	if dot <= 0 {
+		// The light is behind the surface
+		return geometry.Vec3{}
+	}
+
+	reflect := normal.Scale(dot * 2).Sub(lightVector).Normalize()
+	specularVector := math.Pow(math.Max(reflect.Dot(view.Normalize()), 0), shininess)
+
+	specular := I_i
+	if I_i == (geometry.Vec3{}) {
+		specular = light.rgb()
+	}
+
+	for i := range specular {
+		specular[i] = math.Max(specular[i]*K_s[i]*specularVector, 0)
+	}
+
+	return specular
+}
+
+// VertexNormals returns the normal at each corner of the triangles in em,
+// averaging the normals of the triangles that share the corner's position
+// Triangles meeting at more than CreaseAngle keep a sharp edge between them.
+func VertexNormals(em *geometry.Matrix) []geometry.Vec3 {
+	key := func(p []float64) geometry.Vec3 {
+		// Round so that the seams of generated shapes are joined
+		return geometry.Vec3{math.Round(p[0]*1e3) / 1e3, math.Round(p[1]*1e3) / 1e3, math.Round(p[2]*1e3) / 1e3}
+	}
+
+	faces := make([]geometry.Vec3, em.Cols/3)
+	adjacent := make(map[geometry.Vec3][]int)
+	for i := range faces {
+		faces[i] = geometry.Normal(geometry.Vec3Of(em.GetColumn(3*i)), geometry.Vec3Of(em.GetColumn(3*i+1)), geometry.Vec3Of(em.GetColumn(3*i+2))).Normalize()
+		for j := 0; j < 3; j++ {
+			k := key(em.GetColumn(3*i + j))
+			adjacent[k] = append(adjacent[k], i)
+		}
+	}
+
+	crease := math.Cos(geometry.DegreesToRadians(CreaseAngle))
+	normals := make([]geometry.Vec3, 3*len(faces))
+	for i, face := range faces {
+		for j := 0; j < 3; j++ {
+			var normal geometry.Vec3
+			for _, other := range adjacent[key(em.GetColumn(3*i+j))] {
+				if face.Dot(faces[other]) >= crease {
+					normal = normal.Add(faces[other])
+				}
+			}
+			normals[3*i+j] = normal.Normalize()
+		}
+	}
+	return normals
+}
@@ -0,0 +1,150 @@
+package image
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/james9909/graphics-engine/geometry"
+)
+
+// BumpHeight is how far the brightest pixels of a bump map rise above the
+// darkest, in pixels of the map
+const BumpHeight = 2
+
+// NormalMap is an image of the directions that a surface faces, which bends
+// the normals of shapes so that they look detailed without more triangles
+// Each pixel is a direction along the surface, where red points right across
+// the image, green up it, and blue out of the surface.
+type NormalMap struct {
+	width, height int
+	normals       []geometry.Vec3 // direction of each pixel, bottom row first
+	size          float64         // distance across the shape that the width of the map covers
+}
+
+// NormalMapKey identifies a normal map read from a file and laid over shapes
+// at a size
+type NormalMapKey struct {
+	Filename string
+	Size     float64
+}
+
+// ReadNormalMap reads a normal map from a png, jpeg, or binary ppm image that
+// repeats every size units across shapes, where 0 is the width of the image
+// Gray images are bump maps, where brighter pixels are higher, and are turned
+// into the normals of the bumps.
+func ReadNormalMap(filename string, size float64) (*NormalMap, error) {
+	width, height, pixels, err := readPixels(filename)
+	if err != nil {
+		return nil, err
+	}
+	if width == 0 || height == 0 {
+		return nil, fmt.Errorf("%s: normal map is empty", filename)
+	}
+	if size == 0 {
+		size = float64(width)
+	}
+	n := &NormalMap{width: width, height: height, normals: make([]geometry.Vec3, len(pixels)), size: size}
+
+	gray := true
+	for _, p := range pixels {
+		if p[0] != p[1] || p[1] != p[2] {
+			gray = false
+			break
+		}
+	}
+	if !gray {
+		for i, p := range pixels {
+			n.normals[i] = p.Scale(2.0 / 255).Sub(geometry.Vec3{1, 1, 1}).Normalize()
+		}
+		return n, nil
+	}
+
+	// The bumps slope with the difference in height on either side of each
+	// pixel, wrapping around the edges so that the map still repeats
+	elevation := func(x, y int) float64 {
+		x, y = (x+width)%width, (y+height)%height
+		return pixels[y*width+x][0] / 255 * BumpHeight
+	}
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			dx := (elevation(x+1, y) - elevation(x-1, y)) / 2
+			dy := (elevation(x, y+1) - elevation(x, y-1)) / 2
+			n.normals[y*width+x] = geometry.Vec3{-dx, -dy, 1}.Normalize()
+		}
+	}
+	return n, nil
+}
+
+// Sample returns the direction that the surface faces at a point on the map,
+// in units of distance across shapes, blending the four pixels around it
+func (n *NormalMap) Sample(u, v float64) geometry.Vec3 {
+	scale := float64(n.width) / n.size
+	x, y := u*scale-0.5, v*scale-0.5
+	x0, y0 := math.Floor(x), math.Floor(y)
+	fx, fy := x-x0, y-y0
+	at := func(px, py int) geometry.Vec3 {
+		px, py = px%n.width, py%n.height
+		if px < 0 {
+			px += n.width
+		}
+		if py < 0 {
+			py += n.height
+		}
+		return n.normals[py*n.width+px]
+	}
+	ix, iy := int(x0), int(y0)
+	bottom := at(ix, iy).Scale(1 - fx).Add(at(ix+1, iy).Scale(fx))
+	top := at(ix, iy+1).Scale(1 - fx).Add(at(ix+1, iy+1).Scale(fx))
+	return bottom.Scale(1 - fy).Add(top.Scale(fy)).Normalize()
+}
+
+// boxCoordinates returns where a point of a shape lies on a normal map, which
+// is laid over the side of a box around the shape that the surface faces most
+// The map is turned on each side so that its right and up directions cross to
+// point out of the side.
+func boxCoordinates(p, normal geometry.Vec3) (u, v float64) {
+	ax, ay, az := math.Abs(normal[0]), math.Abs(normal[1]), math.Abs(normal[2])
+	switch {
+	case ax >= ay && ax >= az && normal[0] > 0:
+		return -p[2], p[1]
+	case ax >= ay && ax >= az:
+		return p[2], p[1]
+	case ay >= az && normal[1] > 0:
+		return p[0], -p[2]
+	case ay >= az:
+		return p[0], p[2]
+	case normal[2] < 0:
+		return -p[0], p[1]
+	}
+	return p[0], p[1]
+}
+
+// tangents returns the directions in which the coordinates of a normal map
+// increase across a triangle, or false if the map is squashed flat across it
+func tangents(p0, p1, p2 geometry.Vec3, uv0, uv1, uv2 [2]float64) (geometry.Vec3, geometry.Vec3, bool) {
+	dp1, dp2 := p1.Sub(p0), p2.Sub(p0)
+	du1, dv1 := uv1[0]-uv0[0], uv1[1]-uv0[1]
+	du2, dv2 := uv2[0]-uv0[0], uv2[1]-uv0[1]
+	det := du1*dv2 - du2*dv1
+	if det == 0 {
+		return geometry.Vec3{}, geometry.Vec3{}, false
+	}
+	t := dp1.Scale(dv2).Sub(dp2.Scale(dv1)).Scale(1 / det)
+	b := dp2.Scale(du1).Sub(dp1.Scale(du2)).Scale(1 / det)
+	return t, b, true
+}
+
+// Perturb returns a normal bent by the direction sampled from the map, along
+// the surface directions t and b that the map's right and up follow
+func (n *NormalMap) Perturb(normal, t, b geometry.Vec3, u, v float64) geometry.Vec3 {
+	m := n.Sample(u, v)
+	// Make the directions along the surface perpendicular to the normal,
+	// which is interpolated across the triangle and no longer is
+	t = t.Sub(normal.Scale(normal.Dot(t))).Normalize()
+	b = b.Sub(normal.Scale(normal.Dot(b))).Sub(t.Scale(t.Dot(b))).Normalize()
+	perturbed := t.Scale(m[0]).Add(b.Scale(m[1])).Add(normal.Scale(m[2]))
+	if perturbed.Length() == 0 || math.IsNaN(perturbed[0]) {
+		return normal
+	}
+	return perturbed.Normalize()
+}
@@ -0,0 +1,111 @@
+package image
+
+import (
+	"math"
+)
+
+// OcclusionSamples is the number of nearby pixels that each pixel compares its
+// depth with to find how occluded it is
+const OcclusionSamples = 16
+
+// AmbientOcclusion returns a copy of the Image darkened where its surfaces are
+// hidden by the surfaces around them, in creases and where shapes meet, by up
+// to strength
+// Each pixel looks for surfaces rising in front of its own within radius
+// pixels, using only the z buffer. The surface at each pixel is taken to be a
+// plane sloping with the depths beside it, so that sloped surfaces don't hide
+// themselves.
+func (image *Image) AmbientOcclusion(radius, strength float64) *Image {
+	image.Flush()
+	occluded := image.Copy()
+	depth := func(x, y int) float64 {
+		if x < 0 || y < 0 || x >= image.Width || y >= image.Height {
+			return math.Inf(-1)
+		}
+		return image.ZBuffer[y][x]
+	}
+	// slope returns the change in depth from one pixel to the next, along the
+	// side that changes least so that the edges of shapes keep their slope
+	slope := func(before, z, after float64) float64 {
+		back, forward := z-before, after-z
+		switch {
+		case math.IsInf(before, -1) && math.IsInf(after, -1):
+			return 0
+		case math.IsInf(before, -1):
+			return forward
+		case math.IsInf(after, -1):
+			return back
+		case math.Abs(back) < math.Abs(forward):
+			return back
+		}
+		return forward
+	}
+
+	// Samples spiral out from the pixel, turned by a different angle at each
+	// pixel of a 4x4 tile so that the occlusion can be blurred smooth
+	var samples [OcclusionSamples][2]float64
+	golden := math.Pi * (3 - math.Sqrt(5))
+	for i := range samples {
+		r := radius * math.Sqrt((float64(i)+0.5)/OcclusionSamples)
+		samples[i] = [2]float64{r * math.Cos(float64(i)*golden), r * math.Sin(float64(i)*golden)}
+	}
+	bias := radius * 0.05
+
+	occlusion := make([][]float64, image.Height)
+	for y := range occlusion {
+		occlusion[y] = make([]float64, image.Width)
+		for x := range occlusion[y] {
+			z := image.ZBuffer[y][x]
+			if math.IsInf(z, -1) {
+				continue
+			}
+			gx := slope(depth(x-1, y), z, depth(x+1, y))
+			gy := slope(depth(x, y-1), z, depth(x, y+1))
+			turn := 2 * math.Pi * bayer[y%4][x%4] / 16
+			cos, sin := math.Cos(turn), math.Sin(turn)
+			hidden := 0.0
+			for _, s := range samples {
+				dx, dy := math.Round(s[0]*cos-s[1]*sin), math.Round(s[0]*sin+s[1]*cos)
+				sample := depth(x+int(dx), y+int(dy))
+				if math.IsInf(sample, -1) {
+					continue
+				}
+				if sample-(z+gx*dx+gy*dy) > bias {
+					// Surfaces far in front are separate shapes, which
+					// hide less the further away they are
+					hidden += math.Min(1, radius/math.Abs(sample-z))
+				}
+			}
+			occlusion[y][x] = hidden / OcclusionSamples
+		}
+	}
+
+	// Blur the occlusion over each 4x4 tile of turns, without blurring across
+	// the edges of shapes
+	for y := range occlusion {
+		for x := range occlusion[y] {
+			z := image.ZBuffer[y][x]
+			if math.IsInf(z, -1) {
+				continue
+			}
+			total, count := 0.0, 0.0
+			for by := y - 1; by <= y+2; by++ {
+				for bx := x - 1; bx <= x+2; bx++ {
+					if neighbor := depth(bx, by); !math.IsInf(neighbor, -1) && math.Abs(neighbor-z) < radius {
+						total += occlusion[by][bx]
+						count++
+					}
+				}
+			}
+			shade := math.Max(0, 1-strength*total/count)
+			c := occluded.Frame[y][x]
+			occluded.Frame[y][x] = Color{
+				byte(float64(c.R) * shade),
+				byte(float64(c.G) * shade),
+				byte(float64(c.B) * shade),
+				c.A,
+			}
+		}
+	}
+	return occluded
+}
@@ -0,0 +1,43 @@
+package image
+
+// PoolSize is the most images that each drawer keeps for reuse
+const PoolSize = 8
+
+// ImagePool keeps the tiled images of layers that are no longer drawn on, so
+// that starting a frame over reuses their pixels, z buffers, and tiles instead
+// of allocating new ones for the garbage collector to clean up
+// A pool belongs to a single drawer, and so to a single worker, so it isn't
+// safe for concurrent use.
+type ImagePool struct {
+	free []*Image
+}
+
+// Get returns an Image like NewTiledImage does, reusing a released Image of the
+// same size if there is one
+// A reused Image keeps its G-buffer, emptied, if it had one.
+func (p *ImagePool) Get(height, width int) *Image {
+	for i := len(p.free) - 1; i >= 0; i-- {
+		image := p.free[i]
+		if image.Height != height || image.Width != width {
+			continue
+		}
+		p.free = append(p.free[:i], p.free[i+1:]...)
+		image.Clear(Color{})
+		image.ZEpsilon, image.ZOffset = 0, 0
+		image.shapeID, image.primitive = 0, 0
+		return image
+	}
+	return NewTiledImage(height, width)
+}
+
+// Put releases an Image to be reused by Get, after which it must not be used
+// Images that aren't tiled, such as copies, are left to the garbage collector.
+func (p *ImagePool) Put(image *Image) {
+	if image.bins == nil {
+		return
+	}
+	if len(p.free) == PoolSize {
+		p.free = p.free[1:]
+	}
+	p.free = append(p.free, image)
+}
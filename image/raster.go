@@ -0,0 +1,235 @@
+package image
+
+import (
+	"math"
+
+	"github.com/james9909/graphics-engine/geometry"
+)
+
+const (
+	// SubpixelBits is the number of fractional bits kept for screen coordinates
+	SubpixelBits = 8
+	// subpixelScale is the number of sub-pixel steps in a single pixel
+	subpixelScale = 1 << SubpixelBits
+)
+
+// toFixed snaps a screen coordinate to the sub-pixel grid
+func toFixed(v float64) int64 {
+	return int64(math.Round(v * subpixelScale))
+}
+
+// ceilFixed returns the first pixel coordinate at or after a fixed point value
+func ceilFixed(v int64) int {
+	return int((v + subpixelScale - 1) >> SubpixelBits)
+}
+
+// roundFixed returns the pixel coordinate nearest to a fixed point value
+func roundFixed(v int64) int {
+	return int((v + subpixelScale/2) >> SubpixelBits)
+}
+
+// DrawLineSubpixel draws a single line onto the Image, honoring the fractional
+// part of its endpoints
+// The line is stepped along its major axis, and each pixel on that axis takes
+// the minor coordinate nearest to the exact line
+func (image *Image) DrawLineSubpixel(x0, y0, z0, x1, y1, z1 float64, c Color) {
+	if !geometry.Finite(x0, y0, x1, y1) {
+		return
+	}
+	image.bin(boundsOf([]float64{x0, x1}, []float64{y0, y1}), func(tile *Image) {
+		tile.rasterizeLine(x0, y0, z0, x1, y1, z1, c)
+	})
+}
+
+// rasterizeLine draws a line onto the pixels of the Image within its clip
+func (image *Image) rasterizeLine(x0, y0, z0, x1, y1, z1 float64, c Color) {
+	fx0, fy0, fx1, fy1 := toFixed(x0), toFixed(y0), toFixed(x1), toFixed(y1)
+	dx, dy := fx1-fx0, fy1-fy0
+	if abs64(dx) >= abs64(dy) {
+		if dx < 0 {
+			fx0, fy0, z0, fx1, fy1, z1 = fx1, fy1, z1, fx0, fy0, z0
+			dx, dy = -dx, -dy
+		}
+		for x := roundFixed(fx0); x <= roundFixed(fx1); x++ {
+			y, t := roundFixed(fy0), 0.0
+			if dx != 0 {
+				offset := int64(x)<<SubpixelBits - fx0
+				y = roundFixed(fy0 + dy*offset/dx)
+				t = geometry.Clamp(float64(offset)/float64(dx), 0, 1)
+			}
+			image.set(x, y, z0+(z1-z0)*t, c)
+		}
+	} else {
+		if dy < 0 {
+			fx0, fy0, z0, fx1, fy1, z1 = fx1, fy1, z1, fx0, fy0, z0
+			dx, dy = -dx, -dy
+		}
+		for y := roundFixed(fy0); y <= roundFixed(fy1); y++ {
+			offset := int64(y)<<SubpixelBits - fy0
+			x := roundFixed(fx0 + dx*offset/dy)
+			t := geometry.Clamp(float64(offset)/float64(dy), 0, 1)
+			image.set(x, y, z0+(z1-z0)*t, c)
+		}
+	}
+}
+
+// DrawThickLine draws a line of the given width in pixels onto the Image by
+// expanding it into a quad with square caps
+// Lines that are at most a pixel wide are drawn with DrawLineSubpixel
+func (image *Image) DrawThickLine(x0, y0, z0, x1, y1, z1, width float64, c Color) {
+	dx, dy := x1-x0, y1-y0
+	length := math.Hypot(dx, dy)
+	if width <= 1 || length == 0 {
+		image.DrawLineSubpixel(x0, y0, z0, x1, y1, z1, c)
+		return
+	}
+	// Half-width vectors along and perpendicular to the line
+	ux, uy := dx/length*width/2, dy/length*width/2
+	nx, ny := -uy, ux
+
+	a := vertex{x: x0 - ux + nx, y: y0 - uy + ny, z: z0, w: 1}
+	b := vertex{x: x0 - ux - nx, y: y0 - uy - ny, z: z0, w: 1}
+	d := vertex{x: x1 + ux + nx, y: y1 + uy + ny, z: z1, w: 1}
+	e := vertex{x: x1 + ux - nx, y: y1 + uy - ny, z: z1, w: 1}
+	shade := func([]float64) Color {
+		return c
+	}
+	image.fillTriangle(a, b, e, shade)
+	image.fillTriangle(a, e, d, shade)
+}
+
+// vertex is a screen space point along with the attributes interpolated across
+// the polygons it belongs to
+type vertex struct {
+	x, y  float64
+	z     float64   // depth
+	w     float64   // homogeneous coordinate, 1 without a perspective projection
+	attrs []float64 // additional attributes such as colors or normals
+}
+
+// newVertex creates a vertex out of a column of an edge/polygon matrix
+func newVertex(p []float64, attrs []float64) vertex {
+	w := 1.0
+	if len(p) > 3 && p[3] != 0 {
+		w = p[3]
+	}
+	return vertex{x: p[0], y: p[1], z: p[2], w: w, attrs: attrs}
+}
+
+// interpolant holds the attributes of a vertex divided by w, which (unlike the
+// attributes themselves) vary linearly in screen space
+type interpolant struct {
+	q  float64   // 1/w
+	zq float64   // z/w
+	aq []float64 // attrs/w
+}
+
+func newInterpolant(v vertex) interpolant {
+	q := 1 / v.w
+	aq := make([]float64, len(v.attrs))
+	for i, a := range v.attrs {
+		aq[i] = a * q
+	}
+	return interpolant{q: q, zq: v.z * q, aq: aq}
+}
+
+// lerpInterpolant linearly interpolates between two interpolants
+func lerpInterpolant(a, b interpolant, t float64) interpolant {
+	aq := make([]float64, len(a.aq))
+	for i := range aq {
+		aq[i] = a.aq[i] + (b.aq[i]-a.aq[i])*t
+	}
+	return interpolant{
+		q:  a.q + (b.q-a.q)*t,
+		zq: a.zq + (b.zq-a.zq)*t,
+		aq: aq,
+	}
+}
+
+// fillTriangle fills a triangle, interpolating depth and attributes in a
+// perspective correct manner and calling shade for the color of each pixel
+// Vertices are snapped to the sub-pixel grid and edges are evaluated exactly
+// at every scanline. A pixel is filled when its center lies in [min, max) of
+// the triangle, so triangles sharing an edge never overlap or leave gaps.
+// The first three attributes of the vertices, if they have any, are the normal
+// of the surface, which the G-buffer keeps.
+func (image *Image) fillTriangle(v0, v1, v2 vertex, shade func(attrs []float64) Color) {
+	// Coordinates that aren't finite can't be snapped to the sub-pixel grid
+	if !geometry.Finite(v0.x, v0.y, v1.x, v1.y, v2.x, v2.y) {
+		return
+	}
+	bounds := boundsOf([]float64{v0.x, v1.x, v2.x}, []float64{v0.y, v1.y, v2.y})
+	image.bin(bounds, func(tile *Image) {
+		tile.rasterizeTriangle(v0, v1, v2, shade)
+	})
+}
+
+// rasterizeTriangle fills the pixels of a triangle within the clip of the Image
+func (image *Image) rasterizeTriangle(v0, v1, v2 vertex, shade func(attrs []float64) Color) {
+	// Re-order vertices so that v0 is the lowest and v2 is the highest
+	if v0.y > v1.y {
+		v0, v1 = v1, v0
+	}
+	if v0.y > v2.y {
+		v0, v2 = v2, v0
+	}
+	if v1.y > v2.y {
+		v1, v2 = v2, v1
+	}
+	x0, y0 := toFixed(v0.x), toFixed(v0.y)
+	x1, y1 := toFixed(v1.x), toFixed(v1.y)
+	x2, y2 := toFixed(v2.x), toFixed(v2.y)
+	// Triangles without area cover no pixels, though rounding their edges
+	// separately could otherwise leave a sliver along them
+	if y0 == y2 || (x1-x0)*(y2-y0) == (x2-x0)*(y1-y0) {
+		return
+	}
+
+	i0, i1, i2 := newInterpolant(v0), newInterpolant(v1), newInterpolant(v2)
+	for y := max(ceilFixed(y0), image.clip.minY); y < min(ceilFixed(y2), image.clip.maxY); y++ {
+		fy := int64(y) << SubpixelBits
+		longX := x0 + (x2-x0)*(fy-y0)/(y2-y0)
+		long := lerpInterpolant(i0, i2, float64(fy-y0)/float64(y2-y0))
+		var shortX int64
+		var short interpolant
+		if fy < y1 {
+			shortX = x0 + (x1-x0)*(fy-y0)/(y1-y0)
+			short = lerpInterpolant(i0, i1, float64(fy-y0)/float64(y1-y0))
+		} else {
+			shortX = x1 + (x2-x1)*(fy-y1)/(y2-y1)
+			short = lerpInterpolant(i1, i2, float64(fy-y1)/float64(y2-y1))
+		}
+		if longX < shortX {
+			image.fillSpan(y, longX, shortX, long, short, shade)
+		} else {
+			image.fillSpan(y, shortX, longX, short, long, shade)
+		}
+	}
+}
+
+// fillSpan fills the pixels of a single scanline between two fixed point x
+// coordinates
+func (image *Image) fillSpan(y int, xl, xr int64, left, right interpolant, shade func(attrs []float64) Color) {
+	if xl == xr {
+		return
+	}
+	attrs := make([]float64, len(left.aq))
+	for x := max(ceilFixed(xl), image.clip.minX); x < min(ceilFixed(xr), image.clip.maxX); x++ {
+		t := float64(int64(x)<<SubpixelBits-xl) / float64(xr-xl)
+		w := 1 / (left.q + (right.q-left.q)*t)
+		z := (left.zq + (right.zq-left.zq)*t) * w
+		for i := range attrs {
+			attrs[i] = (left.aq[i] + (right.aq[i]-left.aq[i])*t) * w
+		}
+		if image.set(x, y, z, shade(attrs)) && image.GBuffer != nil && len(attrs) >= 3 {
+			image.GBuffer.Normals[y][x] = geometry.Vec3Of(attrs).Normalize()
+		}
+	}
+}
+
+func abs64(v int64) int64 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
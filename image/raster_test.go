@@ -0,0 +1,193 @@
+package image
+
+import (
+	"math"
+	"testing"
+)
+
+// fill fills a triangle on an empty image and returns the pixels it covers,
+// failing the test if any pixel is shaded more than once
+func fill(t *testing.T, width, height int, p0, p1, p2 [2]float64) map[[2]int]bool {
+	t.Helper()
+	image := NewImage(height, width)
+	shaded := 0
+	shade := func([]float64) Color {
+		shaded++
+		return White
+	}
+	vertex := func(p [2]float64) vertex {
+		return newVertex([]float64{p[0], p[1], 0, 1}, nil)
+	}
+	image.fillTriangle(vertex(p0), vertex(p1), vertex(p2), shade)
+	covered := make(map[[2]int]bool)
+	for y, row := range image.Frame {
+		for x, c := range row {
+			if c == White {
+				covered[[2]int{x, y}] = true
+			}
+		}
+	}
+	if shaded != len(covered) {
+		t.Errorf("triangle %v %v %v: shaded %d times but covers %d pixels", p0, p1, p2, shaded, len(covered))
+	}
+	return covered
+}
+
+func TestDegenerateTrianglesFillNothing(t *testing.T) {
+	triangles := [][3][2]float64{
+		{{5, 5}, {5, 5}, {5, 5}},               // a single point
+		{{0, 0}, {5, 5}, {10, 10}},             // collinear
+		{{0.5, 0.5}, {10.5, 3.5}, {20.5, 6.5}}, // collinear, off the pixel grid
+		{{0, 5}, {10, 5}, {20, 5}},             // horizontal
+		{{5, 0}, {5, 10}, {5, 20}},             // vertical
+		{{3, 3}, {3, 3}, {15, 9}},              // two vertices in the same place
+	}
+	for _, tri := range triangles {
+		if covered := fill(t, 32, 32, tri[0], tri[1], tri[2]); len(covered) != 0 {
+			t.Errorf("triangle %v covers %d pixels, want 0", tri, len(covered))
+		}
+	}
+}
+
+func TestSubpixelThinTriangles(t *testing.T) {
+	// Between pixel centers, so no pixel is covered
+	if covered := fill(t, 128, 32, [2]float64{0, 0.2}, [2]float64{100, 0.4}, [2]float64{0, 0.45}); len(covered) != 0 {
+		t.Errorf("thin horizontal triangle between rows covers %d pixels, want 0", len(covered))
+	}
+	if covered := fill(t, 32, 64, [2]float64{10.1, 0}, [2]float64{10.3, 0}, [2]float64{10.2, 50}); len(covered) != 0 {
+		t.Errorf("thin vertical triangle between columns covers %d pixels, want 0", len(covered))
+	}
+	// Straddling a row of pixel centers, so only pixels of that row are covered
+	covered := fill(t, 128, 32, [2]float64{0, 0.9}, [2]float64{100, 1.1}, [2]float64{0, 1.05})
+	if len(covered) == 0 {
+		t.Error("thin triangle across row 1 covers no pixels")
+	}
+	for p := range covered {
+		if p[1] != 1 || p[0] < 0 || p[0] >= 100 {
+			t.Errorf("thin triangle across row 1 covers pixel %v", p)
+		}
+	}
+}
+
+func TestNonFiniteTrianglesAreRejected(t *testing.T) {
+	for _, bad := range []float64{math.NaN(), math.Inf(1), math.Inf(-1)} {
+		triangles := [][3][2]float64{
+			{{bad, 0}, {20, 0}, {10, 20}},
+			{{0, 0}, {20, bad}, {10, 20}},
+			{{0, 0}, {20, 0}, {bad, bad}},
+		}
+		for _, tri := range triangles {
+			if covered := fill(t, 32, 32, tri[0], tri[1], tri[2]); len(covered) != 0 {
+				t.Errorf("triangle %v covers %d pixels, want 0", tri, len(covered))
+			}
+		}
+	}
+}
+
+func TestSharedEdgesCoverPixelsOnce(t *testing.T) {
+	// A quad split along its diagonal, off the pixel grid
+	a, b, c, d := [2]float64{0.5, 0.5}, [2]float64{20.3, 1.7}, [2]float64{18.9, 19.2}, [2]float64{1.1, 17.6}
+	first, second := fill(t, 32, 32, a, b, c), fill(t, 32, 32, a, c, d)
+	for p := range first {
+		if second[p] {
+			t.Errorf("pixel %v is covered by both halves of the quad", p)
+		}
+	}
+	// Each row of the quad is a single run without gaps between the halves
+	rows := make(map[int][]int)
+	for _, half := range []map[[2]int]bool{first, second} {
+		for p := range half {
+			rows[p[1]] = append(rows[p[1]], p[0])
+		}
+	}
+	for y, xs := range rows {
+		low, high := xs[0], xs[0]
+		for _, x := range xs {
+			low, high = min(low, x), max(high, x)
+		}
+		if high-low+1 != len(xs) {
+			t.Errorf("row %d of the quad has a gap: %d pixels from %d to %d", y, len(xs), low, high)
+		}
+	}
+
+	// A fan of triangles around a point covers every pixel inside it once
+	const size, sides = 100, 12
+	center := [2]float64{50.3, 49.7}
+	counts := make(map[[2]int]int)
+	for i := 0; i < sides; i++ {
+		corner := func(i int) [2]float64 {
+			angle := 2 * math.Pi * float64(i) / sides
+			return [2]float64{center[0] + 40*math.Cos(angle), center[1] + 40*math.Sin(angle)}
+		}
+		for p := range fill(t, size, size, center, corner(i), corner(i+1)) {
+			counts[p]++
+		}
+	}
+	inside := 40*math.Cos(math.Pi/sides) - 1
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			p := [2]int{x, y}
+			if counts[p] > 1 {
+				t.Errorf("pixel %v is covered %d times by the fan", p, counts[p])
+			}
+			if math.Hypot(float64(x)-center[0], float64(y)-center[1]) < inside && counts[p] != 1 {
+				t.Errorf("pixel %v inside the fan is covered %d times, want 1", p, counts[p])
+			}
+		}
+	}
+}
+
+func TestFlatTopAndBottomTriangles(t *testing.T) {
+	// Flat bottom and flat top are by y, so a flat bottom triangle has two
+	// vertices at its lowest y. Pixel centers on the edge at the lowest y are
+	// covered, and those on the edge at the highest y belong to whatever is past
+	// it.
+	bottom := fill(t, 32, 32, [2]float64{0, 0}, [2]float64{10, 0}, [2]float64{5, 10})
+	for x := 0; x < 10; x++ {
+		if !bottom[[2]int{x, 0}] {
+			t.Errorf("flat bottom triangle doesn't cover pixel (%d, 0) on its bottom edge", x)
+		}
+	}
+	for p := range bottom {
+		if p[1] < 0 || p[1] >= 10 {
+			t.Errorf("flat bottom triangle covers pixel %v outside of rows 0 to 9", p)
+		}
+	}
+
+	top := fill(t, 32, 32, [2]float64{0, 10}, [2]float64{10, 10}, [2]float64{5, 0})
+	for p := range top {
+		if p[1] < 0 || p[1] >= 10 {
+			t.Errorf("flat top triangle covers pixel %v outside of rows 0 to 9", p)
+		}
+	}
+	for x := 0; x < 32; x++ {
+		if p := [2]int{x, 1}; top[p] != (x == 5) {
+			t.Errorf("flat top triangle covers pixel %v: %t, want only (5, 1) on row 1", p, top[p])
+		}
+		if p := [2]int{x, 9}; top[p] != (x >= 1 && x < 10) {
+			t.Errorf("flat top triangle covers pixel %v: %t, want (1, 9) to (9, 9) on row 9", p, top[p])
+		}
+	}
+
+	// A flat top triangle stacked on a flat bottom one shares a row of pixel
+	// centers, which only the upper triangle covers
+	lower := fill(t, 32, 32, [2]float64{0, 10}, [2]float64{20, 10}, [2]float64{10, 0})
+	upper := fill(t, 32, 32, [2]float64{0, 10}, [2]float64{20, 10}, [2]float64{10, 20})
+	for x := 0; x < 20; x++ {
+		p := [2]int{x, 10}
+		if lower[p] || !upper[p] {
+			t.Errorf("pixel %v on the shared edge: covered by lower %t and upper %t, want only upper", p, lower[p], upper[p])
+		}
+	}
+
+	// The order of the vertices doesn't matter
+	reordered := fill(t, 32, 32, [2]float64{5, 10}, [2]float64{0, 0}, [2]float64{10, 0})
+	if len(reordered) != len(bottom) {
+		t.Errorf("reordering the vertices covers %d pixels, want %d", len(reordered), len(bottom))
+	}
+	for p := range bottom {
+		if !reordered[p] {
+			t.Errorf("reordering the vertices doesn't cover pixel %v", p)
+		}
+	}
+}
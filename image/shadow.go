@@ -0,0 +1,147 @@
+package image
+
+import (
+	"math"
+
+	"github.com/james9909/graphics-engine/geometry"
+)
+
+const (
+	// DefaultShadowSize is the default resolution of shadow maps
+	DefaultShadowSize = 1024
+	// ShadowBias is how much farther from a light a point must be than the
+	// closest occluder to be in shadow, in texels of the shadow map, so surfaces
+	// don't shadow themselves
+	ShadowBias = 2
+)
+
+// ShadowMap is the depth of the surfaces closest to a directional light,
+// rendered orthographically along the light's direction
+type ShadowMap struct {
+	direction geometry.Vec3 // unit vector pointing towards the light
+	u, v      geometry.Vec3 // axes of the map, perpendicular to the direction
+	minU      float64
+	minV      float64
+	scale     float64     // texels per unit of distance
+	size      int         // width and height of the map in texels
+	depth     [][]float64 // distance towards the light of the closest surface
+}
+
+// NewShadowMap returns an empty shadow map for a light that covers an image of
+// the given height and width
+// Everything within the image, and as deep as the image is wide or tall, can
+// cast and receive shadows.
+func NewShadowMap(light LightSource, height, width, size int) *ShadowMap {
+	direction := light.Location.Normalize()
+	// Any vector that isn't parallel to the direction gives the map's axes
+	up := geometry.Vec3{0, 1, 0}
+	if math.Abs(direction[1]) > 0.9 {
+		up = geometry.Vec3{1, 0, 0}
+	}
+	u := up.Cross(direction).Normalize()
+	v := direction.Cross(u)
+
+	extent := math.Max(float64(height), float64(width))
+	minU, minV := math.Inf(1), math.Inf(1)
+	maxU, maxV := math.Inf(-1), math.Inf(-1)
+	for _, x := range []float64{0, float64(width)} {
+		for _, y := range []float64{0, float64(height)} {
+			for _, z := range []float64{-extent, extent} {
+				p := geometry.Vec3{x, y, z}
+				pu, pv := p.Dot(u), p.Dot(v)
+				minU, maxU = math.Min(minU, pu), math.Max(maxU, pu)
+				minV, maxV = math.Min(minV, pv), math.Max(maxV, pv)
+			}
+		}
+	}
+
+	depth := make([][]float64, size)
+	for i := range depth {
+		depth[i] = make([]float64, size)
+		for j := range depth[i] {
+			depth[i][j] = math.Inf(-1)
+		}
+	}
+	return &ShadowMap{
+		direction: direction,
+		u:         u,
+		v:         v,
+		minU:      minU,
+		minV:      minV,
+		scale:     float64(size-1) / math.Max(maxU-minU, maxV-minV),
+		size:      size,
+		depth:     depth,
+	}
+}
+
+// project returns the texel coordinates and depth of a point in the map
+func (m *ShadowMap) project(p geometry.Vec3) (float64, float64, float64) {
+	return (p.Dot(m.u) - m.minU) * m.scale, (p.Dot(m.v) - m.minV) * m.scale, p.Dot(m.direction)
+}
+
+// Cast renders the triangles of em into the map
+// Triangles facing either way cast shadows.
+func (m *ShadowMap) Cast(em *geometry.Matrix) {
+	for i := 0; i < em.Cols-2; i += 3 {
+		x0, y0, z0 := m.project(geometry.Vec3Of(em.GetColumn(i)))
+		x1, y1, z1 := m.project(geometry.Vec3Of(em.GetColumn(i + 1)))
+		x2, y2, z2 := m.project(geometry.Vec3Of(em.GetColumn(i + 2)))
+		area := (x1-x0)*(y2-y0) - (x2-x0)*(y1-y0)
+		if area == 0 {
+			continue
+		}
+		minX := int(math.Max(math.Floor(math.Min(x0, math.Min(x1, x2))), 0))
+		maxX := int(math.Min(math.Ceil(math.Max(x0, math.Max(x1, x2))), float64(m.size-1)))
+		minY := int(math.Max(math.Floor(math.Min(y0, math.Min(y1, y2))), 0))
+		maxY := int(math.Min(math.Ceil(math.Max(y0, math.Max(y1, y2))), float64(m.size-1)))
+		for ty := minY; ty <= maxY; ty++ {
+			for tx := minX; tx <= maxX; tx++ {
+				// Barycentric coordinates of the texel's center
+				px, py := float64(tx)+0.5, float64(ty)+0.5
+				w0 := ((x1-px)*(y2-py) - (x2-px)*(y1-py)) / area
+				w1 := ((x2-px)*(y0-py) - (x0-px)*(y2-py)) / area
+				w2 := 1 - w0 - w1
+				if w0 < 0 || w1 < 0 || w2 < 0 {
+					continue
+				}
+				z := w0*z0 + w1*z1 + w2*z2
+				if z > m.depth[ty][tx] {
+					m.depth[ty][tx] = z
+				}
+			}
+		}
+	}
+}
+
+// Lit returns whether a point can be seen from the light
+func (m *ShadowMap) Lit(p geometry.Vec3) bool {
+	x, y, z := m.project(p)
+	tx, ty := int(x), int(y)
+	if tx < 0 || tx >= m.size || ty < 0 || ty >= m.size {
+		return true
+	}
+	return m.depth[ty][tx] <= z+ShadowBias/m.scale
+}
+
+// litLights returns the lights that reach a point, given the shadow map of
+// each light
+func litLights(lights map[string]LightSource, shadows map[string]*ShadowMap, p geometry.Vec3) map[string]LightSource {
+	var lit map[string]LightSource
+	for name, m := range shadows {
+		if _, found := lights[name]; !found || m.Lit(p) {
+			continue
+		}
+		if lit == nil {
+			// Copy before removing lights so the original is untouched
+			lit = make(map[string]LightSource, len(lights))
+			for n, light := range lights {
+				lit[n] = light
+			}
+		}
+		delete(lit, name)
+	}
+	if lit == nil {
+		return lights
+	}
+	return lit
+}
@@ -0,0 +1,145 @@
+package image
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/james9909/graphics-engine/geometry"
+)
+
+// savedPath is how WriteState writes a vectorPath, followed by its points
+type savedPath struct {
+	Points       int64
+	Closed, Fill bool
+	C            Color
+	Width        float64
+}
+
+// WriteState writes everything drawn onto the Image to w: its pixels, depths,
+// G-buffer if it keeps one, and vector paths, so that ReadState can put them
+// back and drawing can carry on where it left off
+func (image *Image) WriteState(w io.Writer) error {
+	image.Flush()
+	write := func(data interface{}) error {
+		return binary.Write(w, binary.LittleEndian, data)
+	}
+	if err := write([3]int64{int64(image.Height), int64(image.Width), int64(len(image.paths))}); err != nil {
+		return err
+	}
+	if err := write(image.GBuffer != nil); err != nil {
+		return err
+	}
+	for y := 0; y < image.Height; y++ {
+		if err := write(image.Frame[y]); err != nil {
+			return err
+		}
+		if err := write(image.ZBuffer[y]); err != nil {
+			return err
+		}
+		if image.GBuffer == nil {
+			continue
+		}
+		if err := write(image.GBuffer.Normals[y]); err != nil {
+			return err
+		}
+		ids := make([]int64, 2*image.Width)
+		for x := 0; x < image.Width; x++ {
+			ids[2*x], ids[2*x+1] = int64(image.GBuffer.IDs[y][x]), int64(image.GBuffer.Primitives[y][x])
+		}
+		if err := write(ids); err != nil {
+			return err
+		}
+	}
+	for _, path := range image.paths {
+		header := savedPath{int64(len(path.points)), path.closed, path.fill, path.c, path.width}
+		if err := write(header); err != nil {
+			return err
+		}
+		if err := write(path.points); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReadState replaces everything drawn onto the Image with what WriteState
+// wrote for an Image of the same size
+// The G-buffer is kept only if the Image keeps one.
+func (image *Image) ReadState(r io.Reader) error {
+	read := func(data interface{}) error {
+		err := binary.Read(r, binary.LittleEndian, data)
+		if err == io.EOF {
+			return io.ErrUnexpectedEOF
+		}
+		return err
+	}
+	var size [3]int64
+	if err := read(&size); err != nil {
+		return err
+	}
+	if size[0] != int64(image.Height) || size[1] != int64(image.Width) {
+		return fmt.Errorf("saved image is %dx%d, not %dx%d", size[1], size[0], image.Width, image.Height)
+	}
+	if size[2] < 0 {
+		return errors.New("saved image has a negative number of paths")
+	}
+	var gbuffer bool
+	if err := read(&gbuffer); err != nil {
+		return err
+	}
+	image.Flush()
+	var normals []geometry.Vec3
+	var ids []int64
+	if gbuffer {
+		normals = make([]geometry.Vec3, image.Width)
+		ids = make([]int64, 2*image.Width)
+	}
+	for y := 0; y < image.Height; y++ {
+		if err := read(image.Frame[y]); err != nil {
+			return err
+		}
+		if err := read(image.ZBuffer[y]); err != nil {
+			return err
+		}
+		if !gbuffer {
+			continue
+		}
+		if err := read(normals); err != nil {
+			return err
+		}
+		if err := read(ids); err != nil {
+			return err
+		}
+		if image.GBuffer == nil {
+			continue
+		}
+		copy(image.GBuffer.Normals[y], normals)
+		for x := 0; x < image.Width; x++ {
+			image.GBuffer.IDs[y][x], image.GBuffer.Primitives[y][x] = int(ids[2*x]), int(ids[2*x+1])
+		}
+	}
+	image.paths = image.paths[:0]
+	for i := int64(0); i < size[2]; i++ {
+		var header savedPath
+		if err := read(&header); err != nil {
+			return err
+		}
+		if header.Points < 1 {
+			return errors.New("saved image has a path without points")
+		}
+		path := vectorPath{
+			points: make([][2]float64, header.Points),
+			closed: header.Closed,
+			fill:   header.Fill,
+			c:      header.C,
+			width:  header.Width,
+		}
+		if err := read(path.points); err != nil {
+			return err
+		}
+		image.paths = append(image.paths, path)
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package image
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// vectorPath is a line or polygon drawn onto an Image, kept so that the Image
+// can be saved as vector paths
+type vectorPath struct {
+	points [][2]float64
+	closed bool // whether the last point connects back to the first
+	fill   bool // whether the path is filled instead of stroked
+	c      Color
+	width  float64
+}
+
+// addLine records a line, extending the previous path if the line continues
+// it, as the segments of curves do
+func (image *Image) addLine(x0, y0, x1, y1 float64, c Color, width float64) {
+	if n := len(image.paths); n > 0 {
+		last := &image.paths[n-1]
+		end := last.points[len(last.points)-1]
+		if !last.closed && !last.fill && last.c == c && last.width == width &&
+			math.Abs(end[0]-x0) < 1e-6 && math.Abs(end[1]-y0) < 1e-6 {
+			last.points = append(last.points, [2]float64{x1, y1})
+			return
+		}
+	}
+	image.paths = append(image.paths, vectorPath{
+		points: [][2]float64{{x0, y0}, {x1, y1}},
+		c:      c,
+		width:  width,
+	})
+}
+
+// addTriangle records the outline of a triangle, or the triangle filled if
+// width is 0
+func (image *Image) addTriangle(p0, p1, p2 []float64, c Color, width float64) {
+	image.paths = append(image.paths, vectorPath{
+		points: [][2]float64{{p0[0], p0[1]}, {p1[0], p1[1]}, {p2[0], p2[1]}},
+		closed: true,
+		fill:   width == 0,
+		c:      c,
+		width:  width,
+	})
+}
+
+// SvgColor returns a color as an SVG paint and opacity
+func SvgColor(c Color) (string, float64) {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B), float64(c.A) / 255
+}
+
+// SvgBlendModes are the CSS blend modes matching each BlendMode
+var SvgBlendModes = map[BlendMode]string{
+	BlendAdd:      "plus-lighter",
+	BlendMultiply: "multiply",
+	BlendScreen:   "screen",
+}
+
+// WriteSVGPaths writes the paths drawn onto an Image as SVG elements
+func WriteSVGPaths(w *bufio.Writer, image *Image) {
+	for _, path := range image.paths {
+		points := make([]string, len(path.points))
+		for i, p := range path.points {
+			// SVG's origin is the top left
+			points[i] = fmt.Sprintf("%.2f,%.2f", p[0], float64(image.Height)-p[1])
+		}
+		element := "polyline"
+		if path.closed {
+			element = "polygon"
+		}
+		paint, opacity := SvgColor(path.c)
+		fmt.Fprintf(w, `<%s points="%s"`, element, strings.Join(points, " "))
+		if path.fill {
+			// Crisp edges keep seams from showing between adjacent triangles
+			fmt.Fprintf(w, ` fill="%s" shape-rendering="crispEdges"`, paint)
+			if opacity < 1 {
+				fmt.Fprintf(w, ` fill-opacity="%.3g"`, opacity)
+			}
+		} else {
+			fmt.Fprintf(w, ` fill="none" stroke="%s" stroke-width="%g" stroke-linecap="round" stroke-linejoin="round"`, paint, path.width)
+			if opacity < 1 {
+				fmt.Fprintf(w, ` stroke-opacity="%.3g"`, opacity)
+			}
+		}
+		fmt.Fprintln(w, "/>")
+	}
+}
@@ -0,0 +1,42 @@
+package image
+
+import (
+	"math"
+	"runtime"
+	"unsafe"
+)
+
+// NewSwappedImage returns a new Image like NewTiledImage does, but keeps its
+// pixels and z buffer in a file in dir instead of in memory, so that it can be
+// larger than memory
+// The operating system pages the tiles being rasterized in and writes the
+// rest out to the file, which is removed once the Image is garbage collected.
+func NewSwappedImage(dir string, height, width int) (*Image, error) {
+	pixels := height * width
+	// Depths go first, where the mapping starts, so that they're aligned
+	data, err := mapFile(dir, pixels*(8+int(unsafe.Sizeof(Color{}))))
+	if err != nil {
+		return nil, err
+	}
+	depths := unsafe.Slice((*float64)(unsafe.Pointer(&data[0])), pixels)
+	colors := unsafe.Slice((*Color)(unsafe.Pointer(&data[8*pixels])), pixels)
+	frame := make([][]Color, height)
+	zBuffer := make([][]float64, height)
+	for i := 0; i < height; i++ {
+		frame[i] = colors[i*width : (i+1)*width : (i+1)*width]
+		zBuffer[i] = depths[i*width : (i+1)*width : (i+1)*width]
+		for j := 0; j < width; j++ {
+			zBuffer[i][j] = math.Inf(-1)
+		}
+	}
+	image := &Image{
+		Frame:   frame,
+		ZBuffer: zBuffer,
+		Height:  height,
+		Width:   width,
+		clip:    rect{0, 0, width, height},
+		bins:    newBins(height, width),
+	}
+	runtime.AddCleanup(image, unmapFile, data)
+	return image, nil
+}
@@ -0,0 +1,14 @@
+//go:build !unix
+
+package image
+
+import "errors"
+
+// mapFile maps size bytes of a new file in dir into memory, which isn't
+// supported outside of unix
+func mapFile(dir string, size int) ([]byte, error) {
+	return nil, errors.New("keeping images in files is only supported on unix")
+}
+
+// unmapFile unmaps memory mapped by mapFile
+func unmapFile(data []byte) {}
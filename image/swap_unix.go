@@ -0,0 +1,28 @@
+//go:build unix
+
+package image
+
+import (
+	"os"
+	"syscall"
+)
+
+// mapFile maps size bytes of a new file in dir into memory
+// The file is removed right away, so it only lasts as long as the mapping.
+func mapFile(dir string, size int) ([]byte, error) {
+	f, err := os.CreateTemp(dir, "swap-*")
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	defer os.Remove(f.Name())
+	if err = f.Truncate(int64(size)); err != nil {
+		return nil, err
+	}
+	return syscall.Mmap(int(f.Fd()), 0, size, syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_SHARED)
+}
+
+// unmapFile unmaps memory mapped by mapFile
+func unmapFile(data []byte) {
+	syscall.Munmap(data)
+}
@@ -0,0 +1,159 @@
+package image
+
+import (
+	"fmt"
+	"unicode"
+)
+
+// Corner is a corner of an image that labels are drawn in
+type Corner int
+
+const (
+	// CornerTopLeft is the top left corner
+	CornerTopLeft Corner = iota
+	// CornerTopRight is the top right corner
+	CornerTopRight
+	// CornerBottomLeft is the bottom left corner
+	CornerBottomLeft
+	// CornerBottomRight is the bottom right corner
+	CornerBottomRight
+)
+
+var corners = map[string]Corner{
+	"topleft":     CornerTopLeft,
+	"topright":    CornerTopRight,
+	"bottomleft":  CornerBottomLeft,
+	"bottomright": CornerBottomRight,
+}
+
+// ParseCorner returns the corner with the given name
+func ParseCorner(name string) (Corner, error) {
+	if corner, found := corners[name]; found {
+		return corner, nil
+	}
+	return CornerTopLeft, fmt.Errorf("unknown corner '%s'", name)
+}
+
+const (
+	// GlyphWidth is the width of a glyph in pixels, not including spacing
+	GlyphWidth = 5
+	// GlyphHeight is the height of a glyph in pixels
+	GlyphHeight = 7
+)
+
+// font is a 5x7 bitmap font, where each glyph is a list of rows from top to
+// bottom and the highest of the five bits in a row is its leftmost pixel
+var font = map[rune][GlyphHeight]byte{
+	'0': {0x0E, 0x11, 0x13, 0x15, 0x19, 0x11, 0x0E},
+	'1': {0x04, 0x0C, 0x04, 0x04, 0x04, 0x04, 0x0E},
+	'2': {0x0E, 0x11, 0x01, 0x02, 0x04, 0x08, 0x1F},
+	'3': {0x1F, 0x02, 0x04, 0x02, 0x01, 0x11, 0x0E},
+	'4': {0x02, 0x06, 0x0A, 0x12, 0x1F, 0x02, 0x02},
+	'5': {0x1F, 0x10, 0x1E, 0x01, 0x01, 0x11, 0x0E},
+	'6': {0x06, 0x08, 0x10, 0x1E, 0x11, 0x11, 0x0E},
+	'7': {0x1F, 0x01, 0x02, 0x04, 0x08, 0x08, 0x08},
+	'8': {0x0E, 0x11, 0x11, 0x0E, 0x11, 0x11, 0x0E},
+	'9': {0x0E, 0x11, 0x11, 0x0F, 0x01, 0x02, 0x0C},
+	'A': {0x0E, 0x11, 0x11, 0x11, 0x1F, 0x11, 0x11},
+	'B': {0x1E, 0x11, 0x11, 0x1E, 0x11, 0x11, 0x1E},
+	'C': {0x0E, 0x11, 0x10, 0x10, 0x10, 0x11, 0x0E},
+	'D': {0x1C, 0x12, 0x11, 0x11, 0x11, 0x12, 0x1C},
+	'E': {0x1F, 0x10, 0x10, 0x1E, 0x10, 0x10, 0x1F},
+	'F': {0x1F, 0x10, 0x10, 0x1E, 0x10, 0x10, 0x10},
+	'G': {0x0E, 0x11, 0x10, 0x17, 0x11, 0x11, 0x0F},
+	'H': {0x11, 0x11, 0x11, 0x1F, 0x11, 0x11, 0x11},
+	'I': {0x0E, 0x04, 0x04, 0x04, 0x04, 0x04, 0x0E},
+	'J': {0x07, 0x02, 0x02, 0x02, 0x02, 0x12, 0x0C},
+	'K': {0x11, 0x12, 0x14, 0x18, 0x14, 0x12, 0x11},
+	'L': {0x10, 0x10, 0x10, 0x10, 0x10, 0x10, 0x1F},
+	'M': {0x11, 0x1B, 0x15, 0x15, 0x11, 0x11, 0x11},
+	'N': {0x11, 0x11, 0x19, 0x15, 0x13, 0x11, 0x11},
+	'O': {0x0E, 0x11, 0x11, 0x11, 0x11, 0x11, 0x0E},
+	'P': {0x1E, 0x11, 0x11, 0x1E, 0x10, 0x10, 0x10},
+	'Q': {0x0E, 0x11, 0x11, 0x11, 0x15, 0x12, 0x0D},
+	'R': {0x1E, 0x11, 0x11, 0x1E, 0x14, 0x12, 0x11},
+	'S': {0x0F, 0x10, 0x10, 0x0E, 0x01, 0x01, 0x1E},
+	'T': {0x1F, 0x04, 0x04, 0x04, 0x04, 0x04, 0x04},
+	'U': {0x11, 0x11, 0x11, 0x11, 0x11, 0x11, 0x0E},
+	'V': {0x11, 0x11, 0x11, 0x11, 0x11, 0x0A, 0x04},
+	'W': {0x11, 0x11, 0x11, 0x15, 0x15, 0x15, 0x0A},
+	'X': {0x11, 0x11, 0x0A, 0x04, 0x0A, 0x11, 0x11},
+	'Y': {0x11, 0x11, 0x11, 0x0A, 0x04, 0x04, 0x04},
+	'Z': {0x1F, 0x01, 0x02, 0x04, 0x08, 0x10, 0x1F},
+	' ': {0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00},
+	':': {0x00, 0x0C, 0x0C, 0x00, 0x0C, 0x0C, 0x00},
+	'.': {0x00, 0x00, 0x00, 0x00, 0x00, 0x0C, 0x0C},
+	',': {0x00, 0x00, 0x00, 0x00, 0x0C, 0x04, 0x08},
+	'-': {0x00, 0x00, 0x00, 0x1F, 0x00, 0x00, 0x00},
+	'+': {0x00, 0x04, 0x04, 0x1F, 0x04, 0x04, 0x00},
+	'=': {0x00, 0x00, 0x1F, 0x00, 0x1F, 0x00, 0x00},
+	'/': {0x00, 0x01, 0x02, 0x04, 0x08, 0x10, 0x00},
+	'%': {0x18, 0x19, 0x02, 0x04, 0x08, 0x13, 0x03},
+	'(': {0x02, 0x04, 0x08, 0x08, 0x08, 0x04, 0x02},
+	')': {0x08, 0x04, 0x02, 0x02, 0x02, 0x04, 0x08},
+	'_': {0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x1F},
+}
+
+// TextWidth returns the width in pixels of text drawn at the given scale
+func TextWidth(text string, scale int) int {
+	return len([]rune(text)) * (GlyphWidth + 1) * scale
+}
+
+// DrawText draws text over the Image, ignoring the z buffer
+// x and y are the top left corner of the text, and each pixel of the font is
+// drawn as a scale x scale block. Letters are drawn in uppercase and unknown
+// characters are skipped.
+func (image *Image) DrawText(x, y int, text string, scale int, c Color) {
+	for _, r := range text {
+		glyph, found := font[unicode.ToUpper(r)]
+		if found {
+			for row := 0; row < GlyphHeight; row++ {
+				for col := 0; col < GlyphWidth; col++ {
+					if glyph[row]&(1<<uint(GlyphWidth-1-col)) != 0 {
+						image.FillRect(x+col*scale, y-(row+1)*scale+1, scale, scale, c)
+					}
+				}
+			}
+		}
+		x += (GlyphWidth + 1) * scale
+	}
+}
+
+// FillRect fills a rectangle of the Image whose bottom left corner is at (x, y),
+// ignoring the z buffer
+func (image *Image) FillRect(x, y, width, height int, c Color) {
+	image.Flush()
+	for py := y; py < y+height; py++ {
+		for px := x; px < x+width; px++ {
+			if px >= 0 && px < image.Width && py >= 0 && py < image.Height {
+				image.Frame[py][px] = c
+			}
+		}
+	}
+}
+
+// DrawLabel draws text with a black backdrop in the top left corner of the
+// Image, below any lines drawn before it
+// line is the index of the line of text, starting at 0
+func (image *Image) DrawLabel(line int, text string, scale int) {
+	image.DrawCornerLabel(CornerTopLeft, line, text, scale)
+}
+
+// DrawCornerLabel draws text with a black backdrop in a corner of the Image,
+// further from the corner than any lines drawn before it
+// line is the index of the line of text, starting at 0
+func (image *Image) DrawCornerLabel(corner Corner, line int, text string, scale int) {
+	padding := 2 * scale
+	lineHeight := (GlyphHeight + 2) * scale
+	width := TextWidth(text, scale) + 2*padding
+	top := image.Height - 1 - padding - line*lineHeight
+	if corner == CornerBottomLeft || corner == CornerBottomRight {
+		top = lineHeight - 1 + line*lineHeight
+	}
+	left := 0
+	if corner == CornerTopRight || corner == CornerBottomRight {
+		left = image.Width - width
+	}
+	image.FillRect(left, top-lineHeight+1, width, lineHeight+padding, Black)
+	image.DrawText(left+padding, top, text, scale, White)
+}
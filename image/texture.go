@@ -0,0 +1,146 @@
+package image
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/james9909/graphics-engine/geometry"
+)
+
+// DefaultTextureSize is the distance that textures repeat across when the
+// script doesn't say
+const DefaultTextureSize = 32
+
+// DefaultTextureTint is how much of the color of its constants the second
+// color of a texture keeps when the script doesn't say
+const DefaultTextureTint = 0.25
+
+// TextureOctaves is the number of octaves of noise that marble and wood are
+// disturbed by
+const TextureOctaves = 4
+
+// TextureKind defines the pattern of a texture
+type TextureKind int
+
+const (
+	// TextureChecker alternates between the two colors in cubes
+	TextureChecker TextureKind = iota
+	// TextureStripes alternates between the two colors in slabs along x
+	TextureStripes
+	// TextureMarble draws veins of the second color along x, twisted by
+	// noise
+	TextureMarble
+	// TextureWood draws rings of the second color around the y axis, warped
+	// by noise
+	TextureWood
+)
+
+var textureKinds = map[string]TextureKind{
+	"checker": TextureChecker,
+	"stripes": TextureStripes,
+	"marble":  TextureMarble,
+	"wood":    TextureWood,
+}
+
+// ParseTextureKind returns the texture pattern with the given name
+func ParseTextureKind(name string) (TextureKind, error) {
+	if kind, found := textureKinds[name]; found {
+		return kind, nil
+	}
+	return TextureChecker, fmt.Errorf("unknown texture '%s'", name)
+}
+
+// Texture is a pattern computed across a surface, which varies its color from
+// that of its constants to a second color
+type Texture struct {
+	Kind TextureKind
+	Size float64       // distance that the pattern repeats across
+	Tint geometry.Vec3 // second color, as a fraction of the reflection of each channel of the constants
+}
+
+// Pattern returns how much of the second color a point of a shape has, from 0
+// to 1, where the point is in the shape's coordinates from before it was
+// transformed
+func (t *Texture) Pattern(p geometry.Vec3) float64 {
+	// Nudge the point off of the edges of the pattern, which the faces of
+	// boxes the size of the pattern would otherwise lie on
+	x, y, z := p[0]/t.Size+1e-6, p[1]/t.Size+1e-6, p[2]/t.Size+1e-6
+	switch t.Kind {
+	case TextureStripes:
+		return parity(math.Floor(x))
+	case TextureMarble:
+		vein := math.Sin((x + 2*geometry.FractalNoise(x, y, z, TextureOctaves)) * math.Pi)
+		return math.Pow(1-math.Abs(vein), 4)
+	case TextureWood:
+		r := math.Hypot(x, z) + 0.3*geometry.FractalNoise(x, y/4, z, TextureOctaves)
+		ring := r - math.Floor(r)
+		return ring * ring
+	}
+	return parity(math.Floor(x) + math.Floor(y) + math.Floor(z))
+}
+
+// parity returns 1 for odd whole numbers and 0 for even ones
+func parity(n float64) float64 {
+	return math.Abs(math.Mod(n, 2))
+}
+
+// Apply returns the material with its color varied by the texture at a point
+func (t *Texture) Apply(m Material, p geometry.Vec3) Material {
+	f := t.Pattern(p)
+	scale := geometry.Vec3{1, 1, 1}.Scale(1 - f).Add(t.Tint.Scale(f))
+	m.Ambient = m.Ambient.Mul(scale)
+	m.Diffuse = m.Diffuse.Mul(scale)
+	return m
+}
+
+// Sample returns the color of the Image at a point, where the Image spans 0
+// to 1 across and up, blending the four pixels around it
+func (image *Image) Sample(u, v float64) geometry.Vec3 {
+	x := geometry.Clamp(u*float64(image.Width)-0.5, 0, float64(image.Width-1))
+	y := geometry.Clamp(v*float64(image.Height)-0.5, 0, float64(image.Height-1))
+	ix, iy := int(x), int(y)
+	fx, fy := x-float64(ix), y-float64(iy)
+	at := func(px, py int) geometry.Vec3 {
+		if px >= image.Width {
+			px = image.Width - 1
+		}
+		if py >= image.Height {
+			py = image.Height - 1
+		}
+		c := image.Frame[py][px]
+		return geometry.Vec3{float64(c.R), float64(c.G), float64(c.B)}
+	}
+	bottom := at(ix, iy).Scale(1 - fx).Add(at(ix+1, iy).Scale(fx))
+	top := at(ix, iy+1).Scale(1 - fx).Add(at(ix+1, iy+1).Scale(fx))
+	return bottom.Scale(1 - fy).Add(top.Scale(fy))
+}
+
+// stretch returns where points lie on an image stretched over the side of the
+// box from low to high that a surface facing normal faces most, from 0 to 1
+// across and up the image
+func stretch(normal, low, high geometry.Vec3) func(p geometry.Vec3) (float64, float64) {
+	// The corners of the box span the side in each direction
+	u0, v0 := math.Inf(1), math.Inf(1)
+	u1, v1 := math.Inf(-1), math.Inf(-1)
+	for corner := 0; corner < 8; corner++ {
+		c := low
+		for i := range c {
+			if corner&(1<<uint(i)) != 0 {
+				c[i] = high[i]
+			}
+		}
+		u, v := boxCoordinates(c, normal)
+		u0, u1 = math.Min(u0, u), math.Max(u1, u)
+		v0, v1 = math.Min(v0, v), math.Max(v1, v)
+	}
+	across := func(x, x0, x1 float64) float64 {
+		if x1 > x0 {
+			return (x - x0) / (x1 - x0)
+		}
+		return 0.5
+	}
+	return func(p geometry.Vec3) (float64, float64) {
+		u, v := boxCoordinates(p, normal)
+		return across(u, u0, u1), across(v, v0, v1)
+	}
+}
@@ -0,0 +1,137 @@
+package image
+
+import (
+	"math"
+	"runtime"
+	"sync"
+)
+
+// TileSize is the width and height in pixels of the tiles that tiled images
+// are rendered in
+const TileSize = 64
+
+// rect is a rectangle of pixels, including its minimum and excluding its
+// maximum
+type rect struct {
+	minX, minY int
+	maxX, maxY int
+}
+
+// drawOp is drawing binned into a tile, to be rasterized when the Image is
+// flushed
+type drawOp struct {
+	zEpsilon  float64 // depth epsilon of the Image when the drawing was binned
+	zOffset   float64 // depth offset of the Image when the drawing was binned
+	shapeID   int     // ID of the shape being drawn when the drawing was binned
+	primitive int     // triangle or line segment being drawn when the drawing was binned
+	draw      func(tile *Image)
+}
+
+// NewTiledImage returns a new Image that bins what is drawn on it into tiles,
+// rasterizing the tiles in parallel when its pixels are needed
+// Each tile is rasterized in the order things were drawn, so the result is
+// the same as drawing directly.
+func NewTiledImage(height, width int) *Image {
+	image := NewImage(height, width)
+	image.bins = newBins(height, width)
+	return image
+}
+
+// newBins returns empty bins for the tiles of an Image with the given height
+// and width
+func newBins(height, width int) [][]drawOp {
+	columns := (width + TileSize - 1) / TileSize
+	rows := (height + TileSize - 1) / TileSize
+	return make([][]drawOp, columns*rows)
+}
+
+// bin defers drawing that covers bounds until the Image is flushed, or draws
+// it right away if the Image isn't tiled
+func (image *Image) bin(bounds rect, draw func(tile *Image)) {
+	if image.bins == nil {
+		draw(image)
+		return
+	}
+	if bounds.maxX <= 0 || bounds.maxY <= 0 || bounds.minX >= image.Width || bounds.minY >= image.Height {
+		return
+	}
+	columns := (image.Width + TileSize - 1) / TileSize
+	minX, maxX := max(bounds.minX, 0)/TileSize, (min(bounds.maxX, image.Width)-1)/TileSize
+	minY, maxY := max(bounds.minY, 0)/TileSize, (min(bounds.maxY, image.Height)-1)/TileSize
+	op := drawOp{zEpsilon: image.ZEpsilon, zOffset: image.ZOffset, shapeID: image.shapeID, primitive: image.primitive, draw: draw}
+	for ty := minY; ty <= maxY; ty++ {
+		for tx := minX; tx <= maxX; tx++ {
+			image.bins[ty*columns+tx] = append(image.bins[ty*columns+tx], op)
+		}
+	}
+	image.pending = true
+}
+
+// boundsOf returns the pixels that a shape with the given corners can cover
+func boundsOf(xs, ys []float64) rect {
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	for i := range xs {
+		minX, maxX = math.Min(minX, xs[i]), math.Max(maxX, xs[i])
+		minY, maxY = math.Min(minY, ys[i]), math.Max(maxY, ys[i])
+	}
+	// Lines round to the nearest pixel, so include a pixel of slack
+	return rect{
+		minX: int(math.Floor(minX)) - 1,
+		minY: int(math.Floor(minY)) - 1,
+		maxX: int(math.Ceil(maxX)) + 2,
+		maxY: int(math.Ceil(maxY)) + 2,
+	}
+}
+
+// Flush rasterizes everything binned into the tiles of the Image, rendering
+// the tiles in parallel
+func (image *Image) Flush() {
+	if !image.pending {
+		return
+	}
+	image.pending = false
+	tiles := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < runtime.NumCPU(); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for t := range tiles {
+				image.flushTile(t)
+			}
+		}()
+	}
+	for t, ops := range image.bins {
+		if len(ops) > 0 {
+			tiles <- t
+		}
+	}
+	close(tiles)
+	wg.Wait()
+}
+
+// flushTile rasterizes what was binned into a single tile
+func (image *Image) flushTile(t int) {
+	columns := (image.Width + TileSize - 1) / TileSize
+	x, y := t%columns*TileSize, t/columns*TileSize
+	// The tile shares the frame and z buffer of the Image, but can only draw
+	// within its own pixels
+	tile := *image
+	tile.bins = nil
+	tile.clip = rect{x, y, min(x+TileSize, image.Width), min(y+TileSize, image.Height)}
+	for _, op := range image.bins[t] {
+		tile.ZEpsilon, tile.ZOffset = op.zEpsilon, op.zOffset
+		tile.shapeID, tile.primitive = op.shapeID, op.primitive
+		op.draw(&tile)
+	}
+	image.bins[t] = image.bins[t][:0]
+}
+
+// discard drops everything binned that hasn't been rasterized yet
+func (image *Image) discard() {
+	for t := range image.bins {
+		image.bins[t] = image.bins[t][:0]
+	}
+	image.pending = false
+}
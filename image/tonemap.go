@@ -0,0 +1,126 @@
+package image
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/james9909/graphics-engine/geometry"
+)
+
+// ToneMap defines how light intensities brighter than an image can show are
+// brought into range
+type ToneMap int
+
+const (
+	// ToneMapClamp cuts intensities off at full brightness
+	ToneMapClamp ToneMap = iota
+	// ToneMapReinhard compresses highlights with x / (1 + x)
+	ToneMapReinhard
+	// ToneMapACES compresses highlights with a filmic curve fit to the ACES
+	// reference transform
+	ToneMapACES
+)
+
+var toneMaps = map[string]ToneMap{
+	"clamp":    ToneMapClamp,
+	"reinhard": ToneMapReinhard,
+	"aces":     ToneMapACES,
+}
+
+// ParseToneMap returns the tone map with the given name
+func ParseToneMap(name string) (ToneMap, error) {
+	if toneMap, found := toneMaps[name]; found {
+		return toneMap, nil
+	}
+	return ToneMapClamp, fmt.Errorf("unknown tone map '%s'", name)
+}
+
+// apply maps an intensity, where 1 is full brightness, to between 0 and 1
+func (t ToneMap) apply(v float64) float64 {
+	v = math.Max(v, 0)
+	switch t {
+	case ToneMapReinhard:
+		v = v / (1 + v)
+	case ToneMapACES:
+		v = (v * (2.51*v + 0.03)) / (v*(2.43*v+0.59) + 0.14)
+	}
+	return math.Min(v, 1)
+}
+
+// srgbToLinear converts a channel from 0 to 1 in sRGB to linear intensity
+func srgbToLinear(v float64) float64 {
+	if v <= 0.04045 {
+		return v / 12.92
+	}
+	return math.Pow((v+0.055)/1.055, 2.4)
+}
+
+// linearToSRGB converts a linear intensity from 0 to 1 to a channel in sRGB
+func linearToSRGB(v float64) float64 {
+	if v <= 0.0031308 {
+		return v * 12.92
+	}
+	return 1.055*math.Pow(v, 1/2.4) - 0.055
+}
+
+// ColorTransform turns the light intensities computed by lighting, which can
+// exceed full brightness, into colors
+type ColorTransform struct {
+	Exposure float64 // multiplier applied to intensities before tone mapping
+	ToneMap  ToneMap
+	Linear   bool // whether lighting is computed in linear space and encoded as sRGB
+}
+
+// DefaultColorTransform clamps intensities as they are
+var DefaultColorTransform = ColorTransform{Exposure: 1, ToneMap: ToneMapClamp}
+
+// decode converts a channel from 0 to 255 to the space lighting is computed in
+func (t ColorTransform) decode(v float64) float64 {
+	if !t.Linear {
+		return v
+	}
+	return 255 * srgbToLinear(geometry.Clamp(v, 0, 255)/255)
+}
+
+// decodeAll decodes each channel of v
+func (t ColorTransform) decodeAll(v geometry.Vec3) geometry.Vec3 {
+	var decoded geometry.Vec3
+	for i := range v {
+		decoded[i] = t.decode(v[i])
+	}
+	return decoded
+}
+
+// Prepare returns the ambient light, material, and lights converted to the
+// space lighting is computed in
+func (t ColorTransform) Prepare(ambient geometry.Vec3, m Material, lights map[string]LightSource) (geometry.Vec3, Material, map[string]LightSource) {
+	if !t.Linear {
+		return ambient, m, lights
+	}
+	m.Intensity = t.decodeAll(m.Intensity)
+	m.Emissive = t.decodeAll(m.Emissive)
+	if m.Environment != nil {
+		m.Environment = m.Environment.linear
+	}
+	decoded := make(map[string]LightSource, len(lights))
+	for name, light := range lights {
+		linear := t.decodeAll(light.rgb())
+		light.linear = &linear
+		decoded[name] = light
+	}
+	return t.decodeAll(ambient), m, decoded
+}
+
+// Encode returns the color of a light intensity computed by lighting, with
+// channels where 255 is full brightness
+func (t ColorTransform) Encode(I geometry.Vec3, alpha byte) Color {
+	var channels [3]byte
+	for i := range channels {
+		v := t.ToneMap.apply(I[i] / 255 * t.Exposure)
+		if t.Linear {
+			v = linearToSRGB(v)
+		}
+		channels[i] = byte(v*255 + 0.5)
+	}
+	return Color{channels[0], channels[1], channels[2], alpha}
+}
@@ -1,19 +1,192 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
 	"os"
+	"os/signal"
 	"runtime/pprof"
+
+	"github.com/james9909/graphics-engine/image"
+	"github.com/james9909/graphics-engine/parser"
+	"github.com/james9909/graphics-engine/render"
 )
 
 var profile = flag.Bool("profile", false, "Profile")
+var video = flag.String("video", "", "Stream animation frames to ffmpeg and encode them into this file")
+var control = flag.String("control", "", "Render a live preview controlled through this unix socket")
+var midi = flag.String("midi", "", "Render a live preview with knobs driven by this raw MIDI device")
+var midiMap = flag.String("midimap", "", "Comma separated knob=cc[:min:max] mappings for -midi")
+var osc = flag.String("osc", "", "Render a live preview with knobs driven by OSC messages on this UDP address")
+var tune = flag.String("tune", "", "Render a live preview with knobs driven by sliders on a web page, served on this address")
+var lineWidth = flag.Float64("linewidth", 1, "Width of lines and wireframes in pixels")
+var snapLines = flag.Bool("snaplines", false, "Snap the ends of lines and wireframes to whole pixels instead of drawing them with sub-pixel accuracy")
+var dither = flag.String("dither", "none", "Dithering for gifs and low bit depths: none, ordered, or floyd")
+var bits = flag.Int("bits", 8, "Bits per color channel of saved images")
+var resume = flag.Bool("resume", false, "Resume an interrupted render, keeping the frames an animation already rendered or what a still image saved in its last checkpoint")
+var swap = flag.String("swap", "", "Keep the pixels of rendered images in files in this directory instead of in memory, to render images larger than memory")
+var delay = flag.Int("delay", image.DefaultDelay, "Delay between frames of animated gifs in hundredths of a second, overriding the script's fps")
+var loop = flag.Int("loop", 0, "Number of times animated gifs repeat after playing once: 0 repeats forever, -1 plays once")
+var width = flag.Int("width", image.DefaultWidth, "Width of rendered images in pixels, overriding the script's resolution")
+var height = flag.Int("height", image.DefaultHeight, "Height of rendered images in pixels, overriding the script's resolution")
+var preview = flag.String("preview", "", "Show each frame as it is rendered in a web browser, served on this address")
+var stats = flag.Bool("stats", false, "Stamp the frame number, triangle count, and render time onto saved images")
+var interactive = flag.Bool("interactive", false, "Run statements from standard input as they are entered, drawing onto the same image")
+var check = flag.Bool("check", false, "Check the script for problems and report them without rendering anything")
+var knobs = flag.Bool("knobs", false, "Print the value of every knob in each frame as CSV without rendering anything")
+var quad = flag.Bool("quad", false, "Draw the scene from the front, top, side, and in perspective in each quarter of the image")
+var stereo = flag.String("stereo", "", "Draw the scene seen by two eyes as sbs (side by side) or anaglyph (red and cyan) images")
+var passes = flag.Bool("passes", false, "Also save the depth, normal, and shape ID passes of every image, named after it with _depth, _normal, and _id")
+var deterministic = flag.Bool("deterministic", false, "Render the same image bit for bit every time, adding up the light of each light source in a fixed order")
+var watch = flag.Bool("watch", false, "Render the script again whenever it or a file it includes or loads changes")
 
 func main() {
 	flag.Parse()
 	args := flag.Args()
-	parser := NewParser()
+	ditherMode, err := image.ParseDitherMode(*dither)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if *bits < 1 || *bits > 8 {
+		fmt.Fprintln(os.Stderr, "bits must be between 1 and 8")
+		os.Exit(1)
+	}
+	if *delay < 0 {
+		fmt.Fprintln(os.Stderr, "delay must not be negative")
+		os.Exit(1)
+	}
+	if *width <= 0 || *height <= 0 {
+		fmt.Fprintln(os.Stderr, "width and height must be greater than zero")
+		os.Exit(1)
+	}
+	// Only the dimensions and delay given on the command line override the
+	// script
+	var fixedWidth, fixedHeight int
+	fixedDelay := -1
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "width":
+			fixedWidth = *width
+		case "height":
+			fixedHeight = *height
+		case "delay":
+			fixedDelay = *delay
+		}
+	})
+	var mapping map[byte]parser.KnobRange
+	if *midi != "" {
+		mapping, err = parser.ParseMIDIMap(*midiMap)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+	if *interactive && (len(args) > 0 || *watch) {
+		fmt.Fprintln(os.Stderr, "-interactive reads from standard input, and cannot be given a script or -watch")
+		os.Exit(1)
+	}
+	layout := render.LayoutSingle
+	if *quad {
+		layout = render.LayoutQuad
+	}
+	if *stereo != "" {
+		if *quad {
+			fmt.Fprintln(os.Stderr, "-stereo cannot be combined with -quad")
+			os.Exit(1)
+		}
+		layout, err = render.ParseStereoLayout(*stereo)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+	if *interactive && layout != render.LayoutSingle {
+		fmt.Fprintln(os.Stderr, "-quad and -stereo cannot be combined with -interactive")
+		os.Exit(1)
+	}
+	if *passes && layout != render.LayoutSingle {
+		fmt.Fprintln(os.Stderr, "-passes cannot be combined with -quad or -stereo")
+		os.Exit(1)
+	}
+	if *resume && *video != "" {
+		fmt.Fprintln(os.Stderr, "-resume cannot be combined with -video, whose frames are streamed to ffmpeg instead of kept")
+		os.Exit(1)
+	}
+	if *deterministic && *stats {
+		fmt.Fprintln(os.Stderr, "-deterministic cannot be combined with -stats, which stamps the render time")
+		os.Exit(1)
+	}
+	if *watch {
+		if len(args) == 0 {
+			fmt.Fprintln(os.Stderr, "-watch needs a script file")
+			os.Exit(1)
+		}
+		if *control != "" || *midi != "" || *osc != "" || *tune != "" {
+			fmt.Fprintln(os.Stderr, "-watch cannot be combined with -control, -midi, -osc, or -tune")
+			os.Exit(1)
+		}
+	}
+	var previewer *render.Preview
+	if *preview != "" {
+		previewer = render.NewPreview()
+		if err := previewer.Listen(*preview); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+
+	// Interrupting a render stops it cleanly, keeping the frames finished so
+	// far, and interrupting again exits right away
+	// Watching and interactive mode are left to exit when interrupted.
+	ctx, stop := context.Background(), func() {}
+	if !*watch && !*interactive {
+		ctx, stop = signal.NotifyContext(ctx, os.Interrupt)
+		go func() {
+			<-ctx.Done()
+			stop()
+		}()
+	}
+
+	newParser := func() *parser.Parser {
+		p := parser.NewParser()
+		p.SetContext(ctx)
+		p.SetLineWidth(*lineWidth)
+		p.SetSnapLines(*snapLines)
+		p.SetDither(ditherMode, *bits)
+		p.SetStats(*stats)
+		p.SetLayout(layout)
+		p.SetPasses(*passes)
+		p.SetDeterministic(*deterministic)
+		p.SetResume(*resume)
+		p.SetSwap(*swap)
+		p.SetCheck(*check)
+		p.SetDumpKnobs(*knobs)
+		p.SetGIF(fixedDelay, *loop)
+		p.SetResolution(fixedWidth, fixedHeight)
+		if *video != "" {
+			p.SetVideo(*video)
+		}
+		if *control != "" {
+			p.SetControl(*control)
+		}
+		if *midi != "" {
+			p.SetMIDI(*midi, mapping)
+		}
+		if *osc != "" {
+			p.SetOSC(*osc)
+		}
+		if *tune != "" {
+			p.SetTuner(*tune)
+		}
+		if previewer != nil {
+			p.SetPreview(previewer)
+		}
+		return p
+	}
 
 	if *profile {
 		f, err := os.Create("cpu.prof")
@@ -24,14 +197,29 @@ func main() {
 		defer pprof.StopCPUProfile()
 	}
 
-	var err error
-	if len(args) == 0 {
-		err = parser.ParseInput()
+	if *watch {
+		err := parser.Watch(args[0], newParser)
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+	p := newParser()
+	if *interactive {
+		err = p.Interactive(os.Stdin, os.Stdout)
+	} else if len(args) == 0 {
+		err = p.ParseInput()
 	} else {
-		err = parser.ParseFile(args[0])
+		err = p.ParseFile(args[0])
+	}
+	stop()
+	if errors.Is(err, context.Canceled) {
+		os.Exit(130)
 	}
 	if err != nil {
-		fmt.Fprintln(os.Stderr, "Parse error:", err)
+		fmt.Fprintln(os.Stderr, "Error:", err)
 		os.Exit(1)
 	}
+	if previewer != nil {
+		fmt.Println("Rendering finished, still previewing until interrupted")
+		select {}
+	}
 }
@@ -0,0 +1,147 @@
+package parser
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+)
+
+// ReadWAV reads a PCM or floating point WAV file, mixing its channels into a
+// single channel of samples between -1 and 1
+func ReadWAV(filename string) ([]float64, int, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(data) < 12 || string(data[0:4]) != "RIFF" || string(data[8:12]) != "WAVE" {
+		return nil, 0, fmt.Errorf("%s is not a wav file", filename)
+	}
+
+	var format, channels, bitsPerSample int
+	var sampleRate int
+	var samples []byte
+	for chunk := data[12:]; len(chunk) >= 8; {
+		id := string(chunk[0:4])
+		size := int(binary.LittleEndian.Uint32(chunk[4:8]))
+		body := chunk[8:]
+		if size > len(body) {
+			size = len(body)
+		}
+		switch id {
+		case "fmt ":
+			if size < 16 {
+				return nil, 0, errors.New("invalid wav format chunk")
+			}
+			format = int(binary.LittleEndian.Uint16(body[0:2]))
+			channels = int(binary.LittleEndian.Uint16(body[2:4]))
+			sampleRate = int(binary.LittleEndian.Uint32(body[4:8]))
+			bitsPerSample = int(binary.LittleEndian.Uint16(body[14:16]))
+			if format == 0xFFFE && size >= 26 {
+				// WAVE_FORMAT_EXTENSIBLE stores the real format in its sub-format
+				format = int(binary.LittleEndian.Uint16(body[24:26]))
+			}
+		case "data":
+			samples = body[:size]
+		}
+		// Chunks are padded to an even number of bytes
+		next := 8 + size + size%2
+		if next > len(chunk) {
+			break
+		}
+		chunk = chunk[next:]
+	}
+	if channels == 0 || samples == nil {
+		return nil, 0, fmt.Errorf("%s has no audio", filename)
+	}
+
+	width := bitsPerSample / 8
+	var decode func([]byte) float64
+	switch {
+	case format == 1 && width == 1:
+		decode = func(b []byte) float64 { return (float64(b[0]) - 128) / 128 }
+	case format == 1 && width == 2:
+		decode = func(b []byte) float64 { return float64(int16(binary.LittleEndian.Uint16(b))) / 32768 }
+	case format == 1 && width == 3:
+		decode = func(b []byte) float64 {
+			return float64(int32(uint32(b[0])<<8|uint32(b[1])<<16|uint32(b[2])<<24)>>8) / 8388608
+		}
+	case format == 1 && width == 4:
+		decode = func(b []byte) float64 { return float64(int32(binary.LittleEndian.Uint32(b))) / 2147483648 }
+	case format == 3 && width == 4:
+		decode = func(b []byte) float64 { return float64(math.Float32frombits(binary.LittleEndian.Uint32(b))) }
+	default:
+		return nil, 0, fmt.Errorf("unsupported wav encoding (format %d, %d bits)", format, bitsPerSample)
+	}
+
+	frameSize := width * channels
+	mono := make([]float64, len(samples)/frameSize)
+	for i := range mono {
+		sum := 0.0
+		for c := 0; c < channels; c++ {
+			offset := i*frameSize + c*width
+			sum += decode(samples[offset : offset+width])
+		}
+		mono[i] = sum / float64(channels)
+	}
+	return mono, sampleRate, nil
+}
+
+// BandPass filters samples to keep frequencies between low and high hertz,
+// using a one pole high pass filter followed by a one pole low pass filter
+// A bound of 0 leaves that side of the spectrum unfiltered.
+func BandPass(samples []float64, sampleRate int, low, high float64) []float64 {
+	filtered := make([]float64, len(samples))
+	copy(filtered, samples)
+	dt := 1 / float64(sampleRate)
+	if low > 0 {
+		rc := 1 / (2 * math.Pi * low)
+		alpha := rc / (rc + dt)
+		previousIn, previousOut := 0.0, 0.0
+		for i, v := range filtered {
+			out := alpha * (previousOut + v - previousIn)
+			previousIn, previousOut = v, out
+			filtered[i] = out
+		}
+	}
+	if high > 0 {
+		rc := 1 / (2 * math.Pi * high)
+		alpha := dt / (rc + dt)
+		previous := 0.0
+		for i, v := range filtered {
+			previous += alpha * (v - previous)
+			filtered[i] = previous
+		}
+	}
+	return filtered
+}
+
+// AmplitudeEnvelope returns the loudness (root mean square) of the samples
+// during each frame of an animation, normalized so the loudest frame is 1
+func AmplitudeEnvelope(samples []float64, sampleRate int, frameRate float64, frames int) []float64 {
+	envelope := make([]float64, frames)
+	loudest := 0.0
+	for frame := range envelope {
+		start := int(float64(frame*sampleRate) / frameRate)
+		end := int(float64((frame+1)*sampleRate) / frameRate)
+		if end > len(samples) {
+			end = len(samples)
+		}
+		if start >= end {
+			continue
+		}
+		sum := 0.0
+		for _, v := range samples[start:end] {
+			sum += v * v
+		}
+		envelope[frame] = math.Sqrt(sum / float64(end-start))
+		loudest = math.Max(loudest, envelope[frame])
+	}
+	if loudest > 0 {
+		for frame := range envelope {
+			envelope[frame] /= loudest
+		}
+	}
+	return envelope
+}
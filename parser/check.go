@@ -0,0 +1,273 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+)
+
+// checker finds the problems in a parsed script that would stop it from
+// rendering, without rendering it
+type checker struct {
+	tables   *SymbolTables
+	defined  map[string][]string // names defined by savecs, snapshot, and group
+	problems []string
+	reported map[string]bool
+}
+
+// Check returns every problem in the commands of a parsed script that would
+// stop it from rendering: references to knobs, constants, objects, and names
+// that are never defined, and files that can't be loaded or saved
+// Problems that stop the script from being parsed, such as frame ranges that
+// are out of bounds, are returned by parsing instead.
+func (p *Parser) Check(commands []Command) []string {
+	c := &checker{
+		tables:   p.tables,
+		defined:  make(map[string][]string),
+		reported: make(map[string]bool),
+	}
+	// Objects, lights, and constants are checked in order, so problems are
+	// always listed the same way
+	objects := make([]string, 0, len(p.tables.objects))
+	for name := range p.tables.objects {
+		objects = append(objects, name)
+	}
+	sort.Strings(objects)
+	lights := make([]string, 0, len(p.tables.lightSources))
+	for name := range p.tables.lightSources {
+		lights = append(lights, name)
+	}
+	sort.Strings(lights)
+	constants := make([]string, 0, len(p.tables.constants))
+	for name := range p.tables.constants {
+		constants = append(constants, name)
+	}
+	sort.Strings(constants)
+
+	c.walk(commands, c.define)
+	for _, name := range objects {
+		c.walk(p.tables.objects[name], c.define)
+	}
+	for _, name := range lights {
+		light := p.tables.lightSources[name]
+		c.checkKnob(light.ColorKnob, "light "+name)
+		for _, knob := range light.LocationKnobs {
+			c.checkKnob(knob, "light "+name)
+		}
+	}
+	if filename := p.tables.sky.Filename; filename != "" {
+		if _, err := os.Stat(filename); err != nil {
+			c.report("skybox: %v", err)
+		}
+	}
+	for _, name := range constants {
+		if filename := p.tables.constants[name].NormalMapFile; filename != "" {
+			if _, err := os.Stat(filename); err != nil {
+				c.report("constants %s: %v", name, err)
+			}
+		}
+	}
+	c.walk(commands, c.check)
+	for _, name := range objects {
+		c.walk(p.tables.objects[name], c.check)
+	}
+	return c.problems
+}
+
+// reportProblems prints the problems found by Check, returning an error if there are
+// any
+func (p *Parser) reportProblems(problems []string) error {
+	for _, problem := range problems {
+		fmt.Println(problem)
+	}
+	switch len(problems) {
+	case 0:
+		fmt.Println("No problems found")
+		return nil
+	case 1:
+		return errors.New("1 problem found")
+	default:
+		return fmt.Errorf("%d problems found", len(problems))
+	}
+}
+
+// walk calls visit with each command, including the commands of groups,
+// symmetry blocks, render targets, and scenes
+func (c *checker) walk(commands []Command, visit func(command Command)) {
+	for _, command := range commands {
+		visit(command)
+		switch command := command.(type) {
+		case GroupCommand:
+			c.walk(command.commands, visit)
+		case RenderTargetCommand:
+			c.walk(command.commands, visit)
+		case SymmetryCommand:
+			c.walk(command.commands, visit)
+		case SceneCommand:
+			c.walk(command.commands, visit)
+		}
+	}
+}
+
+// define records the names that a command defines
+func (c *checker) define(command Command) {
+	switch command := command.(type) {
+	case SaveCoordinateSystemCommand:
+		c.defined["coordinate system"] = append(c.defined["coordinate system"], command.name)
+	case SnapshotCommand:
+		c.defined["snapshot"] = append(c.defined["snapshot"], command.name)
+	case GroupCommand:
+		c.defined["group"] = append(c.defined["group"], command.name)
+	case RenderTargetCommand:
+		c.defined["render target"] = append(c.defined["render target"], command.name)
+	}
+}
+
+// check reports the problems with a command
+func (c *checker) check(command Command) {
+	switch command := command.(type) {
+	case MoveCommand:
+		c.checkKnob(command.knob, "move")
+	case ScaleCommand:
+		c.checkKnob(command.knob, "scale")
+	case RotateCommand:
+		c.checkKnob(command.knob, "rotate")
+	case RotateQuaternionCommand:
+		c.checkKnob(command.knob, "rotateq")
+	case ExposureCommand:
+		c.checkKnob(command.knob, "exposure")
+	case CameraCommand:
+		for _, knob := range command.knobs {
+			c.checkKnob(knob, "camera")
+		}
+	case CameraPathCommand:
+		c.checkKnob(command.knob, "camerapath")
+	case GroupCommand:
+		c.checkKnob(command.knob, "group "+command.name)
+	case AxesCommand:
+		c.checkShape(command.ShapeCommand, "axes")
+	case GridCommand:
+		c.checkShape(command.ShapeCommand, "grid")
+	case LineCommand:
+		c.checkShape(command.ShapeCommand, "line")
+		c.checkDefined("coordinate system", command.cs2, "line")
+	case CircleCommand:
+		c.checkShape(command.ShapeCommand, "circle")
+	case EllipseCommand:
+		c.checkShape(command.ShapeCommand, "ellipse")
+	case ArcCommand:
+		c.checkShape(command.ShapeCommand, "arc")
+	case HermiteCommand:
+		c.checkShape(command.ShapeCommand, "hermite")
+	case BezierCommand:
+		c.checkShape(command.ShapeCommand, "bezier")
+	case SphereCommand:
+		c.checkShape(command.ShapeCommand, "sphere")
+	case TorusCommand:
+		c.checkShape(command.ShapeCommand, "torus")
+	case BoxCommand:
+		c.checkShape(command.ShapeCommand, "box")
+	case SweepCommand:
+		c.checkShape(command.ShapeCommand, "sweep")
+	case TerrainCommand:
+		c.checkShape(command.ShapeCommand, "terrain")
+	case MetaballsCommand:
+		c.checkShape(command.ShapeCommand, "metaballs")
+	case LSystemCommand:
+		c.checkShape(command.ShapeCommand, "lsystem")
+	case ParticlesCommand:
+		c.checkShape(command.ShapeCommand, "particles")
+		c.checkKnob(command.emitter.knob, "particles")
+	case MeshCommand:
+		c.checkShape(command.ShapeCommand, "mesh")
+		if _, err := os.Stat(command.filename); err != nil {
+			c.report("mesh: %v", err)
+		}
+	case EnvironmentMapCommand:
+		if command.filename != "" {
+			if _, err := os.Stat(command.filename); err != nil {
+				c.report("envmap: %v", err)
+			}
+		}
+	case UseTextureCommand:
+		c.checkDefined("render target", command.name, "usetexture")
+	case InstanceCommand:
+		if _, found := c.tables.objects[command.name]; !found {
+			c.report("instance: undefined object '%s'", command.name)
+		}
+	case HideCommand:
+		c.checkDefined("group", command.group, "hide")
+	case ShowCommand:
+		c.checkDefined("group", command.group, "show")
+	case RestoreCommand:
+		c.checkDefined("snapshot", command.name, "restore")
+	case SaveCommand:
+		c.checkSave(command.filename, "save")
+	case SaveFrameCommand:
+		c.checkSave(command.filename, "saveframe")
+	case SaveDepthCommand:
+		c.checkSave(command.filename, "savedepth")
+	}
+}
+
+// checkKnob reports a knob that is undefined, where "" is no knob
+func (c *checker) checkKnob(name, where string) {
+	if name == "" {
+		return
+	}
+	if _, found := c.tables.knobs[name]; !found {
+		c.report("%s: undefined knob '%s'", where, name)
+	}
+}
+
+// checkShape reports the problems with the options shared by shapes
+func (c *checker) checkShape(shape ShapeCommand, where string) {
+	if shape.constants != "" {
+		if _, err := c.tables.Constants(shape.constants); err != nil {
+			c.report("%s: %v", where, err)
+		}
+	}
+	c.checkDefined("coordinate system", shape.cs, where)
+}
+
+// checkDefined reports a name that is never defined as the kind of thing it is
+// used as, where "" needs no definition
+func (c *checker) checkDefined(kind, name, where string) {
+	if name == "" {
+		return
+	}
+	for _, defined := range c.defined[kind] {
+		if defined == name {
+			return
+		}
+	}
+	c.report("%s: %s '%s' is never defined", where, kind, name)
+}
+
+// checkSave reports an image that can't be saved, because its directory
+// doesn't exist or converting it needs ImageMagick
+func (c *checker) checkSave(filename, where string) {
+	dir := filepath.Dir(filename)
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		c.report("%s: directory %s does not exist", where, dir)
+	}
+	switch filepath.Ext(filename) {
+	case ".ppm", ".gif", ".svg":
+	default:
+		if _, err := exec.LookPath("convert"); err != nil {
+			c.report("%s: saving %s needs ImageMagick's convert, which is not installed", where, filename)
+		}
+	}
+}
+
+// report records a problem, ignoring ones that were already reported
+func (c *checker) report(format string, args ...interface{}) {
+	problem := fmt.Sprintf(format, args...)
+	if !c.reported[problem] {
+		c.reported[problem] = true
+		c.problems = append(c.problems, problem)
+	}
+}
@@ -0,0 +1,175 @@
+package parser
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/james9909/graphics-engine/render"
+)
+
+const (
+	// CheckpointFile is the file in FramesDirectory recording which frames
+	// are done
+	CheckpointFile = ".checkpoint"
+	// StillCheckpointFile is the file in FramesDirectory that what has been
+	// drawn of a still image is saved to while it renders
+	StillCheckpointFile = ".still"
+	// StillCheckpointInterval is how often what has been drawn of a still
+	// image is saved, which is the most work that resuming it loses
+	StillCheckpointInterval = time.Minute
+)
+
+// Checkpoint records the progress of an animation render so that an
+// interrupted render can be resumed
+// The file starts with a header identifying the script and how it is
+// rendered. The number of each completed frame follows on its own line, so
+// recording a frame only requires appending to it.
+type Checkpoint struct {
+	path     string
+	hash     string       // hash of the script being rendered and the files it loads
+	settings string       // output size and render flags that change how frames come out
+	frames   int          // number of frames in the animation
+	done     map[int]bool // frames that have been rendered
+	mu       sync.Mutex
+}
+
+// NewCheckpoint starts a new checkpoint at path, discarding any previous one
+func NewCheckpoint(path, hash, settings string, frames int) (*Checkpoint, error) {
+	c := &Checkpoint{
+		path:     path,
+		hash:     hash,
+		settings: settings,
+		frames:   frames,
+		done:     make(map[int]bool),
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	_, err = fmt.Fprintln(f, c.header())
+	return c, err
+}
+
+// LoadCheckpoint loads the checkpoint at path if it was written for the same
+// script rendered with the same settings, and starts a new one otherwise
+func LoadCheckpoint(path, hash, settings string, frames int) (*Checkpoint, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return NewCheckpoint(path, hash, settings, frames)
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	c := &Checkpoint{
+		path:     path,
+		hash:     hash,
+		settings: settings,
+		frames:   frames,
+		done:     make(map[int]bool),
+	}
+	scanner := bufio.NewScanner(f)
+	if !scanner.Scan() || scanner.Text() != c.header() {
+		fmt.Fprintln(os.Stderr, "Checkpoint is for a different script, input files, or settings: starting over")
+		return NewCheckpoint(path, hash, settings, frames)
+	}
+	for scanner.Scan() {
+		// A partially written last line is ignored
+		frame, err := strconv.Atoi(scanner.Text())
+		if err == nil && frame >= 0 && frame < frames {
+			c.done[frame] = true
+		}
+	}
+	return c, scanner.Err()
+}
+
+func (c *Checkpoint) header() string {
+	return fmt.Sprintf("script %s frames %d %s", c.hash, c.frames, c.settings)
+}
+
+// Done returns whether a frame has already been rendered
+func (c *Checkpoint) Done(frame int) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.done[frame]
+}
+
+// Verify forgets completed frames whose files, named by path, no longer
+// exist, so that they are rendered again
+func (c *Checkpoint) Verify(path func(frame int) string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for frame := range c.done {
+		if _, err := os.Stat(path(frame)); err != nil {
+			delete(c.done, frame)
+		}
+	}
+}
+
+// Remaining returns the number of frames that still need to be rendered
+func (c *Checkpoint) Remaining() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.frames - len(c.done)
+}
+
+// Complete records that a frame has been rendered
+func (c *Checkpoint) Complete(frame int) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	f, err := os.OpenFile(c.path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if _, err = fmt.Fprintln(f, frame); err != nil {
+		return err
+	}
+	c.done[frame] = true
+	return nil
+}
+
+// checkpointHash returns a hash of the script and of every file it includes or
+// loads, so that frames rendered before any of them changed are stale
+// Files that can't be read are hashed by their name alone.
+func (p *Parser) checkpointHash() string {
+	h := sha256.New()
+	fmt.Fprintln(h, p.hash)
+	for _, path := range p.dependencies {
+		fmt.Fprintln(h, path)
+		if input, err := ioutil.ReadFile(path); err == nil {
+			h.Write(input)
+		}
+	}
+	return fmt.Sprintf("%x", h.Sum(nil))
+}
+
+// checkpointSettings returns the output size and the render flags that change
+// how frames come out, so that frames rendered with other flags are stale
+func (p *Parser) checkpointSettings() string {
+	return fmt.Sprintf("size %dx%d bits %d dither %d linewidth %g snaplines %t stats %t layout %d passes %t deterministic %t",
+		p.width, p.height, p.bits, p.dither, p.lineWidth, p.snapLines, p.stats, p.layout, p.passes, p.deterministic)
+}
+
+// checkpointPath returns the path of the checkpoint for an animation
+func checkpointPath() string {
+	return filepath.Join(FramesDirectory, CheckpointFile)
+}
+
+// stillCheckpoint returns the checkpoint that what has been drawn of a still
+// image is saved to, keyed like the checkpoint of an animation
+func (p *Parser) stillCheckpoint() *render.FrameCheckpoint {
+	return &render.FrameCheckpoint{
+		Path:     filepath.Join(FramesDirectory, StillCheckpointFile),
+		Key:      fmt.Sprintf("script %s %s", p.checkpointHash(), p.checkpointSettings()),
+		Interval: StillCheckpointInterval,
+	}
+}
@@ -0,0 +1,66 @@
+package parser
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/james9909/graphics-engine/image"
+	"github.com/james9909/graphics-engine/render"
+)
+
+const checkpointScene = `light key 255 255 255 1 1 1
+constants shiny 0.2 0.5 0.5 0.2 0.5 0.5 0.2 0.5 0.5
+shading phong
+box shiny 100 400 0 150 150 150
+clear
+sphere shiny 250 250 0 100
+torus 250 250 0 120 20
+line 0 0 0 500 500 0
+`
+
+// renderStill renders a still script with a drawer that saves what has been
+// drawn to checkpoint after every shape, resuming from it first if resume is
+// set, and returns the image along with the number of shapes resumed
+func renderStill(t *testing.T, script string, checkpoint *render.FrameCheckpoint, resume bool) (*image.Image, int) {
+	t.Helper()
+	p := NewParser()
+	scene := parse(t, p, script)
+	drawer, err := p.newDrawer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	drawer.SetCheckpoint(checkpoint)
+	var shapes int
+	if resume {
+		if shapes, err = drawer.Resume(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	drawer.BeginFrame(0)
+	if err := drawFrame(context.Background(), drawer, scene.tables, scene.commands, 0); err != nil {
+		t.Fatal(err)
+	}
+	return drawer.Output(false), shapes
+}
+
+func TestResumedStillMatchesOneRenderedAtOnce(t *testing.T) {
+	checkpoint := &render.FrameCheckpoint{
+		Path: filepath.Join(t.TempDir(), StillCheckpointFile),
+		Key:  "script",
+	}
+	want, _ := renderStill(t, checkpointScene, checkpoint, false)
+	// The last checkpoint is saved just before the last shape is drawn
+	got, shapes := renderStill(t, checkpointScene, checkpoint, true)
+	if shapes != 3 {
+		t.Errorf("resumed with %d shapes drawn, want 3", shapes)
+	}
+	if !sameImages(got, want) {
+		t.Error("resumed image differs from the image rendered without stopping")
+	}
+
+	checkpoint.Key = "another script"
+	if _, shapes := renderStill(t, checkpointScene, checkpoint, true); shapes != 0 {
+		t.Errorf("resumed %d shapes of a checkpoint saved for another script", shapes)
+	}
+}
@@ -0,0 +1,692 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/james9909/graphics-engine/geometry"
+	"github.com/james9909/graphics-engine/image"
+	"github.com/james9909/graphics-engine/render"
+)
+
+type Command interface {
+	Name() string
+}
+
+type SaveCommand struct {
+	filename string
+}
+
+func (c SaveCommand) Name() string {
+	return "SAVE"
+}
+
+type SaveCoordinateSystemCommand struct {
+	name string
+}
+
+func (c SaveCoordinateSystemCommand) Name() string {
+	return "SAVECS"
+}
+
+type SaveDepthCommand struct {
+	filename string
+}
+
+func (c SaveDepthCommand) Name() string {
+	return "SAVEDEPTH"
+}
+
+// SaveFrameCommand saves a single frame of an animation as a still image
+type SaveFrameCommand struct {
+	frame    int
+	filename string
+}
+
+func (c SaveFrameCommand) Name() string {
+	return "SAVEFRAME"
+}
+
+type DisplayCommand struct {
+	frame int // frame of an animation to display
+}
+
+func (c DisplayCommand) Name() string {
+	return "DISPLAY"
+}
+
+type ColorCommand struct {
+	color image.Color
+}
+
+func (c ColorCommand) Name() string {
+	return "COLOR"
+}
+
+type ClearCommand struct{}
+
+func (c ClearCommand) Name() string {
+	return "CLEAR"
+}
+
+type PushCommand struct{}
+
+func (c PushCommand) Name() string {
+	return "PUSH"
+}
+
+type PopCommand struct{}
+
+func (c PopCommand) Name() string {
+	return "POP"
+}
+
+type TransformCommand struct {
+	knob string
+}
+
+type MoveCommand struct {
+	TransformCommand
+	args []float64
+}
+
+func (c MoveCommand) Name() string {
+	return "MOVE"
+}
+
+type ScaleCommand struct {
+	TransformCommand
+	args  []float64
+	pivot []float64 // point that stays in place, if not the origin
+}
+
+func (c ScaleCommand) Name() string {
+	return "SCALE"
+}
+
+type RotateCommand struct {
+	TransformCommand
+	axis    string
+	vector  []float64 // axis to rotate about when axis is "axis"
+	degrees float64
+	pivot   []float64 // point that stays in place, if not the origin
+}
+
+func (c RotateCommand) Name() string {
+	return "ROTATE"
+}
+
+// RotateQuaternionCommand rotates to a place along keyframes picked by its
+// knob, interpolating between them along great circles
+type RotateQuaternionCommand struct {
+	TransformCommand
+	keys  []geometry.Quaternion
+	pivot []float64 // point that stays in place, if not the origin
+}
+
+func (c RotateQuaternionCommand) Name() string {
+	return "ROTATEQ"
+}
+
+// MatrixCommand applies a transformation given as a matrix
+type MatrixCommand struct {
+	matrix geometry.Mat4
+}
+
+func (c MatrixCommand) Name() string {
+	return "MATRIX"
+}
+
+// MirrorCommand reflects across the plane where a coordinate is offset
+type MirrorCommand struct {
+	axis   string
+	offset float64
+}
+
+func (c MirrorCommand) Name() string {
+	return "MIRROR"
+}
+
+type ShapeCommand struct {
+	constants string
+	cs        string
+	color     *image.Color // color used when drawing without constants
+	where     string       // where the shape is in the script, for picking
+}
+
+// source returns the statement that draws a shape, for picking
+func (c ShapeCommand) source(name string) string {
+	if c.where == "" {
+		return strings.ToLower(name)
+	}
+	return fmt.Sprintf("%s at %s", strings.ToLower(name), c.where)
+}
+
+// drawColor returns the color to draw the shape with when it is not shaded,
+// which is the current color unless the shape has its own
+func (c ShapeCommand) drawColor(current image.Color) image.Color {
+	if c.color != nil {
+		return *c.color
+	}
+	return current
+}
+
+type LineCommand struct {
+	ShapeCommand
+	p1  []float64
+	p2  []float64
+	cs2 string
+}
+
+func (c LineCommand) Name() string {
+	return "LINE"
+}
+
+type CircleCommand struct {
+	ShapeCommand
+	center []float64
+	radius float64
+}
+
+func (c CircleCommand) Name() string {
+	return "CIRCLE"
+}
+
+type EllipseCommand struct {
+	ShapeCommand
+	center []float64
+	rx, ry float64
+	normal geometry.Vec3 // direction the ellipse faces
+}
+
+func (c EllipseCommand) Name() string {
+	return "ELLIPSE"
+}
+
+type ArcCommand struct {
+	ShapeCommand
+	center     []float64
+	radius     float64
+	start, end float64       // angles in degrees
+	normal     geometry.Vec3 // direction the arc faces
+}
+
+func (c ArcCommand) Name() string {
+	return "ARC"
+}
+
+type HermiteCommand struct {
+	ShapeCommand
+	p0 geometry.Vec3
+	p1 geometry.Vec3
+	r0 geometry.Vec3 // rate of change at p0
+	r1 geometry.Vec3 // rate of change at p1
+}
+
+func (c HermiteCommand) Name() string {
+	return "HERMITE"
+}
+
+type BezierCommand struct {
+	ShapeCommand
+	points []geometry.Vec3 // endpoints and control points, in order along the curve
+}
+
+func (c BezierCommand) Name() string {
+	return "BEZIER"
+}
+
+// SweepCommand is a profile swept along a path
+type SweepCommand struct {
+	ShapeCommand
+	profile geometry.Profile
+	path    geometry.Cubic
+}
+
+func (c SweepCommand) Name() string {
+	return "SWEEP"
+}
+
+// TerrainCommand is a heightmap stretched into a landscape
+type TerrainCommand struct {
+	ShapeCommand
+	corner               []float64 // front left corner of the base of the terrain
+	width, depth, height float64
+	heights              geometry.Heightmap
+}
+
+func (c TerrainCommand) Name() string {
+	return "TERRAIN"
+}
+
+// MetaballsCommand is the surface of metaballs blended together
+type MetaballsCommand struct {
+	ShapeCommand
+	surface *geometry.Matrix // triangles of the surface, polygonized when parsed
+}
+
+func (c MetaballsCommand) Name() string {
+	return "METABALLS"
+}
+
+// LSystemCommand is the lines or branches drawn by a turtle following an
+// l-system
+type LSystemCommand struct {
+	ShapeCommand
+	segments [][2]geometry.Vec3 // lines drawn by the turtle, expanded when parsed
+	radius   float64            // radius of the branches, or 0 to draw lines
+}
+
+func (c LSystemCommand) Name() string {
+	return "LSYSTEM"
+}
+
+// ParticlesCommand is the particles of an emitter as they are in each frame
+type ParticlesCommand struct {
+	ShapeCommand
+	emitter *Emitter
+	size    float64 // radius of the particles, or 0 to draw them as points
+}
+
+func (c ParticlesCommand) Name() string {
+	return "PARTICLES"
+}
+
+// AxesCommand draws the x, y, and z axes from the origin
+type AxesCommand struct {
+	ShapeCommand
+	length float64
+}
+
+func (c AxesCommand) Name() string {
+	return "AXES"
+}
+
+// GridCommand draws a grid on the ground
+type GridCommand struct {
+	ShapeCommand
+	size    float64 // distance across the grid
+	spacing float64 // distance between its lines
+}
+
+func (c GridCommand) Name() string {
+	return "GRID"
+}
+
+type SphereCommand struct {
+	ShapeCommand
+	center   []float64
+	radius   float64
+	segments int // 0 picks the number of segments automatically
+}
+
+func (c SphereCommand) Name() string {
+	return "SPHERE"
+}
+
+type TorusCommand struct {
+	ShapeCommand
+	center   []float64
+	r1       float64
+	r2       float64
+	segments int // 0 picks the number of segments automatically
+}
+
+func (c TorusCommand) Name() string {
+	return "TORUS"
+}
+
+type BoxCommand struct {
+	ShapeCommand
+	p1     []float64
+	width  float64
+	height float64
+	depth  float64
+}
+
+func (c BoxCommand) Name() string {
+	return "BOX"
+}
+
+type MeshCommand struct {
+	ShapeCommand
+	filename string
+}
+
+func (c MeshCommand) Name() string {
+	return "MESH"
+}
+
+type RenderModeCommand struct {
+	mode render.RenderMode
+}
+
+func (c RenderModeCommand) Name() string {
+	return "RENDERMODE"
+}
+
+type CullingCommand struct {
+	mode geometry.CullMode
+}
+
+func (c CullingCommand) Name() string {
+	return "CULLING"
+}
+
+type WindingCommand struct {
+	winding geometry.Winding
+}
+
+func (c WindingCommand) Name() string {
+	return "WINDING"
+}
+
+type ShadingCommand struct {
+	mode image.ShadingMode
+}
+
+func (c ShadingCommand) Name() string {
+	return "SHADING"
+}
+
+type ExposureCommand struct {
+	exposure float64
+	knob     string
+}
+
+func (c ExposureCommand) Name() string {
+	return "EXPOSURE"
+}
+
+type ToneMapCommand struct {
+	toneMap image.ToneMap
+}
+
+func (c ToneMapCommand) Name() string {
+	return "TONEMAP"
+}
+
+type GammaCommand struct {
+	linear bool
+}
+
+func (c GammaCommand) Name() string {
+	return "GAMMA"
+}
+
+type ViewportCommand struct {
+	viewport geometry.Viewport
+}
+
+func (c ViewportCommand) Name() string {
+	return "VIEWPORT"
+}
+
+type ViewportRectCommand struct {
+	rect geometry.Rect
+}
+
+func (c ViewportRectCommand) Name() string {
+	return "VIEWPORT"
+}
+
+type ProjectionCommand struct {
+	projection geometry.Projection
+}
+
+func (c ProjectionCommand) Name() string {
+	return "PROJECTION"
+}
+
+// CameraCommand sets where everything drawn afterwards is seen from
+type CameraCommand struct {
+	eye, aim geometry.Vec3
+	knobs    [6]string // knobs scaling each coordinate of the eye and then the aim, where "" is none
+}
+
+func (c CameraCommand) Name() string {
+	return "CAMERA"
+}
+
+// CameraPathCommand moves the eye of the camera along a path
+type CameraPathCommand struct {
+	path geometry.Cubic
+	knob string // how far along the path the eye is, or "" for the part of the animation that has passed
+}
+
+func (c CameraPathCommand) Name() string {
+	return "CAMERAPATH"
+}
+
+type DepthEpsilonCommand struct {
+	epsilon float64
+}
+
+func (c DepthEpsilonCommand) Name() string {
+	return "ZEPSILON"
+}
+
+// DepthClipCommand clips everything drawn afterwards between a near and a far
+// depth
+type DepthClipCommand struct {
+	near, far float64
+}
+
+func (c DepthClipCommand) Name() string {
+	return "ZCLIP"
+}
+
+type DepthOffsetCommand struct {
+	offset float64
+}
+
+func (c DepthOffsetCommand) Name() string {
+	return "ZOFFSET"
+}
+
+// GroupCommand is a named group of commands drawn in their own coordinate
+// system, which compose with the transformations of their parent
+type GroupCommand struct {
+	name     string
+	knob     string // the group is hidden in frames where this knob is 0
+	commands []Command
+}
+
+func (c GroupCommand) Name() string {
+	return "GROUP"
+}
+
+// SymmetryCommand is a block of commands drawn twice, as they are and
+// reflected across the plane where a coordinate is offset
+type SymmetryCommand struct {
+	axis     string
+	offset   float64
+	commands []Command
+}
+
+func (c SymmetryCommand) Name() string {
+	return "SYMMETRY"
+}
+
+// ObjectCommand defines an object, whose commands are stored in the symbol
+// tables when it is parsed instead of being run
+type ObjectCommand struct {
+	name string
+}
+
+func (c ObjectCommand) Name() string {
+	return "OBJECT"
+}
+
+type InstanceCommand struct {
+	name string
+}
+
+func (c InstanceCommand) Name() string {
+	return "INSTANCE"
+}
+
+type HideCommand struct {
+	group string
+}
+
+func (c HideCommand) Name() string {
+	return "HIDE"
+}
+
+type ShowCommand struct {
+	group string
+}
+
+func (c ShowCommand) Name() string {
+	return "SHOW"
+}
+
+type SnapshotCommand struct {
+	name string
+}
+
+func (c SnapshotCommand) Name() string {
+	return "SNAPSHOT"
+}
+
+type RestoreCommand struct {
+	name string
+}
+
+func (c RestoreCommand) Name() string {
+	return "RESTORE"
+}
+
+type LayerCommand struct {
+	name    string
+	mode    image.BlendMode
+	opacity float64
+}
+
+func (c LayerCommand) Name() string {
+	return "LAYER"
+}
+
+// ClipCommand clips subsequent geometry against a plane, or removes all
+// clipping planes of the coordinate system if plane is nil
+type ClipCommand struct {
+	plane []float64
+}
+
+func (c ClipCommand) Name() string {
+	return "CLIP"
+}
+
+type LevelOfDetailCommand struct {
+	pixels float64
+}
+
+func (c LevelOfDetailCommand) Name() string {
+	return "LOD"
+}
+
+type QualityCommand struct {
+	segments int
+}
+
+func (c QualityCommand) Name() string {
+	return "QUALITY"
+}
+
+// StampCommand stamps the number and time of the frame onto saved images
+type StampCommand struct {
+	corner image.Corner
+	scale  int
+}
+
+func (c StampCommand) Name() string {
+	return "STAMP"
+}
+
+// DisplaceCommand sets how far surfaces drawn after it are displaced by noise
+type DisplaceCommand struct {
+	size   float64 // distance between the features of the noise
+	amount float64 // how far surfaces are displaced, or 0 for not at all
+}
+
+func (c DisplaceCommand) Name() string {
+	return "DISPLACE"
+}
+
+type EnvironmentMapCommand struct {
+	filename string // image of the environment, or "" for none
+}
+
+func (c EnvironmentMapCommand) Name() string {
+	return "ENVMAP"
+}
+
+// RenderTargetCommand draws its commands into an offscreen image, which
+// UseTextureCommand covers shapes with, instead of the image
+type RenderTargetCommand struct {
+	name          string
+	width, height int
+	commands      []Command
+}
+
+func (c RenderTargetCommand) Name() string {
+	return "RENDERTARGET"
+}
+
+type UseTextureCommand struct {
+	name string // render target covering shapes, or "" for none
+}
+
+func (c UseTextureCommand) Name() string {
+	return "USETEXTURE"
+}
+
+// PickCommand reports what covers a pixel of the image
+type PickCommand struct {
+	x, y int
+}
+
+func (c PickCommand) Name() string {
+	return "PICK"
+}
+
+// DebugCommand turns an overlay over the shapes drawn afterwards on or off
+type DebugCommand struct {
+	overlay render.DebugOverlay
+	on      bool
+}
+
+func (c DebugCommand) Name() string {
+	return "DEBUG"
+}
+
+type AmbientOcclusionCommand struct {
+	radius   float64 // distance in pixels that surfaces hide each other within
+	strength float64 // how much hidden surfaces are darkened, or 0 for not at all
+}
+
+func (c AmbientOcclusionCommand) Name() string {
+	return "SSAO"
+}
+
+type LineWidthCommand struct {
+	width float64
+}
+
+func (c LineWidthCommand) Name() string {
+	return "LINEWIDTH"
+}
+
+// SceneCommand is a named shot that is only drawn during its own range of
+// frames, which follows the scenes before it on the timeline
+type SceneCommand struct {
+	name     string
+	start    int // first frame of the scene
+	frames   int // number of frames in the scene
+	commands []Command
+}
+
+func (c SceneCommand) Name() string {
+	return "SCENE"
+}
@@ -0,0 +1,182 @@
+package parser
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/james9909/graphics-engine/render"
+)
+
+// DefaultPreview is the file that live previews are rendered to
+const DefaultPreview = "preview.png"
+
+// ControlServer re-renders a script whenever a controller asks it to
+type ControlServer struct {
+	drawer   *render.Drawer
+	tables   *SymbolTables
+	commands []Command
+	frames   int    // number of frames in the script
+	frame    int    // frame being previewed
+	output   string // file the preview is saved to
+	dirty    bool   // whether a knob changed since the last render
+	mu       sync.Mutex
+}
+
+// NewControlServer returns a server that renders commands with drawer into output
+func NewControlServer(drawer *render.Drawer, tables *SymbolTables, commands []Command, frames int, output string) *ControlServer {
+	return &ControlServer{
+		drawer:   drawer,
+		tables:   tables,
+		commands: commands,
+		frames:   frames,
+		output:   output,
+	}
+}
+
+// Start renders the initial preview
+func (s *ControlServer) Start() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.render()
+}
+
+// ListenUnix accepts controllers on a unix socket until the listener fails
+func (s *ControlServer) ListenUnix(socket string) error {
+	// Remove a stale socket left behind by a previous run
+	os.Remove(socket)
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		return err
+	}
+	defer listener.Close()
+	fmt.Printf("Listening for controllers on %s\n", socket)
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return err
+		}
+		go s.handle(conn)
+	}
+}
+
+// handle executes each line sent by a controller and replies with its result
+func (s *ControlServer) handle(conn net.Conn) {
+	defer conn.Close()
+	scanner := bufio.NewScanner(conn)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if err := s.execute(strings.Fields(line)); err != nil {
+			fmt.Fprintln(conn, "error:", err)
+		} else {
+			fmt.Fprintln(conn, "ok")
+		}
+	}
+}
+
+// execute runs a single control message
+//
+//	set knob value - override the value of a knob
+//	frame n        - choose which frame of the animation to preview
+//	rerender       - render and save the preview again
+func (s *ControlServer) execute(args []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch args[0] {
+	case "set":
+		if len(args) != 3 {
+			return fmt.Errorf("usage: set knob value")
+		}
+		value, err := strconv.ParseFloat(args[2], 64)
+		if err != nil {
+			return err
+		}
+		s.tables.overrides[args[1]] = value
+		s.dirty = true
+	case "frame":
+		if len(args) != 2 {
+			return fmt.Errorf("usage: frame n")
+		}
+		frame, err := strconv.Atoi(args[1])
+		if err != nil {
+			return err
+		}
+		if frame < 0 || frame >= s.frames {
+			return fmt.Errorf("frame %d is out of range", frame)
+		}
+		s.frame = frame
+	case "rerender":
+		return s.render()
+	default:
+		return fmt.Errorf("unknown message %q", args[0])
+	}
+	return nil
+}
+
+// SetKnob overrides the value of a knob, to be picked up by RenderContinuously
+func (s *ControlServer) SetKnob(name string, value float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tables.overrides[name] = value
+	s.dirty = true
+}
+
+// ResetKnob forgets the override of a knob, returning it to the values given by
+// the script
+func (s *ControlServer) ResetKnob(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.tables.overrides, name)
+	s.dirty = true
+}
+
+// SetFrame chooses which frame of the animation to preview, to be picked up by
+// RenderContinuously
+func (s *ControlServer) SetFrame(frame int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if frame < 0 || frame >= s.frames {
+		return fmt.Errorf("frame %d is out of range", frame)
+	}
+	s.frame = frame
+	s.dirty = true
+	return nil
+}
+
+// RenderContinuously re-renders the preview whenever a knob changes
+func (s *ControlServer) RenderContinuously() {
+	ticker := time.NewTicker(time.Second / render.DefaultFrameRate)
+	defer ticker.Stop()
+	for range ticker.C {
+		s.mu.Lock()
+		if s.dirty {
+			if err := s.render(); err != nil {
+				fmt.Fprintln(os.Stderr, "Render error:", err)
+			}
+		}
+		s.mu.Unlock()
+	}
+}
+
+func (s *ControlServer) render() error {
+	s.dirty = false
+	s.drawer.Reset()
+	s.drawer.BeginFrame(s.frame)
+	if err := drawFrame(context.Background(), s.drawer, s.tables, s.commands, s.frame); err != nil {
+		return err
+	}
+	if err := s.drawer.Save(s.output); err != nil {
+		return err
+	}
+	return s.drawer.ShowPreview()
+}
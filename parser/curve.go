@@ -0,0 +1,249 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/james9909/graphics-engine/geometry"
+)
+
+// Curve maps the progress of an animation, from 0 to 1, to the fraction of the
+// way a value has changed
+type Curve interface {
+	At(t float64) float64
+}
+
+// LinearCurve changes at a constant rate
+type LinearCurve struct{}
+
+// At returns t unchanged
+func (c LinearCurve) At(t float64) float64 {
+	return t
+}
+
+// CubicBezier is a timing curve from (0, 0) to (1, 1) with the control points
+// (x1, y1) and (x2, y2), like the CSS cubic-bezier function
+type CubicBezier struct {
+	x1, y1, x2, y2 float64
+}
+
+// NewCubicBezier returns a CubicBezier, whose x coordinates must be between 0
+// and 1 so that the curve is a function of time
+func NewCubicBezier(x1, y1, x2, y2 float64) (CubicBezier, error) {
+	if x1 < 0 || x1 > 1 || x2 < 0 || x2 > 1 {
+		return CubicBezier{}, errors.New("bezier x values must be between 0 and 1")
+	}
+	return CubicBezier{x1, y1, x2, y2}, nil
+}
+
+// bezier evaluates one coordinate of a cubic bezier from 0 to 1 at s
+func bezier(p1, p2, s float64) float64 {
+	return 3*(1-s)*(1-s)*s*p1 + 3*(1-s)*s*s*p2 + s*s*s
+}
+
+// At returns the y coordinate of the curve where its x coordinate is t
+func (c CubicBezier) At(t float64) float64 {
+	t = geometry.Clamp(t, 0, 1)
+	// x increases with s, so bisect for the s where x is t
+	lo, hi := 0.0, 1.0
+	s := t
+	for i := 0; i < 50; i++ {
+		x := bezier(c.x1, c.x2, s)
+		if math.Abs(x-t) < 1e-7 {
+			break
+		}
+		if x < t {
+			lo = s
+		} else {
+			hi = s
+		}
+		s = (lo + hi) / 2
+	}
+	return bezier(c.y1, c.y2, s)
+}
+
+// HermiteCurve passes smoothly through a series of points, using cubic
+// Hermite interpolation with tangents estimated from the neighboring points
+type HermiteCurve struct {
+	times    []float64
+	values   []float64
+	tangents []float64
+}
+
+// NewHermiteCurve returns a HermiteCurve through the points, given as pairs of
+// time and value with increasing times
+func NewHermiteCurve(points []float64) (*HermiteCurve, error) {
+	if len(points) < 4 || len(points)%2 != 0 {
+		return nil, errors.New("a curve needs at least two points")
+	}
+	c := &HermiteCurve{}
+	for i := 0; i < len(points); i += 2 {
+		if i > 0 && points[i] <= points[i-2] {
+			return nil, fmt.Errorf("curve times must increase, but %g follows %g", points[i], points[i-2])
+		}
+		c.times = append(c.times, points[i])
+		c.values = append(c.values, points[i+1])
+	}
+	last := len(c.times) - 1
+	c.tangents = make([]float64, len(c.times))
+	for i := range c.tangents {
+		// One sided differences at the ends, central differences elsewhere
+		before, after := i-1, i+1
+		if before < 0 {
+			before = 0
+		}
+		if after > last {
+			after = last
+		}
+		c.tangents[i] = (c.values[after] - c.values[before]) / (c.times[after] - c.times[before])
+	}
+	return c, nil
+}
+
+// At returns the value of the curve at t, which is held at the first and last
+// points outside of them
+func (c *HermiteCurve) At(t float64) float64 {
+	last := len(c.times) - 1
+	if t <= c.times[0] {
+		return c.values[0]
+	}
+	if t >= c.times[last] {
+		return c.values[last]
+	}
+	i := 0
+	for t > c.times[i+1] {
+		i++
+	}
+	h := c.times[i+1] - c.times[i]
+	s := (t - c.times[i]) / h
+	h00 := 2*s*s*s - 3*s*s + 1
+	h10 := s*s*s - 2*s*s + s
+	h01 := -2*s*s*s + 3*s*s
+	h11 := s*s*s - s*s
+	return h00*c.values[i] + h10*h*c.tangents[i] + h01*c.values[i+1] + h11*h*c.tangents[i+1]
+}
+
+// DefaultSpringStiffness and DefaultSpringDamping are the spring of
+// vary_spring, unless it gives its own
+const (
+	DefaultSpringStiffness = 3.0
+	DefaultSpringDamping   = 0.3
+)
+
+// DefaultBounceElasticity is the elasticity of vary_bounce, unless it gives
+// its own
+const DefaultBounceElasticity = 0.5
+
+// DefaultDamping is the damping of vary_damp, unless it gives its own
+const DefaultDamping = 5.0
+
+// SpringCurve is a value pulled to its end by a spring, overshooting and
+// swinging back and forth around it before it settles
+type SpringCurve struct {
+	stiffness float64 // times the spring would swing back and forth without damping
+	damping   float64 // fraction of critical damping, where 1 and more don't overshoot
+}
+
+// NewSpringCurve returns a SpringCurve, whose stiffness must be positive and
+// damping must not be negative
+func NewSpringCurve(stiffness, damping float64) (SpringCurve, error) {
+	if stiffness <= 0 {
+		return SpringCurve{}, errors.New("spring stiffness must be greater than zero")
+	}
+	if damping < 0 {
+		return SpringCurve{}, errors.New("spring damping must not be negative")
+	}
+	return SpringCurve{stiffness, damping}, nil
+}
+
+// At returns the position of a damped spring released from rest at 0, which
+// is pulled towards 1
+func (c SpringCurve) At(t float64) float64 {
+	t = geometry.Clamp(t, 0, 1)
+	omega := 2 * math.Pi * c.stiffness
+	zeta := c.damping
+	switch {
+	case zeta < 1:
+		omegaD := omega * math.Sqrt(1-zeta*zeta)
+		return 1 - math.Exp(-zeta*omega*t)*(math.Cos(omegaD*t)+zeta/math.Sqrt(1-zeta*zeta)*math.Sin(omegaD*t))
+	case zeta == 1:
+		return 1 - math.Exp(-omega*t)*(1+omega*t)
+	default:
+		root := math.Sqrt(zeta*zeta - 1)
+		r1, r2 := -omega*(zeta-root), -omega*(zeta+root)
+		return 1 + (r2*math.Exp(r1*t)-r1*math.Exp(r2*t))/(r1-r2)
+	}
+}
+
+// BounceCurve is a value dropped onto its end, like a ball falling under
+// gravity that bounces lower each time until it comes to rest at the end
+type BounceCurve struct {
+	elasticity float64 // fraction of its speed the ball keeps on each bounce
+}
+
+// NewBounceCurve returns a BounceCurve, whose elasticity must be at least 0
+// and less than 1 so that it comes to rest
+func NewBounceCurve(elasticity float64) (BounceCurve, error) {
+	if elasticity < 0 || elasticity >= 1 {
+		return BounceCurve{}, errors.New("bounce elasticity must be at least 0 and less than 1")
+	}
+	return BounceCurve{elasticity}, nil
+}
+
+// At returns how far a ball dropped from 1 has fallen towards the floor at 0,
+// where gravity is picked so that the bounces die out at t = 1
+func (c BounceCurve) At(t float64) float64 {
+	t = geometry.Clamp(t, 0, 1)
+	e := c.elasticity
+	// The first bounce takes 2e times as long as the fall, and each bounce
+	// after it e times as long as the last, which all adds up to 1
+	fall := (1 - e) / (1 + e)
+	if t < fall {
+		s := t / fall
+		return s * s
+	}
+	t -= fall
+	duration, height := 2*fall*e, e*e
+	for duration > 1e-9 {
+		if t < duration {
+			s := 2*t/duration - 1
+			return 1 - height*(1-s*s)
+		}
+		t -= duration
+		duration *= e
+		height *= e * e
+	}
+	return 1
+}
+
+// DampCurve is a value thrown towards its end that slows down under drag,
+// coming to a stop at the end
+type DampCurve struct {
+	damping float64 // how quickly the value slows down
+}
+
+// NewDampCurve returns a DampCurve, whose damping must be positive
+func NewDampCurve(damping float64) (DampCurve, error) {
+	if damping <= 0 {
+		return DampCurve{}, errors.New("damping must be greater than zero")
+	}
+	return DampCurve{damping}, nil
+}
+
+// At returns the position of a value whose speed decays exponentially, scaled
+// so that it stops at 1
+func (c DampCurve) At(t float64) float64 {
+	t = geometry.Clamp(t, 0, 1)
+	return (1 - math.Exp(-c.damping*t)) / (1 - math.Exp(-c.damping))
+}
+
+// namedCurves are the timing curves available without being defined, named
+// after their CSS equivalents
+var namedCurves = map[string]Curve{
+	"linear":      LinearCurve{},
+	"ease":        CubicBezier{0.25, 0.1, 0.25, 1},
+	"ease-in":     CubicBezier{0.42, 0, 1, 1},
+	"ease-out":    CubicBezier{0, 0, 0.58, 1},
+	"ease-in-out": CubicBezier{0.42, 0, 0.58, 1},
+}
@@ -0,0 +1,191 @@
+package parser
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ReadKnobData returns the series of values in a column of a CSV or JSON file,
+// where column is the column's name or its number counting from 1, or "" for
+// a file with only one column
+// CSV files may start with a row naming their columns. JSON files may be an
+// array of numbers, an array of rows that are arrays or objects, or an object
+// of arrays of numbers.
+func ReadKnobData(filename, column string) ([]float64, error) {
+	input, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var series []float64
+	if strings.ToLower(filepath.Ext(filename)) == ".json" {
+		series, err = jsonSeries(input, column)
+	} else {
+		series, err = csvSeries(input, column)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", filename, err)
+	}
+	if len(series) == 0 {
+		return nil, fmt.Errorf("%s: no values", filename)
+	}
+	return series, nil
+}
+
+// csvSeries returns the values in a column of CSV
+func csvSeries(input []byte, column string) ([]float64, error) {
+	reader := csv.NewReader(strings.NewReader(string(input)))
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, errors.New("no rows")
+	}
+
+	// The first row names the columns if any of it isn't a number
+	var header []string
+	for _, cell := range rows[0] {
+		if _, err := strconv.ParseFloat(cell, 64); err != nil {
+			header = rows[0]
+			rows = rows[1:]
+			break
+		}
+	}
+	index := -1
+	for i, name := range header {
+		if name == column {
+			index = i
+		}
+	}
+	if index < 0 {
+		if index, err = columnIndex(column, len(rows) > 0 && len(rows[0]) == 1); err != nil {
+			return nil, err
+		}
+	}
+
+	series := make([]float64, 0, len(rows))
+	for i, row := range rows {
+		line := i + 1
+		if header != nil {
+			line++
+		}
+		if index >= len(row) {
+			return nil, fmt.Errorf("line %d has no column %s", line, column)
+		}
+		value, err := strconv.ParseFloat(row[index], 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: '%s' is not a number", line, row[index])
+		}
+		series = append(series, value)
+	}
+	return series, nil
+}
+
+// jsonSeries returns the values in a column of JSON
+func jsonSeries(input []byte, column string) ([]float64, error) {
+	var data interface{}
+	if err := json.Unmarshal(input, &data); err != nil {
+		return nil, err
+	}
+	number := func(value interface{}, where string) (float64, error) {
+		if n, ok := value.(float64); ok {
+			return n, nil
+		}
+		return 0, fmt.Errorf("%s is not a number", where)
+	}
+
+	var values []interface{}
+	switch data := data.(type) {
+	case map[string]interface{}:
+		if column == "" && len(data) == 1 {
+			for name := range data {
+				column = name
+			}
+		}
+		found, ok := data[column]
+		if !ok {
+			return nil, fmt.Errorf("no column %s", column)
+		}
+		if values, ok = found.([]interface{}); !ok {
+			return nil, fmt.Errorf("column %s is not an array", column)
+		}
+	case []interface{}:
+		values = data
+	default:
+		return nil, errors.New("expected an array or an object")
+	}
+
+	series := make([]float64, 0, len(values))
+	for i, value := range values {
+		where := fmt.Sprintf("value %d", i+1)
+		switch row := value.(type) {
+		case map[string]interface{}:
+			found, ok := row[column]
+			if !ok {
+				return nil, fmt.Errorf("row %d has no column %s", i+1, column)
+			}
+			value = found
+		case []interface{}:
+			index, err := columnIndex(column, len(row) == 1)
+			if err != nil {
+				return nil, err
+			}
+			if index >= len(row) {
+				return nil, fmt.Errorf("row %d has no column %s", i+1, column)
+			}
+			value = row[index]
+		}
+		n, err := number(value, where)
+		if err != nil {
+			return nil, err
+		}
+		series = append(series, n)
+	}
+	return series, nil
+}
+
+// columnIndex returns the index of a column given by its number counting from
+// 1, where "" is the only column if there is only one
+func columnIndex(column string, single bool) (int, error) {
+	if column == "" {
+		if !single {
+			return 0, errors.New("the file has more than one column, so one must be given")
+		}
+		return 0, nil
+	}
+	n, err := strconv.Atoi(column)
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("no column %s", column)
+	}
+	return n - 1, nil
+}
+
+// Resample returns a series stretched or squeezed to the given number of
+// frames, with its first value in the first frame and its last value in the
+// last, interpolating linearly between the values in between
+func Resample(series []float64, frames int) []float64 {
+	resampled := make([]float64, frames)
+	for frame := range resampled {
+		if len(series) == 1 || frames == 1 {
+			resampled[frame] = series[0]
+			continue
+		}
+		position := float64(frame) * float64(len(series)-1) / float64(frames-1)
+		i := int(position)
+		if i >= len(series)-1 {
+			resampled[frame] = series[len(series)-1]
+			continue
+		}
+		t := position - float64(i)
+		resampled[frame] = series[i] + (series[i+1]-series[i])*t
+	}
+	return resampled
+}
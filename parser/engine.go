@@ -0,0 +1,139 @@
+// Package parser parses MDL scripts and renders their frames, either all at
+// once like the command line does or one at a time through a Scene and a
+// Renderer
+package parser
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"sync"
+
+	"github.com/james9909/graphics-engine/image"
+	"github.com/james9909/graphics-engine/render"
+)
+
+// The Scene and Renderer API separates parsing a script from rendering it, so
+// that the engine can be driven without the command line (for example, to
+// render frames into an existing program's images). main.go only sets the
+// Parser's options from flags and hands it the script.
+
+// Scene is a parsed script that can be rendered any number of times
+type Scene struct {
+	commands []Command
+	tables   *SymbolTables
+	frames   int      // number of frames, 1 for a still image
+	animated bool     // whether the script is an animation
+	warnings []string // likely mistakes that don't stop the script from rendering
+}
+
+// Frames returns the number of frames in the Scene
+func (s *Scene) Frames() int {
+	return s.frames
+}
+
+// Animated returns whether the Scene is an animation
+func (s *Scene) Animated() bool {
+	return s.animated
+}
+
+// Warnings returns the likely mistakes in the script, such as pushes that are
+// never popped, which don't stop it from rendering
+func (s *Scene) Warnings() []string {
+	return s.warnings
+}
+
+// ParseScene parses a script into a Scene without rendering it
+// Each script gets its own symbols and settings, so a Parser can parse any
+// number of scripts.
+func (p *Parser) ParseScene(input string) (*Scene, error) {
+	p.reset()
+	p.hash = fmt.Sprintf("%x", sha256.Sum256([]byte(input)))
+	p.lexer = Lex(input)
+	commands, err := p.parseChecked()
+	if err != nil {
+		return nil, err
+	}
+	scene := &Scene{
+		commands: commands,
+		tables:   p.tables,
+		frames:   p.frames,
+		animated: p.isAnimated,
+	}
+	// The stack is thrown away once the script ends, so pushes left over
+	// don't break anything, but they are often a pop that was forgotten
+	for _, push := range p.pushes {
+		scene.warnings = append(scene.warnings, fmt.Sprintf("%s: push is never popped", push))
+	}
+	if !scene.animated {
+		scene.frames = 1
+	}
+	return scene, nil
+}
+
+// reset clears what the previous script defined, keeping the options set on the
+// Parser
+func (p *Parser) reset() {
+	p.tables = NewSymbolTables()
+	p.macros = make(map[string]macro)
+	p.macroDepth = 0
+	p.includes = nil
+	p.dependencies = nil
+	p.backup = p.backup[:0]
+	p.isAnimated = false
+	p.frames = 0
+	p.basename = ""
+	p.sceneEnd = 0
+	p.pushes = nil
+	p.fps = 0
+	p.timed = false
+	p.random = nil
+	p.picking = false
+	if !p.fixedDelay {
+		p.delay = image.DefaultDelay
+	}
+	if !p.fixedWidth {
+		p.width = image.DefaultWidth
+	}
+	if !p.fixedHeight {
+		p.height = image.DefaultHeight
+	}
+}
+
+// Renderer renders the frames of a Scene into images
+// A Renderer is safe for use by multiple goroutines, but renders one frame at
+// a time.
+type Renderer struct {
+	drawer *render.Drawer
+	mu     sync.Mutex
+}
+
+// NewRenderer returns a Renderer that renders with the options of the Parser
+func (p *Parser) NewRenderer() (*Renderer, error) {
+	drawer, err := p.newDrawer()
+	if err != nil {
+		return nil, err
+	}
+	return &Renderer{drawer: drawer}, nil
+}
+
+// Render renders a frame of the Scene
+// The returned Image is not reused by later renders.
+func (r *Renderer) Render(scene *Scene, frame int) (*image.Image, error) {
+	if frame < 0 || frame >= scene.frames {
+		return nil, fmt.Errorf("frame %d is out of range for %d frames", frame, scene.frames)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.drawer.Reset()
+	r.drawer.BeginFrame(frame)
+	if err := drawFrame(context.Background(), r.drawer, scene.tables, scene.commands, frame); err != nil {
+		return nil, err
+	}
+	output := r.drawer.Output(false)
+	if output == r.drawer.Base() {
+		// Output returns the base layer itself when there is nothing to add
+		output = output.Copy()
+	}
+	return output, nil
+}
@@ -0,0 +1,145 @@
+package parser
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/james9909/graphics-engine/image"
+	"github.com/james9909/graphics-engine/render"
+)
+
+// parse parses a script with p, failing the test if it doesn't parse
+func parse(t *testing.T, p *Parser, script string) *Scene {
+	t.Helper()
+	scene, err := p.ParseScene(script)
+	if err != nil {
+		t.Fatalf("parsing %q: %v", script, err)
+	}
+	return scene
+}
+
+func TestTimedScriptsDontShareFrameRates(t *testing.T) {
+	p := NewParser()
+	if scene := parse(t, p, "basename \"first\"\nfps 10\nduration 2\n"); scene.Frames() != 20 {
+		t.Errorf("first script has %d frames, want 20", scene.Frames())
+	}
+	if p.delay != 10 {
+		t.Errorf("first script has a delay of %d, want 10", p.delay)
+	}
+	// Without fps of its own, the second script gets the default frame rate
+	want := int(2 * render.DefaultFrameRate)
+	if scene := parse(t, p, "basename \"second\"\nduration 2\n"); scene.Frames() != want {
+		t.Errorf("second script has %d frames, want %d", scene.Frames(), want)
+	}
+	if p.delay != image.DefaultDelay {
+		t.Errorf("second script has a delay of %d, want %d", p.delay, image.DefaultDelay)
+	}
+	// The second script's duration doesn't stop the third from setting fps
+	if scene := parse(t, p, "basename \"third\"\nfps 5\nduration 2\n"); scene.Frames() != 10 {
+		t.Errorf("third script has %d frames, want 10", scene.Frames())
+	}
+}
+
+func TestDelayFromCommandLineOutlastsScripts(t *testing.T) {
+	p := NewParser()
+	p.SetGIF(7, 0)
+	parse(t, p, "basename \"first\"\nfps 10\nduration 2\n")
+	parse(t, p, "basename \"second\"\nduration 2\n")
+	if p.delay != 7 {
+		t.Errorf("delay is %d after two scripts, want the 7 set on the Parser", p.delay)
+	}
+}
+
+func TestScriptsStartFromTheSameSeed(t *testing.T) {
+	p := NewParser()
+	parse(t, p, "seed 42\n")
+	parse(t, p, "\n")
+	if got, want := p.randomSource().Int63(), rand.New(rand.NewSource(0)).Int63(); got != want {
+		t.Errorf("script without a seed draws %d after a seeded script, want %d as if seeded with 0", got, want)
+	}
+}
+
+func TestPickingEndsWithTheScriptThatPicks(t *testing.T) {
+	p := NewParser()
+	parse(t, p, "pick 1 1\n")
+	if !p.picking {
+		t.Fatal("script with pick doesn't keep what covers each pixel")
+	}
+	parse(t, p, "\n")
+	if p.picking {
+		t.Error("script without pick keeps what covers each pixel after a script with one")
+	}
+}
+
+// renderScript renders a frame of a script with a new Parser, failing the test if it
+// doesn't parse or render
+func renderScript(t *testing.T, script string, frame int) *image.Image {
+	t.Helper()
+	p := NewParser()
+	scene := parse(t, p, script)
+	renderer, err := p.NewRenderer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err := renderer.Render(scene, frame)
+	if err != nil {
+		t.Fatalf("rendering frame %d of %q: %v", frame, script, err)
+	}
+	return img
+}
+
+// sameImages reports whether two images have the same pixels
+func sameImages(a, b *image.Image) bool {
+	if a.Bounds() != b.Bounds() {
+		return false
+	}
+	bounds := a.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			if a.At(x, y) != b.At(x, y) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+const cameraScene = "light key 255 255 255 1 1 1\nprojection perspective 60\n%s\nbox 150 350 100 200 200 200\n"
+
+func TestCameraAtTheEyeOfTheProjectionChangesNothing(t *testing.T) {
+	eye := 250 / math.Tan(math.Pi/6)
+	straight := renderScript(t, fmt.Sprintf(cameraScene, ""), 0)
+	camera := renderScript(t, fmt.Sprintf(cameraScene, fmt.Sprintf("camera 250 250 %v 250 250 0", eye)), 0)
+	if !sameImages(straight, camera) {
+		t.Error("a camera where the projection's eye is draws a different image than no camera")
+	}
+	turned := renderScript(t, fmt.Sprintf(cameraScene, "camera 600 250 400 250 250 0"), 0)
+	if sameImages(straight, turned) {
+		t.Error("a camera looking from the side draws the same image as no camera")
+	}
+}
+
+func TestCameraPathMovesTheEyeAcrossTheAnimation(t *testing.T) {
+	path := "curve orbit bezier 250 250 700 750 250 700 750 250 -200 250 250 -200\ncamera 0 0 1 250 250 0\ncamerapath orbit"
+	script := "frames 3\nbasename \"orbit\"\n" + fmt.Sprintf(cameraScene, path)
+	for frame, eye := range []string{"250 250 700", "625 250 250", "250 250 -200"} {
+		want := renderScript(t, fmt.Sprintf(cameraScene, "camera "+eye+" 250 250 0"), 0)
+		if !sameImages(renderScript(t, script, frame), want) {
+			t.Errorf("frame %d isn't seen from %s", frame, eye)
+		}
+	}
+}
+
+func TestCameraPathNeedsACamera(t *testing.T) {
+	p := NewParser()
+	scene := parse(t, p, "curve track bezier 0 0 100 0 0 200 0 0 300 0 0 400\ncamerapath track\n")
+	renderer, err := p.NewRenderer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := renderer.Render(scene, 0); err == nil {
+		t.Error("camerapath without a camera renders")
+	}
+}
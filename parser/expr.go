@@ -0,0 +1,183 @@
+package parser
+
+import (
+	"fmt"
+	"strconv"
+	"unicode"
+)
+
+// SymbolTable holds the variables defined by a script
+type SymbolTable struct {
+	variables map[string]float64
+	saved     []map[string]float64 // variables of the enclosing scopes
+}
+
+// NewSymbolTable returns an empty SymbolTable
+func NewSymbolTable() *SymbolTable {
+	return &SymbolTable{
+		variables: make(map[string]float64),
+	}
+}
+
+// Set defines a variable, replacing any previous value
+func (s *SymbolTable) Set(name string, value float64) {
+	s.variables[name] = value
+}
+
+// Get returns the value of a variable
+func (s *SymbolTable) Get(name string) (float64, error) {
+	if value, found := s.variables[name]; found {
+		return value, nil
+	}
+	return 0, fmt.Errorf("undefined variable '%s'", name)
+}
+
+// Push starts a new scope, whose variables are discarded when it is popped
+func (s *SymbolTable) Push() {
+	variables := make(map[string]float64, len(s.variables))
+	for name, value := range s.variables {
+		variables[name] = value
+	}
+	s.saved = append(s.saved, s.variables)
+	s.variables = variables
+}
+
+// Pop ends the current scope, restoring the variables from before it started
+func (s *SymbolTable) Pop() {
+	last := len(s.saved) - 1
+	s.variables = s.saved[last]
+	s.saved = s.saved[:last]
+}
+
+// Evaluate evaluates an arithmetic expression of numbers, variables, + - * /,
+// and parentheses
+func (s *SymbolTable) Evaluate(expression string) (float64, error) {
+	e := &evaluator{symbols: s, input: []rune(expression)}
+	value, err := e.sum()
+	if err != nil {
+		return 0, err
+	}
+	if e.pos < len(e.input) {
+		return 0, fmt.Errorf("unexpected '%c' in expression '%s'", e.input[e.pos], expression)
+	}
+	return value, nil
+}
+
+// evaluator is a recursive descent evaluator for a single expression
+type evaluator struct {
+	symbols *SymbolTable
+	input   []rune
+	pos     int
+}
+
+// peek returns the next rune, or 0 at the end of the expression
+func (e *evaluator) peek() rune {
+	if e.pos >= len(e.input) {
+		return 0
+	}
+	return e.input[e.pos]
+}
+
+// sum evaluates terms separated by + or -
+func (e *evaluator) sum() (float64, error) {
+	value, err := e.product()
+	if err != nil {
+		return 0, err
+	}
+	for e.peek() == '+' || e.peek() == '-' {
+		op := e.peek()
+		e.pos++
+		operand, err := e.product()
+		if err != nil {
+			return 0, err
+		}
+		if op == '+' {
+			value += operand
+		} else {
+			value -= operand
+		}
+	}
+	return value, nil
+}
+
+// product evaluates factors separated by * or /
+func (e *evaluator) product() (float64, error) {
+	value, err := e.factor()
+	if err != nil {
+		return 0, err
+	}
+	for e.peek() == '*' || e.peek() == '/' {
+		op := e.peek()
+		e.pos++
+		operand, err := e.factor()
+		if err != nil {
+			return 0, err
+		}
+		if op == '*' {
+			value *= operand
+		} else if operand == 0 {
+			return 0, fmt.Errorf("division by zero in expression '%s'", string(e.input))
+		} else {
+			value /= operand
+		}
+	}
+	return value, nil
+}
+
+// factor evaluates a number, variable, negation, or parenthesized expression
+func (e *evaluator) factor() (float64, error) {
+	r := e.peek()
+	switch {
+	case r == '-' || r == '+':
+		e.pos++
+		value, err := e.factor()
+		if r == '-' {
+			value = -value
+		}
+		return value, err
+	case r == '(':
+		e.pos++
+		value, err := e.sum()
+		if err != nil {
+			return 0, err
+		}
+		if e.peek() != ')' {
+			return 0, fmt.Errorf("missing ')' in expression '%s'", string(e.input))
+		}
+		e.pos++
+		return value, nil
+	case unicode.IsDigit(r) || r == '.':
+		start := e.pos
+		for unicode.IsDigit(e.peek()) || e.peek() == '.' || e.peek() == '_' {
+			e.pos++
+		}
+		// An exponent is only part of the number if digits follow it
+		if e.peek() == 'e' || e.peek() == 'E' {
+			exponent := e.pos + 1
+			if exponent < len(e.input) && (e.input[exponent] == '-' || e.input[exponent] == '+') {
+				exponent++
+			}
+			if exponent < len(e.input) && unicode.IsDigit(e.input[exponent]) {
+				e.pos = exponent
+				for unicode.IsDigit(e.peek()) || e.peek() == '_' {
+					e.pos++
+				}
+			}
+		}
+		value, err := strconv.ParseFloat(string(e.input[start:e.pos]), 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid number '%s'", string(e.input[start:e.pos]))
+		}
+		return value, nil
+	case unicode.IsLetter(r) || r == '_':
+		start := e.pos
+		for unicode.IsLetter(e.peek()) || unicode.IsDigit(e.peek()) || e.peek() == '_' {
+			e.pos++
+		}
+		return e.symbols.Get(string(e.input[start:e.pos]))
+	case r == 0:
+		return 0, fmt.Errorf("incomplete expression '%s'", string(e.input))
+	default:
+		return 0, fmt.Errorf("unexpected '%c' in expression '%s'", r, string(e.input))
+	}
+}
@@ -0,0 +1,194 @@
+package parser
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// KnobRange maps a MIDI controller onto a knob
+type KnobRange struct {
+	knob string  // name of the knob
+	min  float64 // knob value when the controller is at 0
+	max  float64 // knob value when the controller is at 127
+}
+
+// ParseMIDIMap parses a comma separated list of knob=cc[:min:max] mappings
+func ParseMIDIMap(s string) (map[byte]KnobRange, error) {
+	mapping := make(map[byte]KnobRange)
+	for _, entry := range strings.Split(s, ",") {
+		if entry == "" {
+			continue
+		}
+		parts := strings.SplitN(entry, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid midi mapping %q", entry)
+		}
+		fields := strings.Split(parts[1], ":")
+		if len(fields) != 1 && len(fields) != 3 {
+			return nil, fmt.Errorf("invalid midi mapping %q", entry)
+		}
+		cc, err := strconv.ParseUint(fields[0], 10, 7)
+		if err != nil {
+			return nil, fmt.Errorf("invalid controller in midi mapping %q", entry)
+		}
+		r := KnobRange{knob: parts[0], min: 0, max: 1}
+		if len(fields) == 3 {
+			if r.min, err = strconv.ParseFloat(fields[1], 64); err != nil {
+				return nil, err
+			}
+			if r.max, err = strconv.ParseFloat(fields[2], 64); err != nil {
+				return nil, err
+			}
+		}
+		mapping[byte(cc)] = r
+	}
+	return mapping, nil
+}
+
+// ListenMIDI reads a raw MIDI byte stream (such as /dev/snd/midiC1D0) and
+// applies control changes to their mapped knobs
+func (s *ControlServer) ListenMIDI(device string, mapping map[byte]KnobRange) error {
+	f, err := os.Open(device)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	fmt.Printf("Listening for MIDI on %s\n", device)
+
+	reader := bufio.NewReader(f)
+	var status byte // running status
+	var data []byte // data bytes of the current message
+	for {
+		b, err := reader.ReadByte()
+		if err != nil {
+			return err
+		}
+		switch {
+		case b >= 0xF8:
+			// System real-time messages may appear anywhere and are ignored
+			continue
+		case b >= 0xF0:
+			// System common messages cancel the running status
+			status = 0
+			data = data[:0]
+			continue
+		case b >= 0x80:
+			status = b
+			data = data[:0]
+			continue
+		}
+		// Only control changes are used
+		if status&0xF0 != 0xB0 {
+			continue
+		}
+		data = append(data, b)
+		if len(data) == 2 {
+			if r, found := mapping[data[0]]; found {
+				value := r.min + (r.max-r.min)*float64(data[1])/127
+				s.SetKnob(r.knob, value)
+			}
+			data = data[:0]
+		}
+	}
+}
+
+// ListenOSC receives OSC messages over UDP and applies them to knobs
+// The knob is named by the last element of the address, so both /spin and
+// /knob/spin set the knob "spin"
+func (s *ControlServer) ListenOSC(address string) error {
+	conn, err := net.ListenPacket("udp", address)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	fmt.Printf("Listening for OSC on %s\n", conn.LocalAddr())
+
+	packet := make([]byte, 65536)
+	for {
+		n, _, err := conn.ReadFrom(packet)
+		if err != nil {
+			return err
+		}
+		if err := s.handleOSC(packet[:n]); err != nil {
+			fmt.Fprintln(os.Stderr, "OSC error:", err)
+		}
+	}
+}
+
+// handleOSC decodes an OSC message or bundle
+func (s *ControlServer) handleOSC(packet []byte) error {
+	address, rest, err := readOSCString(packet)
+	if err != nil {
+		return err
+	}
+	if address == "#bundle" {
+		// Skip the time tag and handle each element of the bundle
+		if len(rest) < 8 {
+			return errors.New("truncated bundle")
+		}
+		rest = rest[8:]
+		for len(rest) >= 4 {
+			size := int(binary.BigEndian.Uint32(rest))
+			rest = rest[4:]
+			if size > len(rest) {
+				return errors.New("truncated bundle element")
+			}
+			if err := s.handleOSC(rest[:size]); err != nil {
+				return err
+			}
+			rest = rest[size:]
+		}
+		return nil
+	}
+
+	tags, rest, err := readOSCString(rest)
+	if err != nil {
+		return err
+	}
+	if len(tags) < 2 || tags[0] != ',' {
+		return fmt.Errorf("message %s has no arguments", address)
+	}
+	var value float64
+	switch tags[1] {
+	case 'f':
+		if len(rest) < 4 {
+			return errors.New("truncated argument")
+		}
+		value = float64(math.Float32frombits(binary.BigEndian.Uint32(rest)))
+	case 'i':
+		if len(rest) < 4 {
+			return errors.New("truncated argument")
+		}
+		value = float64(int32(binary.BigEndian.Uint32(rest)))
+	case 'd':
+		if len(rest) < 8 {
+			return errors.New("truncated argument")
+		}
+		value = math.Float64frombits(binary.BigEndian.Uint64(rest))
+	default:
+		return fmt.Errorf("unsupported argument type '%c' for %s", tags[1], address)
+	}
+	s.SetKnob(path.Base(address), value)
+	return nil
+}
+
+// readOSCString reads a null terminated string padded to a multiple of 4 bytes
+func readOSCString(b []byte) (string, []byte, error) {
+	end := strings.IndexByte(string(b), 0)
+	if end < 0 {
+		return "", nil, errors.New("unterminated string")
+	}
+	padded := (end + 4) &^ 3
+	if padded > len(b) {
+		padded = len(b)
+	}
+	return string(b[:end]), b[padded:], nil
+}
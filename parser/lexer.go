@@ -1,5 +1,5 @@
 // Lexing behavior adopted from the talk "Lexical Scanning in Go" (https://talks.golang.org/2011/lex.slide)
-package main
+package parser
 
 import (
 	"fmt"
@@ -20,6 +20,7 @@ type Lexer struct {
 	pos    int        // lexer's current position in the input
 	start  int        // starting position of the current item
 	line   int        // current line
+	first  int        // line the current item started on
 	width  int        // width of the last rune
 }
 
@@ -31,6 +32,8 @@ func Lex(input string) (l *Lexer) {
 		tokens: make(chan Token),
 		input:  input,
 		length: len(input),
+		line:   1,
+		first:  1,
 	}
 	go lexer.run()
 	return lexer
@@ -64,13 +67,15 @@ func (l *Lexer) emit(tt TokenType) {
 	l.tokens <- Token{
 		tt:    tt,
 		value: l.input[l.start:l.pos],
+		line:  l.first,
 	}
-	l.start = l.pos
+	l.ignore()
 }
 
 // ignore passes over the current token
 func (l *Lexer) ignore() {
 	l.start = l.pos
+	l.first = l.line
 }
 
 // next consumes and returns the next rune
@@ -131,6 +136,8 @@ func lexRoot(l *Lexer) stateFn {
 			return lexComment
 		}
 		return lexString
+	case r == '"':
+		return lexQuoted
 	case unicode.IsPrint(r):
 		return lexString
 	default:
@@ -138,13 +145,16 @@ func lexRoot(l *Lexer) stateFn {
 	}
 }
 
-// error emits a lex error
+// error emits a lex error and carries on lexing after it, so that the parser
+// can report errors past it
 func (l *Lexer) error(s string) stateFn {
 	l.tokens <- Token{
 		tt:    tError,
-		value: fmt.Sprintf("%d: syntax error: %s", l.line, s),
+		value: fmt.Sprintf("syntax error: %s", s),
+		line:  l.first,
 	}
-	return nil
+	l.ignore()
+	return lexRoot
 }
 
 // lexComment lexes a comment
@@ -162,22 +172,47 @@ func lexComment(l *Lexer) stateFn {
 	}
 }
 
-// lexNumber lexes a number
+// acceptDigits consumes a run of digits, which can be separated by single
+// underscores as in 1_000, returning whether there were any digits
+func (l *Lexer) acceptDigits() bool {
+	start := l.pos
+	for {
+		l.acceptRun("0123456789")
+		if l.pos == start || !l.accept("_") {
+			return l.pos > start
+		}
+		// An underscore is only part of the number between two digits
+		if r := l.peek(); r < '0' || r > '9' {
+			l.unread()
+			return true
+		}
+	}
+}
+
+// lexNumber lexes a number, which can have an exponent as in 1.5e-3
 func lexNumber(l *Lexer) stateFn {
 	// accept an optional sign
 	l.accept("+-")
 
-	l.acceptRun("0123456789")
+	l.acceptDigits()
 	// accept floating points
 	if l.accept(".") {
-		l.acceptRun("0123456789")
+		l.acceptDigits()
+	}
+	if l.accept("eE") {
+		l.accept("+-")
+		if !l.acceptDigits() {
+			// Not a number, but it can still be an expression such as 2e
+			return lexString
+		}
 	}
 	next := l.peek()
-	// The next character must be numeric
-	if unicode.IsLetter(next) {
-		return l.error("invalid number")
+	// A number followed by anything but whitespace is an expression such as
+	// 2*r, so lex it as a string
+	if next != eof && unicode.IsPrint(next) && !unicode.IsSpace(next) {
+		return lexString
 	}
-	if strings.ContainsRune(l.input[l.start:l.pos], '.') {
+	if strings.ContainsAny(l.input[l.start:l.pos], ".eE") {
 		l.emit(tFloat)
 	} else {
 		l.emit(tInt)
@@ -185,6 +220,28 @@ func lexNumber(l *Lexer) stateFn {
 	return lexRoot
 }
 
+// lexQuoted lexes a string surrounded by double quotes, which can contain spaces
+// and escape sequences such as \" and \\
+func lexQuoted(l *Lexer) stateFn {
+	for {
+		switch l.next() {
+		case '"':
+			l.emit(tString)
+			return lexRoot
+		case '\\':
+			// The escaped rune can't end the string, but the end of the line
+			// still does
+			if r := l.peek(); r != '\n' && r != eof {
+				l.next()
+			}
+		case '\n', eof:
+			// The end of the line is left to end the statement
+			l.unread()
+			return l.error("unterminated string")
+		}
+	}
+}
+
 // lexString lexes a string
 func lexString(l *Lexer) stateFn {
 	r := l.next()
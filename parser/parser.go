@@ -0,0 +1,3033 @@
+package parser
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/james9909/graphics-engine/geometry"
+	"github.com/james9909/graphics-engine/image"
+	"github.com/james9909/graphics-engine/render"
+)
+
+const (
+	DefaultBasename = "frame"  // Default frame basename
+	FramesDirectory = "frames" // FramesDirectory is the directory containing all animation frames
+	MaxWorkers      = 2        // maximum number of workers
+)
+
+// MaxMacroDepth is the most macros that can be called within each other
+const MaxMacroDepth = 100
+
+// macro is a sequence of commands that is parsed each time it is called
+type macro struct {
+	params []string // names of the variables the arguments are bound to
+	body   []Token  // tokens of the commands
+}
+
+// script is a script that is being parsed
+type script struct {
+	name string // name the script was given by
+	path string // absolute path of the script
+}
+
+// block is a block of commands that has been started but not ended
+type block struct {
+	name    string    // description of the block for errors
+	command Command   // command the block becomes once it is ended
+	parent  []Command // commands of the enclosing block
+	pushes  []string  // pushes of the enclosing block that are not popped yet
+}
+
+// Parser is a script parser
+type Parser struct {
+	lexer  *Lexer  // lexer
+	backup []Token // token backup
+
+	isAnimated    bool    // whether or not to parse as an animation
+	frames        int     // number of frames in the animation
+	fps           float64 // frames per second set by the script, or 0 for the default
+	timed         bool    // whether a duration, time in seconds, or audio was given yet
+	basename      string  // animation basename
+	video         string  // video file to stream frames into, if any
+	control       string  // unix socket to accept live controllers on, if any
+	midi          string  // raw MIDI device to read knob changes from, if any
+	midiMap       map[byte]KnobRange
+	osc           string          // UDP address to receive OSC knob messages on, if any
+	tuner         string          // address to serve the knob tuner on, if any
+	preview       *render.Preview // preview that rendered frames are shown in, if any
+	lineWidth     float64
+	snapLines     bool // whether the ends of lines are snapped to whole pixels
+	dither        image.DitherMode
+	bits          int             // bits per color channel of saved images
+	stats         bool            // whether to stamp render statistics onto saved images
+	layout        render.Layout   // how each frame is drawn in panes
+	passes        bool            // whether depth, normal, and shape ID passes are saved with every image
+	picking       bool            // whether the script picks pixels, which needs what covers each pixel kept
+	deterministic bool            // whether every render of the script must come out the same bit for bit
+	resume        bool            // whether to resume an interrupted animation
+	swap          string          // directory that the pixels of images are kept in, or "" for memory
+	ctx           context.Context // cancelled to stop rendering
+	checkOnly     bool            // whether to only check scripts for problems instead of rendering them
+	dumpKnobs     bool            // whether to print the value of every knob in each frame instead of rendering
+	delay         int             // delay between gif frames in hundredths of a second
+	loop          int             // number of times gifs repeat after playing once
+	hash          string          // hash of the script itself, for checkpoints
+	tables        *SymbolTables   // knobs, lights, and variables defined by the script
+	macros        map[string]macro
+	macroDepth    int    // number of macros being expanded
+	filename      string // script being parsed, if it was read from a file
+	height        int
+	width         int
+	fixedWidth    bool       // whether the width was set from the command line
+	fixedHeight   bool       // whether the height was set from the command line
+	fixedDelay    bool       // whether the gif delay was set from the command line
+	includes      []script   // included scripts being parsed, innermost last
+	dependencies  []string   // files included or loaded by the script
+	sceneEnd      int        // frame after the last scene
+	pushes        []string   // where each push of the block being parsed that is not popped yet is
+	random        *rand.Rand // source of randomness for terrain, seeded by seed
+}
+
+// NewParser returns a new parser
+func NewParser() *Parser {
+	return &Parser{
+		backup:     make([]Token, 0, 10),
+		isAnimated: false,
+		lineWidth:  1,
+		bits:       8,
+		delay:      image.DefaultDelay,
+		tables:     NewSymbolTables(),
+		macros:     make(map[string]macro),
+		height:     image.DefaultHeight,
+		width:      image.DefaultWidth,
+		ctx:        context.Background(),
+	}
+}
+
+// ParseInput parses a file for commands and executes them
+func (p *Parser) ParseInput() error {
+	scanner := bufio.NewScanner(os.Stdin)
+	var input bytes.Buffer
+	for scanner.Scan() {
+		input.Write(scanner.Bytes())
+		input.WriteRune('\n')
+	}
+	err := p.ParseString(input.String())
+	return err
+}
+
+// ParseFile parses a file for commands and executes them
+func (p *Parser) ParseFile(filename string) error {
+	input, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	p.filename = filename
+	err = p.ParseString(string(input))
+	return err
+}
+
+// ParseString parses a string for commands and executes them
+func (p *Parser) ParseString(input string) error {
+	scene, err := p.ParseScene(input)
+	if err != nil {
+		return err
+	}
+	for _, warning := range scene.Warnings() {
+		fmt.Fprintln(os.Stderr, "Warning:", warning)
+	}
+	if p.checkOnly {
+		return p.reportProblems(p.Check(scene.commands))
+	}
+	if p.dumpKnobs {
+		return scene.tables.WriteKnobs(os.Stdout, scene.frames)
+	}
+	if p.isLive() {
+		return p.serve(scene.commands)
+	}
+	return p.process(p.ctx, scene.commands)
+}
+
+// Dependencies returns the files other than the script itself that were
+// included or loaded by the script parsed last
+func (p *Parser) Dependencies() []string {
+	return p.dependencies
+}
+
+// parseChecked parses the script, returning the errors that nextRequired panics
+// with instead of crashing
+func (p *Parser) parseChecked() (commands []Command, err error) {
+	err = p.recovering(func() error {
+		commands, err = p.parse()
+		return err
+	})
+	return commands, err
+}
+
+func (p *Parser) parse() ([]Command, error) {
+	commands := make([]Command, 0, 50)
+	blocks := make([]block, 0) // blocks that have not been ended yet
+	var scene *SceneCommand    // scene being parsed, if any
+	var errs ParseErrors       // errors in the statements parsed so far
+	for {
+		t := p.nextToken()
+		var err error
+		switch t.tt {
+		case tError:
+			err = errors.New(t.value)
+		case tMacroEnd:
+			p.tables.variables.Pop()
+			p.macroDepth--
+		case tIncludeEnd:
+			p.includes = p.includes[:len(p.includes)-1]
+		case tEOF:
+			if len(blocks) > 0 {
+				errs = append(errs, fmt.Errorf("%s is never ended", blocks[len(blocks)-1].name))
+			}
+			if len(errs) == 1 {
+				return nil, errs[0]
+			} else if len(errs) > 0 {
+				return nil, errs
+			}
+			p.tables.frames = p.frames
+			p.tables.frameRate = p.frameRate()
+			if p.isAnimated {
+				if p.basename == "" {
+					fmt.Fprintf(os.Stderr, "No basename provided: using default basename '%s'\n", DefaultBasename)
+					p.basename = DefaultBasename
+				}
+				// The basename can contain anything, including the verbs of format strings
+				basename := strings.ReplaceAll(p.basename, "%", "%%")
+				p.tables.formatString = fmt.Sprintf("%s/%s-%%0%dd.ppm", FramesDirectory, basename, len(strconv.Itoa(p.frames)))
+				// Knobs keep the value they were last set to, or 0, in frames
+				// they were never varied in
+				for name, knob := range p.tables.knobs {
+					for len(knob) < p.frames {
+						knob = append(knob, p.tables.knobValues[name])
+					}
+					p.tables.knobs[name] = knob
+				}
+			}
+			return commands, nil
+		case tIdent:
+			err = p.recovering(func() error {
+				var command Command
+				switch LookupIdent(t.value) {
+				case MOVE:
+					c := MoveCommand{}
+					c.args = []float64{p.nextFloat(), p.nextFloat(), p.nextFloat()}
+					c.knob, _ = p.next(tString)
+					command = c
+				case SCALE:
+					c := ScaleCommand{}
+					c.args = []float64{p.nextFloat(), p.nextFloat(), p.nextFloat()}
+					c.knob, c.pivot = p.nextKnobAndPivot()
+					command = c
+				case ROTATE:
+					c := RotateCommand{}
+					if p.peek().tt == tIdent {
+						c.axis = p.nextIdent()
+					} else if c.axis = p.nextString(); c.axis == "axis" {
+						c.vector = []float64{p.nextFloat(), p.nextFloat(), p.nextFloat()}
+						if c.vector[0] == 0 && c.vector[1] == 0 && c.vector[2] == 0 {
+							return errors.New("rotation axis must not be zero")
+						}
+					} else {
+						return fmt.Errorf("invalid rotation axis '%s'", c.axis)
+					}
+					c.degrees = p.nextFloat()
+					c.knob, c.pivot = p.nextKnobAndPivot()
+					command = c
+				case ROTATEQ:
+					c := RotateQuaternionCommand{}
+					for {
+						q, err := geometry.QuaternionAbout(p.nextFloat(), p.nextFloat(), p.nextFloat(), geometry.DegreesToRadians(p.nextFloat()))
+						if err != nil {
+							return err
+						}
+						c.keys = append(c.keys, q)
+						if !p.peekNumber() {
+							break
+						}
+					}
+					c.knob, c.pivot = p.nextKnobAndPivot()
+					if len(c.keys) > 1 && c.knob == "" {
+						return errors.New("rotateq needs a knob to pick between its keyframes")
+					}
+					command = c
+				case MIRROR:
+					c := MirrorCommand{}
+					c.axis, c.offset = p.nextMirror()
+					command = c
+				case MATRIX:
+					c := MatrixCommand{}
+					for i := range c.matrix {
+						c.matrix[i] = p.nextFloat()
+					}
+					// Points are never divided by w, so only affine
+					// transformations come out right
+					if c.matrix[12] != 0 || c.matrix[13] != 0 || c.matrix[14] != 0 || c.matrix[15] != 1 {
+						return errors.New("the last row of a matrix must be 0 0 0 1")
+					}
+					command = c
+				case LINE:
+					c := LineCommand{ShapeCommand: p.shapeAt(t)}
+					c.constants = p.nextConstants()
+					c.p1 = []float64{p.nextFloat(), p.nextFloat(), p.nextFloat()}
+					c.cs = p.nextName()
+					c.p2 = []float64{p.nextFloat(), p.nextFloat(), p.nextFloat()}
+					c.cs2 = p.nextName()
+					c.color = p.nextColor()
+					command = c
+				case CIRCLE:
+					c := CircleCommand{ShapeCommand: p.shapeAt(t)}
+					c.center = []float64{p.nextFloat(), p.nextFloat(), p.nextFloat()}
+					c.radius = p.nextFloat()
+					c.cs = p.nextName()
+					c.color = p.nextColor()
+					command = c
+				case ELLIPSE:
+					c := EllipseCommand{ShapeCommand: p.shapeAt(t)}
+					c.center = []float64{p.nextFloat(), p.nextFloat(), p.nextFloat()}
+					c.rx = p.nextFloat()
+					c.ry = p.nextFloat()
+					c.normal = p.nextFacing()
+					c.cs = p.nextName()
+					c.color = p.nextColor()
+					command = c
+				case ARC:
+					c := ArcCommand{ShapeCommand: p.shapeAt(t)}
+					c.center = []float64{p.nextFloat(), p.nextFloat(), p.nextFloat()}
+					c.radius = p.nextFloat()
+					c.start = p.nextFloat()
+					c.end = p.nextFloat()
+					c.normal = p.nextFacing()
+					c.cs = p.nextName()
+					c.color = p.nextColor()
+					command = c
+				case HERMITE:
+					c := HermiteCommand{ShapeCommand: p.shapeAt(t)}
+					var points []geometry.Vec3
+					points, c.color = p.nextCurvePoints(4)
+					c.p0, c.p1, c.r0, c.r1 = points[0], points[1], points[2], points[3]
+					if c.color == nil {
+						c.cs = p.nextName()
+						c.color = p.nextColor()
+					}
+					command = c
+				case BEZIER:
+					c := BezierCommand{ShapeCommand: p.shapeAt(t)}
+					c.points, c.color = p.nextCurvePoints(4)
+					if c.color == nil {
+						c.cs = p.nextName()
+						c.color = p.nextColor()
+					}
+					command = c
+				case PROFILE:
+					name := p.nextString()
+					var profile geometry.Profile
+					if next := p.peek(); next.tt == tIdent && LookupIdent(next.value) == CIRCLE {
+						p.nextToken()
+						radius := p.nextFloat()
+						sides := geometry.DefaultCircularSteps
+						if p.peekNumber() {
+							sides = p.nextInt()
+						}
+						if sides < 3 {
+							return fmt.Errorf("profile %s: a circle needs at least 3 sides", name)
+						}
+						profile = geometry.CircleProfile(radius, sides)
+					} else {
+						var points []float64
+						for p.peekNumber() {
+							points = append(points, p.nextFloat())
+						}
+						var err error
+						if profile, err = geometry.NewProfile(points); err != nil {
+							return fmt.Errorf("profile %s: %s", name, err)
+						}
+					}
+					p.tables.profiles[name] = profile
+				case EMITTER:
+					name := p.nextString()
+					e := &Emitter{
+						origin: geometry.Vec3{p.nextFloat(), p.nextFloat(), p.nextFloat()},
+						rate:   p.nextFloat(),
+						life:   DefaultParticleLife,
+						seed:   uint64(p.randomSource().Int63()),
+					}
+					if e.rate < 0 {
+						return fmt.Errorf("emitter %s: rate must not be negative", name)
+					}
+					options := map[string]bool{"velocity": true, "spread": true, "jitter": true, "gravity": true, "life": true}
+					if next := p.peek(); next.tt == tString && !options[next.value] {
+						e.knob = p.nextString()
+					}
+					for next := p.peek(); next.tt == tString && options[next.value]; next = p.peek() {
+						switch option := p.nextString(); option {
+						case "velocity":
+							e.velocity = geometry.Vec3{p.nextFloat(), p.nextFloat(), p.nextFloat()}
+						case "spread":
+							e.spread = p.nextFloat()
+							if e.spread < 0 || e.spread > 180 {
+								return fmt.Errorf("emitter %s: spread must be between 0 and 180 degrees", name)
+							}
+						case "jitter":
+							e.jitter = p.nextFloat()
+							if e.jitter < 0 || e.jitter > 1 {
+								return fmt.Errorf("emitter %s: jitter must be between 0 and 1", name)
+							}
+						case "gravity":
+							e.gravity = geometry.Vec3{p.nextFloat(), p.nextFloat(), p.nextFloat()}
+						case "life":
+							e.life = p.nextInt()
+							if e.life < 1 {
+								return fmt.Errorf("emitter %s: particles must live for at least 1 frame", name)
+							}
+						}
+					}
+					p.tables.emitters[name] = e
+				case PARTICLES:
+					c := ParticlesCommand{ShapeCommand: p.shapeAt(t)}
+					// Both the constants and the emitter are names, so a first
+					// name that isn't an emitter is the constants
+					name := p.nextString()
+					if _, found := p.tables.emitters[name]; !found && p.peek().tt == tString && !p.peekNumber() {
+						c.constants = name
+						if c.constants == "nil" {
+							c.constants = ""
+						}
+						name = p.nextString()
+					}
+					emitter, found := p.tables.emitters[name]
+					if !found {
+						return fmt.Errorf("undefined emitter '%s'", name)
+					}
+					c.emitter = emitter
+					if p.peekOptionalNumber() {
+						if c.size = p.nextFloat(); c.size < 0 {
+							return errors.New("particle size must not be negative")
+						}
+					}
+					if c.constants != "" && c.size == 0 {
+						return errors.New("particles need a size to be drawn with constants")
+					}
+					c.cs = p.nextName()
+					c.color = p.nextColor()
+					command = c
+				case SWEEP:
+					c := SweepCommand{ShapeCommand: p.shapeAt(t)}
+					// Both the constants and the profile are names, so the
+					// profile is the last name before the path
+					name := p.nextString()
+					if p.peek().tt == tString {
+						c.constants = name
+						if c.constants == "nil" {
+							c.constants = ""
+						}
+						name = p.nextString()
+					}
+					profile, found := p.tables.profiles[name]
+					if !found {
+						return fmt.Errorf("undefined profile '%s'", name)
+					}
+					c.profile = profile
+					var points []geometry.Vec3
+					switch path := p.nextIdent(); LookupIdent(path) {
+					case BEZIER:
+						points, c.color = p.nextCurvePoints(4)
+						c.path = geometry.BezierCubic(points[0], points[1], points[2], points[3])
+					case HERMITE:
+						points, c.color = p.nextCurvePoints(4)
+						c.path = geometry.HermiteCubic(points[0], points[1], points[2], points[3])
+					default:
+						return fmt.Errorf("invalid sweep path '%s'", path)
+					}
+					if c.color == nil {
+						c.cs = p.nextName()
+						c.color = p.nextColor()
+					}
+					command = c
+				case SEED:
+					p.random = rand.New(rand.NewSource(int64(p.nextInt())))
+				case TERRAIN:
+					c := TerrainCommand{ShapeCommand: p.shapeAt(t)}
+					c.constants = p.nextConstants()
+					c.corner = []float64{p.nextFloat(), p.nextFloat(), p.nextFloat()}
+					c.width = p.nextFloat()
+					c.depth = p.nextFloat()
+					c.height = p.nextFloat()
+					roughness := p.nextFloat()
+					detail := geometry.DefaultTerrainDetail
+					if p.peekOptionalNumber() {
+						detail = p.nextInt()
+					}
+					heights, err := geometry.NewHeightmap(detail, roughness, p.randomSource())
+					if err != nil {
+						return err
+					}
+					c.heights = heights
+					c.cs = p.nextName()
+					c.color = p.nextColor()
+					command = c
+				case LSYSTEM:
+					c := LSystemCommand{ShapeCommand: p.shapeAt(t)}
+					// The constants, axiom, and rules are all names, and only
+					// the rules have an =
+					var names, rules []string
+					for p.peek().tt == tString && !p.peekNumber() {
+						if name := p.nextString(); strings.Contains(name, "=") {
+							rules = append(rules, name)
+						} else if len(rules) == 0 {
+							names = append(names, name)
+						} else {
+							return fmt.Errorf("expected a rule, got '%s'", name)
+						}
+					}
+					switch len(names) {
+					case 2:
+						c.constants = names[0]
+						if c.constants == "nil" {
+							c.constants = ""
+						}
+					case 1:
+					default:
+						return errors.New("lsystem needs an axiom, optionally after its constants")
+					}
+					lsystem, err := geometry.NewLSystem(names[len(names)-1], rules)
+					if err != nil {
+						return err
+					}
+					iterations := p.nextInt()
+					if iterations < 0 {
+						return errors.New("lsystem iterations must not be negative")
+					}
+					angle := p.nextFloat()
+					length := p.nextFloat()
+					// The radius is told apart from the color by being a single
+					// number or followed by all three numbers of the color
+					if p.peekOptionalNumber() {
+						if c.radius = p.nextFloat(); c.radius < 0 {
+							return errors.New("lsystem radius must not be negative")
+						}
+					}
+					if c.constants != "" && c.radius == 0 {
+						return errors.New("lsystem needs a radius to be drawn with constants")
+					}
+					symbols, err := lsystem.Expand(iterations)
+					if err != nil {
+						return err
+					}
+					if c.segments, err = geometry.TurtleSegments(symbols, angle, length); err != nil {
+						return err
+					}
+					c.cs = p.nextName()
+					c.color = p.nextColor()
+					command = c
+				case METABALLS:
+					c := MetaballsCommand{ShapeCommand: p.shapeAt(t)}
+					c.constants = p.nextConstants()
+					var numbers []float64
+					for p.peekNumber() {
+						numbers = append(numbers, p.nextFloat())
+					}
+					// Each metaball is five numbers, and the resolution and
+					// color that may follow them leave a different remainder
+					resolution := float64(geometry.DefaultMetaballResolution)
+					var rgb []float64
+					switch len(numbers) % 5 {
+					case 0:
+					case 1:
+						resolution, numbers = numbers[len(numbers)-1], numbers[:len(numbers)-1]
+					case 3:
+						rgb, numbers = numbers[len(numbers)-3:], numbers[:len(numbers)-3]
+					case 4:
+						resolution, rgb, numbers = numbers[len(numbers)-4], numbers[len(numbers)-3:], numbers[:len(numbers)-4]
+					default:
+						return errors.New("metaballs are given as x y z radius strength")
+					}
+					if resolution != math.Trunc(resolution) {
+						return fmt.Errorf("expected an integer resolution, got %v", resolution)
+					}
+					if rgb != nil {
+						c.color = channelsColor(rgb)
+					}
+					if len(numbers) == 0 {
+						return errors.New("metaballs needs at least one metaball")
+					}
+					balls := make([]geometry.Metaball, len(numbers)/5)
+					for i := range balls {
+						n := numbers[5*i:]
+						balls[i] = geometry.Metaball{Center: geometry.Vec3{n[0], n[1], n[2]}, Radius: n[3], Strength: n[4]}
+					}
+					c.surface = geometry.NewMatrix(4, 0)
+					if err := c.surface.AddMetaballs(balls, int(resolution)); err != nil {
+						return err
+					}
+					if c.color == nil {
+						c.cs = p.nextName()
+						c.color = p.nextColor()
+					}
+					command = c
+				case SPHERE:
+					c := SphereCommand{ShapeCommand: p.shapeAt(t)}
+					c.constants = p.nextConstants()
+					c.center = []float64{p.nextFloat(), p.nextFloat(), p.nextFloat()}
+					c.radius = p.nextFloat()
+					c.segments = p.nextSegments()
+					c.cs = p.nextName()
+					c.color = p.nextColor()
+					command = c
+				case TORUS:
+					c := TorusCommand{ShapeCommand: p.shapeAt(t)}
+					c.constants = p.nextConstants()
+					c.center = []float64{p.nextFloat(), p.nextFloat(), p.nextFloat()}
+					c.r1 = p.nextFloat()
+					c.r2 = p.nextFloat()
+					c.segments = p.nextSegments()
+					c.cs = p.nextName()
+					c.color = p.nextColor()
+					command = c
+				case BOX:
+					c := BoxCommand{ShapeCommand: p.shapeAt(t)}
+					c.constants = p.nextConstants()
+					c.p1 = []float64{p.nextFloat(), p.nextFloat(), p.nextFloat()}
+					c.width = p.nextFloat()
+					c.height = p.nextFloat()
+					c.depth = p.nextFloat()
+					c.cs = p.nextName()
+					c.color = p.nextColor()
+					command = c
+				case POP:
+					// Popping more than was pushed would pop the coordinate
+					// system of the enclosing block
+					if len(p.pushes) == 0 {
+						return errors.New("pop without a matching push")
+					}
+					p.pushes = p.pushes[:len(p.pushes)-1]
+					command = PopCommand{}
+				case PUSH:
+					p.pushes = append(p.pushes, p.position(t))
+					command = PushCommand{}
+				case SAVE:
+					command = SaveCommand{
+						filename: p.nextString(),
+					}
+				case SAVECS:
+					command = SaveCoordinateSystemCommand{
+						name: p.nextString(),
+					}
+				case SAVEDEPTH:
+					command = SaveDepthCommand{
+						filename: p.nextString(),
+					}
+				case DISPLAY:
+					// Animations are only displayed once, at the chosen frame
+					c := DisplayCommand{}
+					if next := p.peek(); next.tt != tNewline && next.tt != tEOF {
+						c.frame = p.nextFrame(p.frames)
+						if err := p.checkFrame(c.frame, "display"); err != nil {
+							return err
+						}
+					}
+					command = c
+				case SAVEFRAME:
+					c := SaveFrameCommand{}
+					c.frame = p.nextFrame(p.frames)
+					if err := p.checkFrame(c.frame, "saveframe"); err != nil {
+						return err
+					}
+					c.filename = p.nextString()
+					command = c
+				case VARY:
+					err := p.vary(scene, func() (Curve, error) {
+						if p.peekNumber() {
+							return NewCubicBezier(p.nextFloat(), p.nextFloat(), p.nextFloat(), p.nextFloat())
+						} else if p.peek().tt == tString {
+							return p.tables.Curve(p.nextString())
+						}
+						return LinearCurve{}, nil
+					})
+					if err != nil {
+						return err
+					}
+				case VARY_SPRING:
+					err := p.vary(scene, func() (Curve, error) {
+						stiffness, damping := DefaultSpringStiffness, DefaultSpringDamping
+						if p.peekNumber() {
+							stiffness = p.nextFloat()
+							if p.peekNumber() {
+								damping = p.nextFloat()
+							}
+						}
+						return NewSpringCurve(stiffness, damping)
+					})
+					if err != nil {
+						return err
+					}
+				case VARY_BOUNCE:
+					err := p.vary(scene, func() (Curve, error) {
+						elasticity := DefaultBounceElasticity
+						if p.peekNumber() {
+							elasticity = p.nextFloat()
+						}
+						return NewBounceCurve(elasticity)
+					})
+					if err != nil {
+						return err
+					}
+				case VARY_DAMP:
+					err := p.vary(scene, func() (Curve, error) {
+						damping := DefaultDamping
+						if p.peekNumber() {
+							damping = p.nextFloat()
+						}
+						return NewDampCurve(damping)
+					})
+					if err != nil {
+						return err
+					}
+				case CURVE:
+					name := p.nextString()
+					if next := p.peek(); next.tt == tIdent && (LookupIdent(next.value) == BEZIER || LookupIdent(next.value) == HERMITE) {
+						kind := LookupIdent(p.nextIdent())
+						points, color := p.nextCurvePoints(4)
+						if color != nil {
+							return fmt.Errorf("curve %s: a 3D curve has no color", name)
+						}
+						if kind == BEZIER {
+							p.tables.paths[name] = geometry.BezierCubic(points[0], points[1], points[2], points[3])
+						} else {
+							p.tables.paths[name] = geometry.HermiteCubic(points[0], points[1], points[2], points[3])
+						}
+						break
+					}
+					var points []float64
+					for p.peekNumber() {
+						points = append(points, p.nextFloat())
+					}
+					curve, err := NewHermiteCurve(points)
+					if err != nil {
+						return fmt.Errorf("curve %s: %s", name, err)
+					}
+					p.tables.curves[name] = curve
+				case AUDIO:
+					if p.frames == 0 {
+						return errors.New("number of frames is not set")
+					}
+					name := p.nextString()
+					filename := p.nextString()
+					var low, high float64
+					if p.peekNumber() {
+						low, high = p.nextFloat(), p.nextFloat()
+					}
+					samples, sampleRate, err := ReadWAV(filename)
+					if err != nil {
+						return err
+					}
+					p.dependencies = append(p.dependencies, filename)
+					if low > 0 || high > 0 {
+						samples = BandPass(samples, sampleRate, low, high)
+					}
+					p.tables.knobs[name] = AmplitudeEnvelope(samples, sampleRate, p.frameRate(), p.frames)
+					// The envelope is sampled at the frame rate, which can't
+					// change afterwards
+					p.timed = true
+					p.isAnimated = true
+				case KNOBDATA:
+					if p.frames == 0 {
+						return errors.New("number of frames is not set")
+					}
+					name := p.nextString()
+					filename := p.nextString()
+					var column string
+					if p.peekNumber() {
+						column = strconv.Itoa(p.nextInt())
+					} else if p.peek().tt == tString {
+						column = p.nextString()
+					}
+					series, err := ReadKnobData(filename, column)
+					if err != nil {
+						return err
+					}
+					p.dependencies = append(p.dependencies, filename)
+					p.tables.knobs[name] = Resample(series, p.frames)
+					p.isAnimated = true
+				case BASENAME:
+					if p.basename != "" {
+						fmt.Fprintln(os.Stderr, "Setting the basename multiple times")
+					}
+					p.basename = p.nextString()
+					p.isAnimated = true
+				case FRAMES:
+					if p.frames != 0 {
+						fmt.Fprintln(os.Stderr, "Setting the number of frames multiple times")
+					}
+					p.frames = p.nextInt()
+					if p.frames <= 0 {
+						return errors.New("number of frames must be greater than zero")
+					}
+					p.isAnimated = true
+				case FPS:
+					// Times in seconds are turned into frames as they are parsed
+					if p.timed {
+						return errors.New("fps must be set before any duration, time in seconds, or audio")
+					}
+					fps := p.nextFloat()
+					if fps <= 0 {
+						return errors.New("fps must be greater than zero")
+					}
+					p.fps = fps
+					if !p.fixedDelay {
+						p.delay = int(math.Round(100 / fps))
+					}
+				case DURATION:
+					if p.frames != 0 {
+						fmt.Fprintln(os.Stderr, "Setting the number of frames multiple times")
+					}
+					seconds := p.nextFloat()
+					p.frames = int(math.Round(seconds * p.frameRate()))
+					if p.frames <= 0 {
+						return errors.New("duration must be at least one frame long")
+					}
+					p.timed = true
+					p.isAnimated = true
+				case SET:
+					name := p.nextString()
+					p.setKnob(name, p.nextFloat(), scene)
+				case SETKNOBS:
+					value := p.nextFloat()
+					for name := range p.tables.knobs {
+						p.setKnob(name, value, scene)
+					}
+					for name := range p.tables.knobValues {
+						p.setKnob(name, value, scene)
+					}
+				case SAVE_KNOBS:
+					name := p.nextString()
+					knobList := make(map[string]float64, len(p.tables.knobValues))
+					for knob, value := range p.tables.knobValues {
+						knobList[knob] = value
+					}
+					p.tables.knobLists[name] = knobList
+				case TWEEN:
+					offset, frames := 0, p.frames
+					if scene != nil {
+						offset, frames = scene.start, scene.frames
+					}
+					if frames == 0 {
+						return errors.New("number of frames is not set")
+					}
+					startFrame := p.nextInt()
+					if startFrame < 0 || startFrame >= frames {
+						return fmt.Errorf("invalid start frame %d for tween", startFrame)
+					}
+					endFrame := p.nextInt()
+					if endFrame < 0 || endFrame >= frames || endFrame < startFrame {
+						return fmt.Errorf("invalid end frame %d for tween", endFrame)
+					}
+					lists := make([]map[string]float64, 2)
+					for i := range lists {
+						name := p.nextString()
+						list, found := p.tables.knobLists[name]
+						if !found {
+							return fmt.Errorf("undefined knob list '%s'", name)
+						}
+						lists[i] = list
+					}
+					// Knobs missing from one of the lists are 0 in it
+					names := make(map[string]bool)
+					for _, list := range lists {
+						for name := range list {
+							names[name] = true
+						}
+					}
+					for name := range names {
+						knob := p.tables.knobs[name]
+						if len(knob) < offset+frames {
+							knob = append(knob, make([]float64, offset+frames-len(knob))...)
+						}
+						start, end := lists[0][name], lists[1][name]
+						for frame := startFrame; frame <= endFrame; frame++ {
+							t := 0.0
+							if endFrame > startFrame {
+								t = float64(frame-startFrame) / float64(endFrame-startFrame)
+							}
+							knob[offset+frame] = start + (end-start)*t
+						}
+						p.tables.knobs[name] = knob
+					}
+					p.isAnimated = true
+				case MESH:
+					c := MeshCommand{ShapeCommand: p.shapeAt(t)}
+					// The filename is marked by a colon, which tells it apart
+					// from the name of the constants before it
+					filename := p.nextString()
+					if !strings.HasPrefix(filename, ":") && strings.HasPrefix(p.peek().value, ":") {
+						if filename != "nil" {
+							c.constants = filename
+						}
+						filename = p.nextString()
+					}
+					c.filename = strings.TrimPrefix(filename, ":")
+					p.dependencies = append(p.dependencies, c.filename)
+					c.cs = p.nextName()
+					c.color = p.nextColor()
+					command = c
+				case GROUP:
+					group := GroupCommand{
+						name: p.nextString(),
+					}
+					group.knob, _ = p.next(tString)
+					blocks = append(blocks, block{
+						name:    "group " + group.name,
+						command: group,
+						parent:  commands,
+						pushes:  p.pushes,
+					})
+					commands = make([]Command, 0, 10)
+					p.pushes = nil
+				case SYMMETRY:
+					c := SymmetryCommand{}
+					c.axis, c.offset = p.nextMirror()
+					blocks = append(blocks, block{
+						name:    "symmetry " + c.axis,
+						command: c,
+						parent:  commands,
+						pushes:  p.pushes,
+					})
+					commands = make([]Command, 0, 10)
+					p.pushes = nil
+				case RENDERTARGET:
+					target := RenderTargetCommand{
+						name:   p.nextString(),
+						width:  p.nextInt(),
+						height: p.nextInt(),
+					}
+					if target.width <= 0 || target.height <= 0 {
+						return fmt.Errorf("render target %s must be at least 1x1", target.name)
+					}
+					blocks = append(blocks, block{
+						name:    "rendertarget " + target.name,
+						command: target,
+						parent:  commands,
+						pushes:  p.pushes,
+					})
+					commands = make([]Command, 0, 10)
+					p.pushes = nil
+				case USETEXTURE:
+					c := UseTextureCommand{}
+					if name := p.nextString(); name != "off" {
+						c.name = name
+					}
+					command = c
+				case PICK:
+					command = PickCommand{x: p.nextInt(), y: p.nextInt()}
+					p.picking = true
+				case AXES:
+					c := AxesCommand{ShapeCommand: p.shapeAt(t)}
+					if c.length = p.nextFloat(); c.length <= 0 {
+						return errors.New("axes length must be positive")
+					}
+					c.cs = p.nextName()
+					command = c
+				case GRID:
+					c := GridCommand{ShapeCommand: p.shapeAt(t)}
+					c.size = p.nextFloat()
+					c.spacing = p.nextFloat()
+					if c.size <= 0 || c.spacing <= 0 {
+						return errors.New("grid size and spacing must be positive")
+					}
+					c.cs = p.nextName()
+					command = c
+				case DEBUG:
+					overlay, err := render.ParseDebugOverlay(p.nextString())
+					if err != nil {
+						return err
+					}
+					switch state := p.nextString(); state {
+					case "on":
+						command = DebugCommand{overlay: overlay, on: true}
+					case "off":
+						command = DebugCommand{overlay: overlay, on: false}
+					default:
+						return fmt.Errorf("invalid debug setting '%s'", state)
+					}
+				case OBJECT:
+					name := p.nextString()
+					if _, found := p.tables.objects[name]; found {
+						return fmt.Errorf("object %s is already defined", name)
+					}
+					blocks = append(blocks, block{
+						name:    "object " + name,
+						command: ObjectCommand{name: name},
+						parent:  commands,
+						pushes:  p.pushes,
+					})
+					commands = make([]Command, 0, 10)
+					p.pushes = nil
+				case INSTANCE:
+					command = InstanceCommand{
+						name: p.nextString(),
+					}
+				case SCENE:
+					if scene != nil {
+						return fmt.Errorf("scene %s is inside scene %s", p.peek().value, scene.name)
+					}
+					c := SceneCommand{
+						name:   p.nextString(),
+						start:  p.sceneEnd,
+						frames: p.nextInt(),
+					}
+					if c.frames <= 0 {
+						return fmt.Errorf("scene %s must have at least one frame", c.name)
+					}
+					p.sceneEnd += c.frames
+					if p.frames < p.sceneEnd {
+						p.frames = p.sceneEnd
+					}
+					p.isAnimated = true
+					scene = &c
+					blocks = append(blocks, block{
+						name:   "scene " + c.name,
+						parent: commands,
+						pushes: p.pushes,
+					})
+					commands = make([]Command, 0, 10)
+					p.pushes = nil
+				case END:
+					last := len(blocks) - 1
+					if last < 0 {
+						return errors.New("end without a matching block")
+					}
+					b := blocks[last]
+					switch c := b.command.(type) {
+					case GroupCommand:
+						c.commands = commands
+						command = c
+					case RenderTargetCommand:
+						c.commands = commands
+						command = c
+					case SymmetryCommand:
+						c.commands = commands
+						command = c
+					case ObjectCommand:
+						p.tables.objects[c.name] = commands
+					case nil:
+						// Scenes are the only blocks that cannot be nested
+						scene.commands = commands
+						command = *scene
+						scene = nil
+					}
+					// A push left over would leak the block's transformations
+					// out of it
+					if len(p.pushes) > 0 {
+						errs = append(errs, fmt.Errorf("%s: push is never popped before the end of %s", p.pushes[len(p.pushes)-1], b.name))
+					}
+					commands = b.parent
+					p.pushes = b.pushes
+					blocks = blocks[:last]
+				case HIDE:
+					command = HideCommand{
+						group: p.nextString(),
+					}
+				case SHOW:
+					command = ShowCommand{
+						group: p.nextString(),
+					}
+				case SNAPSHOT:
+					command = SnapshotCommand{
+						name: p.nextString(),
+					}
+				case RESTORE:
+					command = RestoreCommand{
+						name: p.nextString(),
+					}
+				case LAYER:
+					c := LayerCommand{
+						name:    p.nextString(),
+						opacity: 1,
+					}
+					if mode, err := p.next(tString); err == nil {
+						c.mode, err = image.ParseBlendMode(mode)
+						if err != nil {
+							return err
+						}
+					}
+					if p.peekNumber() {
+						c.opacity = p.nextFloat()
+					}
+					command = c
+				case CLIP:
+					c := ClipCommand{}
+					switch kind := p.nextString(); kind {
+					case "plane":
+						c.plane = []float64{p.nextFloat(), p.nextFloat(), p.nextFloat(), p.nextFloat()}
+					case "clear":
+					default:
+						return fmt.Errorf("unknown clip type '%s'", kind)
+					}
+					command = c
+				case LOD:
+					c := LevelOfDetailCommand{}
+					if value, err := p.next(tString); err == nil {
+						if value != "off" {
+							return fmt.Errorf("invalid level of detail '%s'", value)
+						}
+					} else {
+						c.pixels = p.nextFloat()
+					}
+					command = c
+				case QUALITY:
+					c := QualityCommand{
+						segments: p.nextInt(),
+					}
+					if c.segments < render.MinCircularSteps {
+						return fmt.Errorf("quality must be at least %d segments", render.MinCircularSteps)
+					}
+					command = c
+				case STAMP:
+					c := StampCommand{corner: image.CornerTopRight, scale: 1}
+					if p.peek().tt == tString && !p.peekNumber() {
+						corner, err := image.ParseCorner(p.nextString())
+						if err != nil {
+							return err
+						}
+						c.corner = corner
+					}
+					if p.peekNumber() {
+						c.scale = p.nextInt()
+						if c.scale < 1 {
+							return errors.New("stamp scale must be at least 1")
+						}
+					}
+					command = c
+				case DISPLACE:
+					c := DisplaceCommand{}
+					if p.peek().tt == tString && !p.peekNumber() {
+						if state := p.nextString(); state != "off" {
+							return fmt.Errorf("invalid displace setting '%s'", state)
+						}
+					} else {
+						c.size = p.nextFloat()
+						c.amount = p.nextFloat()
+						if c.size <= 0 {
+							return errors.New("displacement size must be greater than zero")
+						}
+					}
+					command = c
+				case ENVMAP:
+					c := EnvironmentMapCommand{}
+					if filename := p.nextString(); filename != "off" {
+						c.filename = filename
+						p.dependencies = append(p.dependencies, filename)
+					}
+					command = c
+				case SSAO:
+					c := AmbientOcclusionCommand{}
+					if p.peek().tt == tString && !p.peekNumber() {
+						if state := p.nextString(); state != "off" {
+							return fmt.Errorf("invalid ssao setting '%s'", state)
+						}
+					} else {
+						c.radius = p.nextFloat()
+						c.strength = p.nextFloat()
+						if c.radius <= 0 {
+							return errors.New("ssao radius must be greater than zero")
+						}
+						if c.strength < 0 {
+							return errors.New("ssao strength must not be negative")
+						}
+					}
+					command = c
+				case LINEWIDTH:
+					c := LineWidthCommand{
+						width: p.nextFloat(),
+					}
+					if c.width <= 0 {
+						return errors.New("line width must be greater than zero")
+					}
+					command = c
+				case RENDERMODE:
+					mode, err := render.ParseRenderMode(p.nextString())
+					if err != nil {
+						return err
+					}
+					command = RenderModeCommand{mode: mode}
+				case CULLING:
+					mode, err := geometry.ParseCullMode(p.nextString())
+					if err != nil {
+						return err
+					}
+					command = CullingCommand{mode: mode}
+				case WINDING:
+					winding, err := geometry.ParseWinding(p.nextString())
+					if err != nil {
+						return err
+					}
+					command = WindingCommand{winding: winding}
+				case LET:
+					name := p.nextString()
+					if equals := p.nextString(); equals != "=" {
+						return fmt.Errorf("expected '=' after let %s, got '%s'", name, equals)
+					}
+					p.tables.variables.Set(name, p.nextFloat())
+				case DEFINE:
+					name := p.nextString()
+					m := macro{}
+					for p.peek().tt == tString {
+						m.params = append(m.params, p.nextString())
+					}
+					body, err := p.macroBody(name)
+					if err != nil {
+						return err
+					}
+					// The body is read first, so that it is skipped either way
+					if _, found := p.macros[name]; found {
+						return fmt.Errorf("macro %s is already defined", name)
+					}
+					m.body = body
+					p.macros[name] = m
+				case CALL:
+					name := p.nextString()
+					m, found := p.macros[name]
+					if !found {
+						return fmt.Errorf("undefined macro '%s'", name)
+					}
+					if p.macroDepth >= MaxMacroDepth {
+						return fmt.Errorf("macros are nested more than %d deep while calling %s", MaxMacroDepth, name)
+					}
+					// Arguments are evaluated before the parameters are bound, so
+					// they can refer to variables of the same name
+					args := make([]float64, len(m.params))
+					for i := range args {
+						if !p.peekNumber() {
+							return fmt.Errorf("macro %s takes %d arguments", name, len(m.params))
+						}
+						args[i] = p.nextFloat()
+					}
+					end := p.nextToken()
+					if end.tt != tNewline && end.tt != tEOF {
+						return fmt.Errorf("macro %s takes %d arguments", name, len(m.params))
+					}
+					p.tables.variables.Push()
+					for i, param := range m.params {
+						p.tables.variables.Set(param, args[i])
+					}
+					p.macroDepth++
+					// Parse the body next, followed by whatever came after the call
+					p.unread(end)
+					p.unread(Token{tt: tMacroEnd})
+					for i := len(m.body) - 1; i >= 0; i-- {
+						p.unread(m.body[i])
+					}
+					p.unread(Token{tt: tNewline})
+				case RESOLUTION:
+					width, height := p.nextInt(), p.nextInt()
+					if width <= 0 || height <= 0 {
+						return errors.New("resolution must be greater than zero")
+					}
+					if !p.fixedWidth {
+						p.width = width
+					}
+					if !p.fixedHeight {
+						p.height = height
+					}
+				case SHADOWS:
+					switch state := p.nextString(); state {
+					case "on":
+						p.tables.shadowSize = image.DefaultShadowSize
+						if p.peekNumber() {
+							p.tables.shadowSize = p.nextInt()
+						}
+						if p.tables.shadowSize <= 1 {
+							return errors.New("shadow map resolution must be greater than one")
+						}
+					case "off":
+						p.tables.shadowSize = 0
+					default:
+						return fmt.Errorf("invalid shadow setting '%s'", state)
+					}
+				case INCLUDE:
+					filename := p.nextString()
+					end := p.nextToken()
+					if end.tt != tNewline && end.tt != tEOF {
+						return fmt.Errorf("unexpected %v at end of statement", end)
+					}
+					tokens, err := p.include(filename)
+					if err != nil {
+						p.unread(end)
+						return err
+					}
+					// Parse the included script next, followed by whatever came
+					// after the include
+					p.unread(end)
+					p.unread(Token{tt: tIncludeEnd})
+					for i := len(tokens) - 1; i >= 0; i-- {
+						p.unread(tokens[i])
+					}
+					p.unread(Token{tt: tNewline})
+				case SHADING:
+					mode, err := image.ParseShadingMode(p.nextString())
+					if err != nil {
+						return err
+					}
+					command = ShadingCommand{mode: mode}
+				case EXPOSURE:
+					c := ExposureCommand{exposure: p.nextFloat()}
+					if c.exposure <= 0 {
+						return errors.New("exposure must be positive")
+					}
+					c.knob = p.nextName()
+					command = c
+				case TONEMAP:
+					toneMap, err := image.ParseToneMap(p.nextString())
+					if err != nil {
+						return err
+					}
+					command = ToneMapCommand{toneMap: toneMap}
+				case GAMMA:
+					switch state := p.nextString(); state {
+					case "on":
+						command = GammaCommand{linear: true}
+					case "off":
+						command = GammaCommand{linear: false}
+					default:
+						return fmt.Errorf("invalid gamma setting '%s'", state)
+					}
+				case VIEWPORT:
+					if p.peekNumber() {
+						c := ViewportRectCommand{rect: geometry.Rect{
+							X: p.nextFloat(),
+							Y: p.nextFloat(),
+							W: p.nextFloat(),
+							H: p.nextFloat(),
+						}}
+						if c.rect.W <= 0 || c.rect.H <= 0 {
+							return errors.New("viewport width and height must be positive")
+						}
+						command = c
+						break
+					}
+					origin, err := geometry.ParseOrigin(p.nextString())
+					if err != nil {
+						return err
+					}
+					c := ViewportCommand{viewport: geometry.Viewport{Origin: origin}}
+					if direction, err := p.next(tString); err == nil {
+						switch direction {
+						case "up":
+						case "down":
+							c.viewport.YDown = true
+						default:
+							return fmt.Errorf("invalid y direction '%s'", direction)
+						}
+					}
+					command = c
+				case PROJECTION:
+					switch kind := p.nextString(); kind {
+					case "ortho":
+						command = ProjectionCommand{}
+					case "perspective":
+						c := ProjectionCommand{projection: geometry.Projection{
+							Perspective: true,
+							FOV:         p.nextFloat(),
+							Near:        1,
+							Far:         math.Inf(1),
+						}}
+						if c.projection.FOV <= 0 || c.projection.FOV >= 180 {
+							return errors.New("field of view must be between 0 and 180 degrees")
+						}
+						if p.peekNumber() {
+							c.projection.Near = p.nextFloat()
+							c.projection.Far = p.nextFloat()
+						}
+						if c.projection.Near <= 0 || c.projection.Far <= c.projection.Near {
+							return errors.New("projection must have 0 < near < far")
+						}
+						command = c
+					default:
+						return fmt.Errorf("invalid projection '%s', expected ortho or perspective", kind)
+					}
+				case CAMERA:
+					c := CameraCommand{}
+					for i := range c.eye {
+						c.eye[i] = p.nextFloat()
+						c.knobs[i] = p.nextName()
+					}
+					for i := range c.aim {
+						c.aim[i] = p.nextFloat()
+						c.knobs[3+i] = p.nextName()
+					}
+					command = c
+				case CAMERAPATH:
+					name := p.nextString()
+					path, found := p.tables.paths[name]
+					if !found {
+						return fmt.Errorf("undefined 3D curve '%s'", name)
+					}
+					command = CameraPathCommand{path: path, knob: p.nextName()}
+				case ZEPSILON:
+					command = DepthEpsilonCommand{
+						epsilon: p.nextFloat(),
+					}
+				case ZOFFSET:
+					command = DepthOffsetCommand{
+						offset: p.nextFloat(),
+					}
+				case ZCLIP:
+					c := DepthClipCommand{near: p.nextFloat(), far: p.nextFloat()}
+					if c.near <= c.far {
+						return errors.New("zclip: the near depth must be greater than the far depth")
+					}
+					command = c
+				case LIGHT:
+					name := p.nextString()
+					_, found := p.tables.lightSources[name]
+					if found {
+						return fmt.Errorf("light %s is already defined", name)
+					}
+					lightSource := image.LightSource{
+						Color: p.nextRGB(),
+					}
+					lightSource.ColorKnob = p.nextName()
+					lightSource.LocationKnobs = make([]string, 3)
+					for i := range lightSource.Location {
+						lightSource.Location[i] = p.nextFloat()
+						lightSource.LocationKnobs[i] = p.nextName()
+					}
+					p.tables.lightSources[name] = lightSource
+				case AMBIENT:
+					p.tables.ambient = geometry.Vec3{p.nextFloat(), p.nextFloat(), p.nextFloat()}
+				case BACKGROUND:
+					p.tables.background = p.nextRGB()
+				case SKYBOX:
+					p.tables.sky = render.Sky{Filename: p.nextString()}
+					p.dependencies = append(p.dependencies, p.tables.sky.Filename)
+				case SKYGRADIENT:
+					top := p.nextRGB()
+					bottom := p.nextRGB()
+					p.tables.sky = render.Sky{Top: top, Bottom: bottom}
+				case CLEAR:
+					command = ClearCommand{}
+				case COLOR:
+					c := ColorCommand{}
+					if p.peekNumber() {
+						c.color = p.nextRGB()
+						if p.peekNumber() {
+							c.color.A = p.nextChannel()
+						}
+					} else {
+						hex := p.nextString()
+						var err error
+						if c.color, err = image.ParseHexColor(hex); err != nil {
+							return err
+						}
+					}
+					command = c
+				case CONSTANTS:
+					name := p.nextString()
+					kar, kdr, ksr, kag, kdg, ksg, kab, kdb, ksb := p.nextFloat(), p.nextFloat(), p.nextFloat(), p.nextFloat(), p.nextFloat(), p.nextFloat(), p.nextFloat(), p.nextFloat(), p.nextFloat()
+					constant := image.Material{
+						Ambient:   geometry.Vec3{kar, kag, kab},
+						Diffuse:   geometry.Vec3{kdr, kdg, kdb},
+						Specular:  geometry.Vec3{ksr, ksg, ksb},
+						Shininess: image.DefaultShininess,
+						Opacity:   1,
+					}
+					if p.peekNumber() {
+						constant.Intensity = geometry.Vec3{p.nextFloat(), p.nextFloat(), p.nextFloat()}
+					}
+					if p.peekNumber() {
+						constant.Shininess = p.nextFloat()
+						if constant.Shininess <= 0 {
+							return errors.New("shininess must be positive")
+						}
+					}
+					if p.peekNumber() {
+						constant.Emissive = geometry.Vec3{p.nextFloat(), p.nextFloat(), p.nextFloat()}
+					}
+					if p.peekNumber() {
+						constant.Opacity = p.nextFloat()
+						if constant.Opacity < 0 || constant.Opacity > 1 {
+							return errors.New("opacity must be between 0 and 1")
+						}
+					}
+					if p.peekNumber() {
+						constant.Reflectivity = p.nextFloat()
+						if constant.Reflectivity < 0 || constant.Reflectivity > 1 {
+							return errors.New("reflectivity must be between 0 and 1")
+						}
+					}
+					for next := p.peek(); next.tt == tString; next = p.peek() {
+						if next.value == "normalmap" {
+							p.nextToken()
+							constant.NormalMapFile = p.nextString()
+							p.dependencies = append(p.dependencies, constant.NormalMapFile)
+							if p.peekNumber() {
+								constant.NormalMapSize = p.nextFloat()
+								if constant.NormalMapSize <= 0 {
+									return errors.New("normal map size must be positive")
+								}
+							}
+						} else if next.value == "texture" {
+							p.nextToken()
+							kind, err := image.ParseTextureKind(p.nextString())
+							if err != nil {
+								return err
+							}
+							texture := &image.Texture{Kind: kind, Size: image.DefaultTextureSize, Tint: geometry.Vec3{image.DefaultTextureTint, image.DefaultTextureTint, image.DefaultTextureTint}}
+							if p.peekNumber() {
+								texture.Size = p.nextFloat()
+								if texture.Size <= 0 {
+									return errors.New("texture size must be positive")
+								}
+							}
+							if p.peekNumber() {
+								texture.Tint = geometry.Vec3{p.nextFloat(), p.nextFloat(), p.nextFloat()}
+							}
+							constant.Texture = texture
+						} else {
+							break
+						}
+					}
+					p.tables.constants[name] = constant
+				}
+				if command != nil {
+					commands = append(commands, command)
+				}
+				next := p.nextToken()
+				if next.tt != tNewline && next.tt != tEOF {
+					return fmt.Errorf("unexpected %v at end of statement", next)
+				}
+				return nil
+			})
+		case tString:
+			err = fmt.Errorf("unrecognized identifier: \"%s\"", t.value)
+		}
+		if err != nil {
+			// Carry on with the next statement, so that every error in the
+			// script is found at once
+			errs = append(errs, p.locate(t, err))
+			p.skipStatement()
+		}
+	}
+}
+
+// ParseErrors is every error found while parsing a script, in order
+type ParseErrors []error
+
+func (e ParseErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "\n")
+}
+
+// recovering parses a statement, returning the errors that nextRequired panics
+// with instead of crashing
+func (p *Parser) recovering(parse func() error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			e, isError := r.(error)
+			if _, isRuntime := r.(runtime.Error); !isError || isRuntime {
+				panic(r)
+			}
+			err = e
+		}
+	}()
+	return parse()
+}
+
+// locate adds where t is in the script to an error in the statement it starts
+func (p *Parser) locate(t Token, err error) error {
+	return fmt.Errorf("%s: %v", p.position(t), err)
+}
+
+// position returns where t is in the script
+func (p *Parser) position(t Token) string {
+	if len(p.includes) > 0 {
+		return fmt.Sprintf("%s: line %d", p.includeStack(), t.line)
+	}
+	return fmt.Sprintf("line %d", t.line)
+}
+
+// shapeAt returns the options of a shape whose statement starts with t, which
+// only know where it is until the rest is parsed
+func (p *Parser) shapeAt(t Token) ShapeCommand {
+	return ShapeCommand{where: p.position(t)}
+}
+
+// skipStatement skips the rest of a statement with an error in it, up to the
+// start of the next one
+func (p *Parser) skipStatement() {
+	for {
+		switch t := p.nextToken(); t.tt {
+		case tNewline:
+			return
+		case tEOF, tMacroEnd, tIncludeEnd:
+			p.unread(t)
+			return
+		}
+	}
+}
+
+// SetVideo makes animations stream their frames to ffmpeg, encoding them into
+// filename instead of writing individual frames to disk
+func (p *Parser) SetVideo(filename string) {
+	p.video = filename
+}
+
+// SetLineWidth sets the width in pixels of lines and wireframes
+func (p *Parser) SetLineWidth(width float64) {
+	p.lineWidth = width
+}
+
+// SetSnapLines sets whether the ends of lines and wireframes are snapped to
+// whole pixels instead of being drawn with sub-pixel accuracy
+func (p *Parser) SetSnapLines(snap bool) {
+	p.snapLines = snap
+}
+
+// SetDither sets how saved images are dithered when they are reduced to the
+// given number of bits per channel or to a palette-limited format
+func (p *Parser) SetDither(mode image.DitherMode, bits int) {
+	p.dither = mode
+	p.bits = bits
+}
+
+// SetStats sets whether the frame number, triangle count, and render time are
+// stamped onto every saved image
+func (p *Parser) SetStats(stats bool) {
+	p.stats = stats
+}
+
+// SetLayout sets how every frame is drawn in panes, such as the quad view or
+// stereo views
+func (p *Parser) SetLayout(layout render.Layout) {
+	p.layout = layout
+}
+
+// SetPasses sets whether the depth, normal, and shape ID passes are saved
+// alongside every image
+func (p *Parser) SetPasses(passes bool) {
+	p.passes = passes
+}
+
+// SetDeterministic sets whether every render of the script comes out the same
+// bit for bit, for golden images and reproducible renders
+func (p *Parser) SetDeterministic(deterministic bool) {
+	p.deterministic = deterministic
+}
+
+// SetSwap sets the directory that the pixels of rendered images are kept in
+// instead of memory, or "" to keep them in memory
+func (p *Parser) SetSwap(dir string) {
+	p.swap = dir
+}
+
+// newDrawer returns a drawer configured with the parser's options
+func (p *Parser) newDrawer() (*render.Drawer, error) {
+	var drawer *render.Drawer
+	if p.swap != "" {
+		// Only the base layer is kept in a file: other layers, render targets,
+		// and copies made for compositing, stamping, or dithering are still
+		// kept in memory
+		img, err := image.NewSwappedImage(p.swap, p.height, p.width)
+		if err != nil {
+			return nil, err
+		}
+		drawer = render.NewDrawerOn(img)
+	} else {
+		drawer = render.NewDrawer(p.height, p.width)
+	}
+	drawer.SetDefaultLineWidth(p.lineWidth)
+	drawer.SetSnapLines(p.snapLines)
+	drawer.SetDither(p.dither, p.bits)
+	drawer.SetStats(p.stats)
+	drawer.SetLayout(p.layout)
+	drawer.SetPasses(p.passes)
+	drawer.SetPicking(p.picking)
+	drawer.SetDeterministic(p.deterministic)
+	drawer.SetPreview(p.preview)
+	// Animation frames are assembled into a gif
+	drawer.SetPaletted(p.isAnimated && p.video == "")
+	return drawer, nil
+}
+
+// SetResolution sets the size of rendered images in pixels, overriding any
+// resolution set by the script
+// A width or height of 0 is left for the script to set.
+func (p *Parser) SetResolution(width, height int) {
+	if width > 0 {
+		p.width = width
+		p.fixedWidth = true
+	}
+	if height > 0 {
+		p.height = height
+		p.fixedHeight = true
+	}
+}
+
+// SetGIF sets the delay between the frames of animated gifs in hundredths of a
+// second and the number of times they repeat after playing once (0 repeats
+// forever, -1 plays them once)
+// A negative delay is left for the script's fps to set.
+func (p *Parser) SetGIF(delay, loop int) {
+	if delay >= 0 {
+		p.delay = delay
+		p.fixedDelay = true
+	}
+	p.loop = loop
+}
+
+// SetResume makes animations keep the frames rendered by a previous,
+// interrupted run of the same script and only render the remaining frames
+func (p *Parser) SetResume(resume bool) {
+	p.resume = resume
+}
+
+// SetContext makes rendering stop once ctx is cancelled
+// Animations keep the frames that were finished, so they can be resumed.
+func (p *Parser) SetContext(ctx context.Context) {
+	p.ctx = ctx
+}
+
+// SetCheck makes the parser check scripts for problems and report them instead
+// of rendering them
+func (p *Parser) SetCheck(check bool) {
+	p.checkOnly = check
+}
+
+// SetDumpKnobs makes the parser print the value of every knob in each frame of
+// scripts as CSV instead of rendering them
+func (p *Parser) SetDumpKnobs(dump bool) {
+	p.dumpKnobs = dump
+}
+
+// SetControl makes the parser render a live preview that is controlled over a
+// unix socket instead of rendering the script once
+func (p *Parser) SetControl(socket string) {
+	p.control = socket
+}
+
+// SetMIDI makes the parser render a live preview whose knobs are driven by
+// control changes read from a raw MIDI device
+func (p *Parser) SetMIDI(device string, mapping map[byte]KnobRange) {
+	p.midi = device
+	p.midiMap = mapping
+}
+
+// SetOSC makes the parser render a live preview whose knobs are driven by OSC
+// messages received on a UDP address
+func (p *Parser) SetOSC(address string) {
+	p.osc = address
+}
+
+// SetTuner makes the parser render a live preview whose knobs are driven by
+// sliders on a web page served on address
+func (p *Parser) SetTuner(address string) {
+	p.tuner = address
+}
+
+// SetPreview makes the parser show each frame in preview as it is rendered
+func (p *Parser) SetPreview(preview *render.Preview) {
+	p.preview = preview
+}
+
+// isLive returns whether the script should be rendered as a live preview
+func (p *Parser) isLive() bool {
+	return p.control != "" || p.midi != "" || p.osc != "" || p.tuner != ""
+}
+
+func (p *Parser) serve(commands []Command) error {
+	if !p.isAnimated {
+		p.frames = 1
+	}
+	drawer, err := p.newDrawer()
+	if err != nil {
+		return err
+	}
+	if p.tuner != "" && drawer.Preview() == nil {
+		drawer.SetPreview(render.NewPreview())
+	}
+	server := NewControlServer(drawer, p.tables, commands, p.frames, DefaultPreview)
+	if err := server.Start(); err != nil {
+		return err
+	}
+
+	errs := make(chan error)
+	if p.control != "" {
+		go func() { errs <- server.ListenUnix(p.control) }()
+	}
+	if p.midi != "" {
+		go func() { errs <- server.ListenMIDI(p.midi, p.midiMap) }()
+	}
+	if p.osc != "" {
+		go func() { errs <- server.ListenOSC(p.osc) }()
+	}
+	if p.tuner != "" {
+		go func() { errs <- server.ListenTuner(p.tuner) }()
+	}
+	if p.midi != "" || p.osc != "" || p.tuner != "" {
+		go server.RenderContinuously()
+	}
+	select {
+	case err := <-errs:
+		return err
+	case <-p.ctx.Done():
+		return p.ctx.Err()
+	}
+}
+
+func (p *Parser) process(ctx context.Context, commands []Command) error {
+	// Still images only need a single worker
+	drawers := make([]*render.Drawer, MaxWorkers)
+	if !p.isAnimated {
+		drawers = drawers[:1]
+	}
+	for i := range drawers {
+		drawer, err := p.newDrawer()
+		if err != nil {
+			return err
+		}
+		drawers[i] = drawer
+	}
+
+	var encoder *render.VideoEncoder
+	var checkpoint *Checkpoint
+	var err error
+	if p.isAnimated && p.video != "" {
+		encoder, err = render.NewVideoEncoder(p.video, p.height, p.width, p.frameRate())
+		if err != nil {
+			return err
+		}
+	} else if p.isAnimated {
+		if p.resume {
+			os.Mkdir(FramesDirectory, 0755)
+			checkpoint, err = LoadCheckpoint(checkpointPath(), p.checkpointHash(), p.checkpointSettings(), p.frames)
+			if err == nil {
+				checkpoint.Verify(func(frame int) string {
+					return fmt.Sprintf(p.tables.formatString, frame)
+				})
+				fmt.Printf("Resuming with %d of %d frames left\n", checkpoint.Remaining(), p.frames)
+			}
+		} else {
+			os.RemoveAll(FramesDirectory)
+			os.Mkdir(FramesDirectory, 0755)
+			checkpoint, err = NewCheckpoint(checkpointPath(), p.checkpointHash(), p.checkpointSettings(), p.frames)
+		}
+		if err != nil {
+			return err
+		}
+	} else {
+		p.frames = 1
+		drawers[0].SetCheckpoint(p.stillCheckpoint())
+		if p.resume {
+			shapes, err := drawers[0].Resume()
+			if err != nil {
+				return err
+			}
+			if shapes > 0 {
+				fmt.Printf("Resuming with the first %d shapes already drawn\n", shapes)
+			} else {
+				fmt.Println("Nothing to resume: starting over")
+			}
+		}
+	}
+
+	for _, command := range commands {
+		if c, isScene := command.(SceneCommand); isScene {
+			fmt.Printf("Scene %s: frames %d to %d\n", c.name, c.start, c.start+c.frames-1)
+		}
+	}
+
+	// Failed frames are reported as they happen, and rendering carries on,
+	// except into a video: its frames are encoded in order, so every frame
+	// after a failed one would be held in memory until the render ends
+	// Frames that were already started are still finished.
+	dispatching, stop := context.WithCancel(ctx)
+	defer stop()
+	errs := make(chan error)
+	var failed []error
+	collected := make(chan struct{})
+	go func() {
+		for err := range errs {
+			if p.isAnimated {
+				fmt.Fprintln(os.Stderr, "Error:", err)
+			}
+			failed = append(failed, err)
+			if encoder != nil {
+				stop()
+			}
+		}
+		close(collected)
+	}()
+
+	var wg sync.WaitGroup
+	// Videos don't queue frames ahead of the workers, so that none are left
+	// waiting once a frame fails
+	queued := 100
+	if encoder != nil {
+		queued = 0
+	}
+	jobs := make(chan Job, queued)
+	for _, drawer := range drawers {
+		wg.Add(1)
+		go worker(ctx, drawer, p.tables, commands, jobs, encoder, checkpoint, errs, &wg)
+	}
+
+dispatch:
+	for frame := 0; frame < p.frames; frame++ {
+		if checkpoint != nil && checkpoint.Done(frame) {
+			continue
+		}
+		select {
+		case jobs <- Job{animated: p.isAnimated, frame: frame}:
+		case <-dispatching.Done():
+			break dispatch
+		}
+	}
+
+	close(jobs)
+	wg.Wait()
+	close(errs)
+	<-collected
+	if ctx.Err() != nil {
+		return p.interrupted(ctx, encoder, checkpoint)
+	}
+	if len(failed) > 0 {
+		return p.failed(failed, encoder, checkpoint)
+	}
+	if encoder != nil {
+		fmt.Println("Encoding video...")
+		err = encoder.Close()
+	} else if p.isAnimated {
+		fmt.Println("Making animation...")
+		err = image.MakeAnimation(p.basename, p.tables.formatString, p.frames, p.delay, p.loop)
+	} else {
+		// The image is finished, so there is nothing left to resume
+		os.Remove(p.stillCheckpoint().Path)
+	}
+	return err
+}
+
+// interrupted finishes an animation whose rendering was stopped by ctx,
+// reporting how far it got
+func (p *Parser) interrupted(ctx context.Context, encoder *render.VideoEncoder, checkpoint *Checkpoint) error {
+	switch {
+	case encoder != nil:
+		fmt.Printf("Interrupted: encoding the first %d of %d frames\n", encoder.Written(), p.frames)
+		encoder.Close()
+	case checkpoint != nil:
+		fmt.Printf("Interrupted with %d of %d frames rendered: run again with -resume to finish\n", p.frames-checkpoint.Remaining(), p.frames)
+	default:
+		if _, err := os.Stat(p.stillCheckpoint().Path); err == nil {
+			fmt.Println("Interrupted before the image was saved: run again with -resume to carry on from the last checkpoint")
+		} else {
+			fmt.Println("Interrupted before the image was saved")
+		}
+	}
+	return ctx.Err()
+}
+
+// failed finishes an animation some of whose frames failed, returning an
+// error that summarizes the failures
+// Still images return the error that stopped them, and videos stop at the
+// first frame that fails.
+func (p *Parser) failed(errs []error, encoder *render.VideoEncoder, checkpoint *Checkpoint) error {
+	if !p.isAnimated {
+		return errs[0]
+	}
+	if encoder != nil {
+		encoder.Close()
+		return fmt.Errorf("rendering stopped at the first failed frame: the video only has the first %d of %d frames", encoder.Written(), p.frames)
+	}
+	if checkpoint != nil {
+		return fmt.Errorf("%d of %d frames failed: run again with -resume to retry only those", len(errs), p.frames)
+	}
+	return fmt.Errorf("%d of %d frames failed", len(errs), p.frames)
+}
+
+// drawFrame renders a frame of the script, once in each pane if the drawer
+// draws frames in panes
+func drawFrame(ctx context.Context, drawer *render.Drawer, tables *SymbolTables, commands []Command, frame int) error {
+	drawer.SetBackground(tables.background)
+	if err := drawer.SetSky(tables.sky); err != nil {
+		return err
+	}
+	panes := drawer.Panes()
+	if panes == 0 {
+		return drawScene(ctx, drawer, tables, commands, frame)
+	}
+	for i := 0; i < panes; i++ {
+		if i > 0 {
+			drawer.Reset()
+		}
+		drawer.BeginPane(i)
+		err := drawScene(ctx, drawer, tables, commands, frame)
+		drawer.EndPane()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// drawScene renders the script once, first rendering the shadow maps of the
+// lights if objects cast shadows
+func drawScene(ctx context.Context, drawer *render.Drawer, tables *SymbolTables, commands []Command, frame int) error {
+	if tables.shadowSize == 0 {
+		drawer.ClearShadows()
+		return renderFrame(ctx, drawer, tables, commands, frame)
+	}
+	lights, err := tables.Lights(frame)
+	if err != nil {
+		return err
+	}
+	drawer.BeginShadowPass(lights, tables.shadowSize)
+	err = renderFrame(ctx, drawer, tables, commands, frame)
+	drawer.EndShadowPass()
+	if err != nil {
+		return err
+	}
+	// Start the frame over, keeping the shadow maps
+	drawer.Reset()
+	return renderFrame(ctx, drawer, tables, commands, frame)
+}
+
+func renderFrame(ctx context.Context, drawer *render.Drawer, tables *SymbolTables, commands []Command, frame int) error {
+	lights, err := tables.Lights(frame)
+	if err != nil {
+		return err
+	}
+	drawer.SetSurfaceCoordinates(tables.SurfaceMapped())
+	for _, command := range commands {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if shape, isShape := command.(interface{ source(string) string }); isShape {
+			drawer.SetSource(shape.source(command.Name()))
+		}
+		switch command.(type) {
+		case MoveCommand:
+			c := command.(MoveCommand)
+			x, y, z := c.args[0], c.args[1], c.args[2]
+			if c.knob != "" {
+				if knob, err := tables.Knob(c.knob, frame); err == nil {
+					x *= knob
+					y *= knob
+					z *= knob
+				} else {
+					return err
+				}
+			}
+			err = drawer.Move(x, y, z)
+		case ScaleCommand:
+			c := command.(ScaleCommand)
+			x, y, z := c.args[0], c.args[1], c.args[2]
+			if c.knob != "" {
+				if knob, err := tables.Knob(c.knob, frame); err == nil {
+					x *= knob
+					y *= knob
+					z *= knob
+				} else {
+					return err
+				}
+			}
+			err = drawer.AboutPivot(c.pivot, func() error {
+				return drawer.Scale(x, y, z)
+			})
+		case RotateCommand:
+			c := command.(RotateCommand)
+			degrees := c.degrees
+			if c.knob != "" {
+				if knob, err := tables.Knob(c.knob, frame); err == nil {
+					degrees *= knob
+				} else {
+					return err
+				}
+			}
+			err = drawer.AboutPivot(c.pivot, func() error {
+				if c.vector != nil {
+					return drawer.RotateAbout(c.vector[0], c.vector[1], c.vector[2], degrees)
+				}
+				return drawer.Rotate(c.axis, degrees)
+			})
+		case RotateQuaternionCommand:
+			c := command.(RotateQuaternionCommand)
+			q := c.keys[0]
+			if c.knob != "" {
+				if knob, err := tables.Knob(c.knob, frame); err == nil {
+					q = geometry.Keyframe(c.keys, knob)
+				} else {
+					return err
+				}
+			}
+			err = drawer.AboutPivot(c.pivot, func() error {
+				drawer.RotateBy(q)
+				return nil
+			})
+		case MatrixCommand:
+			err = drawer.Transform(command.(MatrixCommand).matrix)
+		case MirrorCommand:
+			c := command.(MirrorCommand)
+			err = drawer.Mirror(c.axis, c.offset)
+		case LineCommand:
+			c := command.(LineCommand)
+			err = drawer.LineBetween(c.p1[0], c.p1[1], c.p1[2], c.cs, c.p2[0], c.p2[1], c.p2[2], c.cs2)
+			if err != nil {
+				return err
+			}
+			err = drawShape(drawer, tables, lights, c.ShapeCommand, true)
+		case CircleCommand:
+			c := command.(CircleCommand)
+			err = drawer.InCoordinateSystem(c.cs, func() error {
+				return drawer.Circle(c.center[0], c.center[1], c.center[2], c.radius)
+			})
+			if err != nil {
+				return err
+			}
+			err = drawShape(drawer, tables, lights, c.ShapeCommand, true)
+		case EllipseCommand:
+			c := command.(EllipseCommand)
+			err = drawer.InCoordinateSystem(c.cs, func() error {
+				return drawer.Arc(c.center[0], c.center[1], c.center[2], c.rx, c.ry, 0, 360, c.normal)
+			})
+			if err != nil {
+				return err
+			}
+			err = drawShape(drawer, tables, lights, c.ShapeCommand, true)
+		case ArcCommand:
+			c := command.(ArcCommand)
+			err = drawer.InCoordinateSystem(c.cs, func() error {
+				return drawer.Arc(c.center[0], c.center[1], c.center[2], c.radius, c.radius, c.start, c.end, c.normal)
+			})
+			if err != nil {
+				return err
+			}
+			err = drawShape(drawer, tables, lights, c.ShapeCommand, true)
+		case HermiteCommand:
+			c := command.(HermiteCommand)
+			err = drawer.InCoordinateSystem(c.cs, func() error {
+				return drawer.Hermite(c.p0, c.p1, c.r0, c.r1)
+			})
+			if err != nil {
+				return err
+			}
+			err = drawShape(drawer, tables, lights, c.ShapeCommand, true)
+		case BezierCommand:
+			c := command.(BezierCommand)
+			err = drawer.InCoordinateSystem(c.cs, func() error {
+				return drawer.Bezier(c.points[0], c.points[1], c.points[2], c.points[3])
+			})
+			if err != nil {
+				return err
+			}
+			err = drawShape(drawer, tables, lights, c.ShapeCommand, true)
+		case SphereCommand:
+			c := command.(SphereCommand)
+			err = drawer.InCoordinateSystem(c.cs, func() error {
+				return drawer.Sphere(c.center[0], c.center[1], c.center[2], c.radius, c.segments)
+			})
+			if err != nil {
+				return err
+			}
+			err = drawShape(drawer, tables, lights, c.ShapeCommand, false)
+		case TorusCommand:
+			c := command.(TorusCommand)
+			err = drawer.InCoordinateSystem(c.cs, func() error {
+				return drawer.Torus(c.center[0], c.center[1], c.center[2], c.r1, c.r2, c.segments)
+			})
+			if err != nil {
+				return err
+			}
+			err = drawShape(drawer, tables, lights, c.ShapeCommand, false)
+		case SweepCommand:
+			c := command.(SweepCommand)
+			err = drawer.InCoordinateSystem(c.cs, func() error {
+				return drawer.Sweep(c.profile, c.path)
+			})
+			if err != nil {
+				return err
+			}
+			err = drawShape(drawer, tables, lights, c.ShapeCommand, false)
+		case ParticlesCommand:
+			c := command.(ParticlesCommand)
+			var particles []geometry.Vec3
+			particles, err = c.emitter.Particles(tables, frame)
+			if err != nil {
+				return err
+			}
+			err = drawer.InCoordinateSystem(c.cs, func() error {
+				return drawer.Particles(particles, c.size)
+			})
+			if err != nil {
+				return err
+			}
+			err = drawShape(drawer, tables, lights, c.ShapeCommand, c.size == 0)
+		case LSystemCommand:
+			c := command.(LSystemCommand)
+			err = drawer.InCoordinateSystem(c.cs, func() error {
+				return drawer.LSystem(c.segments, c.radius)
+			})
+			if err != nil {
+				return err
+			}
+			err = drawShape(drawer, tables, lights, c.ShapeCommand, c.radius == 0)
+		case MetaballsCommand:
+			c := command.(MetaballsCommand)
+			err = drawer.InCoordinateSystem(c.cs, func() error {
+				return drawer.Metaballs(c.surface)
+			})
+			if err != nil {
+				return err
+			}
+			err = drawShape(drawer, tables, lights, c.ShapeCommand, false)
+		case TerrainCommand:
+			c := command.(TerrainCommand)
+			err = drawer.InCoordinateSystem(c.cs, func() error {
+				return drawer.Terrain(c.corner[0], c.corner[1], c.corner[2], c.width, c.depth, c.height, c.heights)
+			})
+			if err != nil {
+				return err
+			}
+			err = drawShape(drawer, tables, lights, c.ShapeCommand, false)
+		case BoxCommand:
+			c := command.(BoxCommand)
+			err = drawer.InCoordinateSystem(c.cs, func() error {
+				return drawer.Box(c.p1[0], c.p1[1], c.p1[2], c.width, c.height, c.depth)
+			})
+			if err != nil {
+				return err
+			}
+			err = drawShape(drawer, tables, lights, c.ShapeCommand, false)
+		case ClearCommand:
+			drawer.Clear()
+		case ColorCommand:
+			c := command.(ColorCommand)
+			drawer.SetColor(c.color)
+		case PopCommand:
+			drawer.Pop()
+		case PushCommand:
+			drawer.Push()
+		case SaveCommand:
+			c := command.(SaveCommand)
+			err = drawer.Save(c.filename)
+		case SaveCoordinateSystemCommand:
+			c := command.(SaveCoordinateSystemCommand)
+			err = drawer.SaveCoordinateSystem(c.name)
+		case SaveDepthCommand:
+			c := command.(SaveDepthCommand)
+			err = drawer.SaveDepth(c.filename)
+		case SaveFrameCommand:
+			c := command.(SaveFrameCommand)
+			if frame == c.frame {
+				err = drawer.SaveStill(c.filename)
+			}
+		case DisplayCommand:
+			c := command.(DisplayCommand)
+			if frame == c.frame {
+				err = drawer.Display()
+			}
+		case SceneCommand:
+			c := command.(SceneCommand)
+			if frame >= c.start && frame < c.start+c.frames {
+				drawer.Push()
+				err = renderFrame(ctx, drawer, tables, c.commands, frame)
+				drawer.Pop()
+			}
+		case GroupCommand:
+			c := command.(GroupCommand)
+			visible := !drawer.IsHidden(c.name)
+			if visible && c.knob != "" {
+				knob, err := tables.Knob(c.knob, frame)
+				if err != nil {
+					return err
+				}
+				visible = knob != 0
+			}
+			if visible {
+				drawer.Push()
+				err = renderFrame(ctx, drawer, tables, c.commands, frame)
+				drawer.Pop()
+			}
+		case SymmetryCommand:
+			c := command.(SymmetryCommand)
+			drawer.Push()
+			err = renderFrame(ctx, drawer, tables, c.commands, frame)
+			drawer.Pop()
+			if err != nil {
+				return err
+			}
+			drawer.Push()
+			if err = drawer.Mirror(c.axis, c.offset); err == nil {
+				err = renderFrame(ctx, drawer, tables, c.commands, frame)
+			}
+			drawer.Pop()
+		case RenderTargetCommand:
+			c := command.(RenderTargetCommand)
+			err = drawer.RenderTarget(c.name, c.width, c.height, func() error {
+				return renderFrame(ctx, drawer, tables, c.commands, frame)
+			})
+		case UseTextureCommand:
+			c := command.(UseTextureCommand)
+			err = drawer.UseTexture(c.name)
+		case AxesCommand:
+			c := command.(AxesCommand)
+			err = drawer.InCoordinateSystem(c.cs, func() error {
+				return drawer.Axes(c.length)
+			})
+		case GridCommand:
+			c := command.(GridCommand)
+			err = drawer.InCoordinateSystem(c.cs, func() error {
+				return drawer.Grid(c.size, c.spacing)
+			})
+		case DebugCommand:
+			c := command.(DebugCommand)
+			drawer.SetDebug(c.overlay, c.on)
+		case PickCommand:
+			c := command.(PickCommand)
+			var picked render.Picked
+			if picked, err = drawer.Pick(c.x, c.y); err == nil && !drawer.InShadowPass() {
+				fmt.Printf("Frame %d: pixel (%d, %d): %v\n", frame, c.x, c.y, picked)
+			}
+		case InstanceCommand:
+			c := command.(InstanceCommand)
+			object, found := tables.objects[c.name]
+			if !found {
+				return fmt.Errorf("undefined object '%s'", c.name)
+			}
+			err = drawer.Instance(c.name, func() error {
+				return renderFrame(ctx, drawer, tables, object, frame)
+			})
+		case HideCommand:
+			c := command.(HideCommand)
+			drawer.Hide(c.group)
+		case ShowCommand:
+			c := command.(ShowCommand)
+			drawer.Show(c.group)
+		case SnapshotCommand:
+			c := command.(SnapshotCommand)
+			drawer.Snapshot(c.name)
+		case RestoreCommand:
+			c := command.(RestoreCommand)
+			err = drawer.Restore(c.name)
+		case LayerCommand:
+			c := command.(LayerCommand)
+			drawer.UseLayer(c.name, c.mode, c.opacity)
+		case ClipCommand:
+			c := command.(ClipCommand)
+			if c.plane != nil {
+				err = drawer.Clip(c.plane[0], c.plane[1], c.plane[2], c.plane[3])
+			} else {
+				drawer.ClearClip()
+			}
+		case LevelOfDetailCommand:
+			c := command.(LevelOfDetailCommand)
+			drawer.SetLevelOfDetail(c.pixels)
+		case QualityCommand:
+			c := command.(QualityCommand)
+			drawer.SetQuality(c.segments)
+		case StampCommand:
+			c := command.(StampCommand)
+			drawer.SetStamp(c.corner, c.scale, tables.frameRate)
+		case DisplaceCommand:
+			c := command.(DisplaceCommand)
+			drawer.SetDisplacement(c.size, c.amount)
+		case EnvironmentMapCommand:
+			c := command.(EnvironmentMapCommand)
+			err = drawer.SetEnvironmentMap(c.filename)
+		case AmbientOcclusionCommand:
+			c := command.(AmbientOcclusionCommand)
+			drawer.SetAmbientOcclusion(c.radius, c.strength)
+		case LineWidthCommand:
+			c := command.(LineWidthCommand)
+			drawer.SetLineWidth(c.width)
+		case ExposureCommand:
+			c := command.(ExposureCommand)
+			exposure := c.exposure
+			if c.knob != "" {
+				knob, err := tables.Knob(c.knob, frame)
+				if err != nil {
+					return err
+				}
+				exposure *= knob
+			}
+			drawer.SetExposure(exposure)
+		case ToneMapCommand:
+			c := command.(ToneMapCommand)
+			drawer.SetToneMap(c.toneMap)
+		case GammaCommand:
+			c := command.(GammaCommand)
+			drawer.SetLinearLighting(c.linear)
+		case RenderModeCommand:
+			c := command.(RenderModeCommand)
+			drawer.SetRenderMode(c.mode)
+		case CullingCommand:
+			c := command.(CullingCommand)
+			drawer.SetCulling(c.mode)
+		case WindingCommand:
+			c := command.(WindingCommand)
+			drawer.SetWinding(c.winding)
+		case ShadingCommand:
+			c := command.(ShadingCommand)
+			drawer.SetShading(c.mode)
+		case ViewportCommand:
+			c := command.(ViewportCommand)
+			drawer.SetViewport(c.viewport)
+		case ViewportRectCommand:
+			c := command.(ViewportRectCommand)
+			drawer.SetViewportRect(c.rect)
+		case ProjectionCommand:
+			c := command.(ProjectionCommand)
+			drawer.SetProjection(c.projection)
+		case CameraCommand:
+			c := command.(CameraCommand)
+			camera := geometry.Camera{Eye: c.eye, Aim: c.aim}
+			for i, name := range c.knobs {
+				if name == "" {
+					continue
+				}
+				knob, err := tables.Knob(name, frame)
+				if err != nil {
+					return err
+				}
+				if i < 3 {
+					camera.Eye[i] *= knob
+				} else {
+					camera.Aim[i-3] *= knob
+				}
+			}
+			err = drawer.SetCamera(&camera)
+		case CameraPathCommand:
+			c := command.(CameraPathCommand)
+			var t float64
+			if c.knob != "" {
+				if t, err = tables.Knob(c.knob, frame); err != nil {
+					return err
+				}
+			} else if tables.frames > 1 {
+				t = float64(frame) / float64(tables.frames-1)
+			}
+			err = drawer.MoveCamera(c.path.At(t))
+		case DepthEpsilonCommand:
+			c := command.(DepthEpsilonCommand)
+			drawer.SetDepthEpsilon(c.epsilon)
+		case DepthOffsetCommand:
+			c := command.(DepthOffsetCommand)
+			drawer.SetDepthOffset(c.offset)
+		case DepthClipCommand:
+			c := command.(DepthClipCommand)
+			err = drawer.SetDepthClip(c.near, c.far)
+		case MeshCommand:
+			c := command.(MeshCommand)
+			err = drawer.Mesh(c.filename)
+			if err != nil {
+				return err
+			}
+			err = drawer.InCoordinateSystem(c.cs, drawer.ApplySurface)
+			if err != nil {
+				return err
+			}
+			err = drawShape(drawer, tables, lights, c.ShapeCommand, false)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return err
+}
+
+// drawShape draws what a shape added to the drawer, as lines if lines is set
+// and as polygons otherwise, lit with the shape's constants if it has any and
+// in its color if not
+func drawShape(drawer *render.Drawer, tables *SymbolTables, lights map[string]image.LightSource, shape ShapeCommand, lines bool) error {
+	color := shape.drawColor(drawer.Color())
+	if shape.constants == "" {
+		if lines {
+			return drawer.DrawLines(color)
+		}
+		return drawer.DrawPolygons(color)
+	}
+	constant, err := tables.Constants(shape.constants)
+	if err != nil {
+		return err
+	}
+	if lines {
+		return drawer.DrawShadedLines(tables.ambient, constant, lights)
+	}
+	return drawer.DrawShadedPolygons(tables.ambient, constant, lights, drawer.Shading(), color)
+}
+
+// include reads and lexes an included script, whose path is relative to the
+// script including it
+func (p *Parser) include(filename string) ([]Token, error) {
+	including := p.filename
+	if len(p.includes) > 0 {
+		including = p.includes[len(p.includes)-1].name
+	}
+	name := filename
+	if !filepath.IsAbs(name) && including != "" {
+		name = filepath.Join(filepath.Dir(including), name)
+	}
+	path, err := filepath.Abs(name)
+	if err != nil {
+		return nil, err
+	}
+
+	chain := []string{}
+	if p.filename != "" {
+		if main, err := filepath.Abs(p.filename); err == nil && main == path {
+			chain = append(chain, p.filename)
+		}
+	}
+	for _, s := range p.includes {
+		if len(chain) > 0 || s.path == path {
+			chain = append(chain, s.name)
+		}
+	}
+	if len(chain) > 0 {
+		return nil, fmt.Errorf("include cycle: %s -> %s", strings.Join(chain, " -> "), name)
+	}
+
+	p.dependencies = append(p.dependencies, path)
+	input, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	lexer := Lex(string(input))
+	tokens := make([]Token, 0)
+	for {
+		t := lexer.NextToken()
+		if t.tt == tEOF {
+			break
+		}
+		tokens = append(tokens, t)
+	}
+	p.includes = append(p.includes, script{name: name, path: path})
+	return tokens, nil
+}
+
+// includeStack describes the included scripts being parsed for errors
+func (p *Parser) includeStack() string {
+	stack := make([]string, 0, len(p.includes)+1)
+	for i := len(p.includes) - 1; i >= 0; i-- {
+		stack = append(stack, p.includes[i].name)
+	}
+	if p.filename != "" {
+		stack = append(stack, p.filename)
+	}
+	return "in " + strings.Join(stack, ", included from ")
+}
+
+// macroBody returns the tokens of a macro up to the end of its definition
+// Blocks started within the macro must also be ended within it.
+func (p *Parser) macroBody(name string) ([]Token, error) {
+	body := make([]Token, 0)
+	depth := 0
+	for {
+		t := p.nextToken()
+		switch t.tt {
+		case tError:
+			return nil, errors.New(t.value)
+		case tEOF:
+			return nil, fmt.Errorf("macro %s is never ended", name)
+		case tIdent:
+			switch tt := LookupIdent(t.value); {
+			case StartsBlock(tt):
+				depth++
+			case tt == DEFINE:
+				return nil, fmt.Errorf("macro %s cannot define another macro", name)
+			case tt == END:
+				if depth == 0 {
+					return body, nil
+				}
+				depth--
+			}
+		}
+		body = append(body, t)
+	}
+}
+
+// nextToken returns the nextToken token from the lexer
+func (p *Parser) nextToken() Token {
+	lenBackup := len(p.backup)
+	// Use the token from backup if it exists
+	if lenBackup > 0 {
+		token := p.backup[lenBackup-1]
+		p.backup = p.backup[:lenBackup-1]
+		return token
+	}
+	token := p.lexer.NextToken()
+	return token
+}
+
+// next returns the next token if it matches the given token types
+// If the token does not match, error is non-nil
+func (p *Parser) next(typs ...TokenType) (string, error) {
+	next := p.peek()
+	for _, tt := range typs {
+		if next.tt == tt {
+			p.nextToken()
+			return next.value, nil
+		}
+	}
+	if next.tt == tError {
+		return "", errors.New(next.value)
+	}
+	return "", fmt.Errorf("expected %v, got %v", typs, next.tt)
+}
+
+// nextRequired returns the value of the nextRequired token if its type is valid
+// Panics if none of the token types match
+func (p *Parser) nextRequired(typs ...TokenType) string {
+	next, err := p.next(typs...)
+	if err != nil {
+		panic(err)
+	}
+	return next
+}
+
+// nextInt returns the next integer token from the lexer
+// An expression is evaluated and must result in a whole number.
+func (p *Parser) nextInt() int {
+	next := p.peek()
+	if next.tt != tString {
+		v, _ := strconv.Atoi(strings.ReplaceAll(p.nextRequired(tInt), "_", ""))
+		return v
+	}
+	v := p.nextFloat()
+	if v != math.Trunc(v) {
+		panic(fmt.Errorf("expected an integer, got %v from '%s'", v, next.value))
+	}
+	return int(v)
+}
+
+// nextFloat returns the next token from the lexer as a float.
+// An expression is evaluated against the variables defined so far.
+func (p *Parser) nextFloat() float64 {
+	next := p.peek()
+	if next.tt != tString {
+		v, _ := strconv.ParseFloat(strings.ReplaceAll(p.nextRequired(tInt, tFloat), "_", ""), 64)
+		return v
+	}
+	p.nextToken()
+	v, err := p.tables.variables.Evaluate(next.value)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+// nextFrame returns the next token as a frame number, which can also be given
+// as a time in seconds such as 1.5s
+// A time at the very end of an animation that is frames long is its last frame.
+func (p *Parser) nextFrame(frames int) int {
+	next := p.peek()
+	if next.tt == tString && strings.HasSuffix(next.value, "s") {
+		seconds, err := strconv.ParseFloat(strings.ReplaceAll(strings.TrimSuffix(next.value, "s"), "_", ""), 64)
+		if err == nil {
+			p.nextToken()
+			p.timed = true
+			frame := int(math.Round(seconds * p.frameRate()))
+			if frames > 0 && frame == frames {
+				frame--
+			}
+			return frame
+		}
+	}
+	return p.nextInt()
+}
+
+// checkFrame returns an error if frame is not in the animation, as far as its
+// number of frames is known yet
+func (p *Parser) checkFrame(frame int, where string) error {
+	if frame < 0 || (p.frames > 0 && frame >= p.frames) {
+		return fmt.Errorf("invalid frame %d for %s", frame, where)
+	}
+	return nil
+}
+
+// frameRate returns the number of frames per second of the animation
+func (p *Parser) frameRate() float64 {
+	if p.fps > 0 {
+		return p.fps
+	}
+	return render.DefaultFrameRate
+}
+
+// peekNumber returns whether the next token is a number or an expression that
+// can be evaluated
+func (p *Parser) peekNumber() bool {
+	next := p.peek()
+	switch next.tt {
+	case tInt, tFloat:
+		return true
+	case tString:
+		_, err := p.tables.variables.Evaluate(next.value)
+		return err == nil
+	}
+	return false
+}
+
+// nextKnobAndPivot returns the optional knob and pivot point that end a
+// rotation or scale, where the pivot is given as "about x y z"
+func (p *Parser) nextKnobAndPivot() (string, []float64) {
+	var knob string
+	if next := p.peek(); next.tt == tString && next.value != "about" {
+		knob, _ = p.next(tString)
+	}
+	if next := p.peek(); next.tt != tString || next.value != "about" {
+		return knob, nil
+	}
+	p.nextToken()
+	return knob, []float64{p.nextFloat(), p.nextFloat(), p.nextFloat()}
+}
+
+// nextCurvePoints returns the n points that define a curve, given as x y pairs
+// for a flat curve or as x y z triples, along with the color that directly
+// follows them, if any
+// Flat and 3D curves are told apart by how many numbers there are, which
+// differs for every combination when n is 4.
+func (p *Parser) nextCurvePoints(n int) ([]geometry.Vec3, *image.Color) {
+	var numbers []float64
+	for p.peekNumber() {
+		numbers = append(numbers, p.nextFloat())
+	}
+	var dimensions int
+	switch len(numbers) {
+	case 2 * n, 2*n + 3:
+		dimensions = 2
+	case 3 * n, 3*n + 3:
+		dimensions = 3
+	default:
+		panic(fmt.Errorf("expected %d points as x y or x y z, got %d numbers", n, len(numbers)))
+	}
+	points := make([]geometry.Vec3, n)
+	for i := range points {
+		copy(points[i][:], numbers[i*dimensions:(i+1)*dimensions])
+	}
+	var color *image.Color
+	if rgb := numbers[n*dimensions:]; len(rgb) == 3 {
+		color = channelsColor(rgb)
+	}
+	return points, color
+}
+
+// nextFacing returns the optional direction that a flat shape faces, given as
+// "facing x y z", which is the z axis unless given
+func (p *Parser) nextFacing() geometry.Vec3 {
+	if next := p.peek(); next.tt != tString || next.value != "facing" {
+		return geometry.Vec3{0, 0, 1}
+	}
+	p.nextToken()
+	normal := geometry.Vec3{p.nextFloat(), p.nextFloat(), p.nextFloat()}
+	if normal.Length() == 0 {
+		panic(errors.New("the direction a shape faces must not be zero"))
+	}
+	return normal
+}
+
+// vary fills a knob between two frames of the scene being parsed, or of the
+// animation outside of scenes, with values from a start value to an end value
+// that change over time as the curve read by nextCurve does
+func (p *Parser) vary(scene *SceneCommand, nextCurve func() (Curve, error)) error {
+	// Frames within a scene are relative to the start of the scene
+	offset, frames := 0, p.frames
+	if scene != nil {
+		offset, frames = scene.start, scene.frames
+	}
+	if frames == 0 {
+		return errors.New("number of frames is not set")
+	}
+	name := p.nextString()
+	knob := p.tables.knobs[name]
+	if len(knob) < offset+frames {
+		knob = append(knob, make([]float64, offset+frames-len(knob))...)
+	}
+	startFrame := p.nextFrame(frames)
+	if startFrame < 0 || startFrame >= frames {
+		return fmt.Errorf("invalid start frame %d for knob %s", startFrame, name)
+	}
+	endFrame := p.nextFrame(frames)
+	if endFrame < 0 || endFrame >= frames || endFrame < startFrame {
+		return fmt.Errorf("invalid end frame %d for knob %s", endFrame, name)
+	}
+	startValue := p.nextFloat()
+	endValue := p.nextFloat()
+	curve, err := nextCurve()
+	if err != nil {
+		return err
+	}
+	length := endFrame - startFrame
+	for frame := startFrame; frame <= endFrame; frame++ {
+		t := float64(frame-startFrame) / float64(length+1)
+		knob[offset+frame] = startValue + (endValue-startValue)*curve.At(t)
+	}
+	p.tables.knobs[name] = knob
+	p.isAnimated = true
+	return nil
+}
+
+// setKnob sets a knob to a value in every frame of the scene being parsed, or
+// of the animation outside of scenes, and records it for save_knobs
+func (p *Parser) setKnob(name string, value float64, scene *SceneCommand) {
+	offset, frames := 0, p.frames
+	if scene != nil {
+		offset, frames = scene.start, scene.frames
+	}
+	if frames == 0 {
+		// Scripts that aren't animated still render frame 0
+		frames = 1
+	}
+	knob := p.tables.knobs[name]
+	if len(knob) < offset+frames {
+		knob = append(knob, make([]float64, offset+frames-len(knob))...)
+	}
+	for frame := offset; frame < offset+frames; frame++ {
+		knob[frame] = value
+	}
+	p.tables.knobs[name] = knob
+	p.tables.knobValues[name] = value
+}
+
+// nextSegments returns the optional number of segments of a curved shape,
+// which is told apart from the shape's color by being a single number or
+// followed by all three numbers of the color
+func (p *Parser) nextSegments() int {
+	if !p.peekOptionalNumber() {
+		return 0
+	}
+	segments := p.nextInt()
+	if segments < render.MinCircularSteps {
+		panic(fmt.Errorf("a shape must have at least %d segments", render.MinCircularSteps))
+	}
+	return segments
+}
+
+// randomSource returns the source of randomness for generated shapes, which is
+// seeded with 0 until the script picks a seed, so scripts always render the same
+func (p *Parser) randomSource() *rand.Rand {
+	if p.random == nil {
+		p.random = rand.New(rand.NewSource(0))
+	}
+	return p.random
+}
+
+// peekOptionalNumber returns whether an optional number comes before a shape's
+// color, by being a single number or followed by all three numbers of the color
+func (p *Parser) peekOptionalNumber() bool {
+	// Count the numbers that follow without consuming them
+	var tokens []Token
+	for len(tokens) < 4 && p.peekNumber() {
+		tokens = append(tokens, p.nextToken())
+	}
+	count := len(tokens)
+	for i := count - 1; i >= 0; i-- {
+		p.unread(tokens[i])
+	}
+	return count == 1 || count == 4
+}
+
+// nextName returns the optional name of a knob or coordinate system that
+// modifies the values before it
+func (p *Parser) nextName() string {
+	if p.peek().tt != tString || p.peekNumber() {
+		return ""
+	}
+	knob, _ := p.next(tString)
+	return knob
+}
+
+// nextConstants returns the optional name of the lighting constants a shape is
+// drawn with, where "nil" means none
+// A string that is a valid expression is the shape's first number instead.
+func (p *Parser) nextConstants() string {
+	if p.peekNumber() {
+		return ""
+	}
+	name, _ := p.next(tString)
+	if name == "nil" {
+		return ""
+	}
+	return name
+}
+
+// nextColor returns the optional r g b color that follows, or nil if the next
+// token is not a number
+func (p *Parser) nextColor() *image.Color {
+	if !p.peekNumber() {
+		return nil
+	}
+	color := p.nextRGB()
+	return &color
+}
+
+// nextRGB returns the next three numbers as the red, green, and blue of an
+// opaque color
+func (p *Parser) nextRGB() image.Color {
+	return image.Color{R: p.nextChannel(), G: p.nextChannel(), B: p.nextChannel(), A: 255}
+}
+
+// nextChannel returns the next number as a channel of a color, which must be
+// an integer from 0 to 255
+func (p *Parser) nextChannel() byte {
+	next := p.peek()
+	v := p.nextInt()
+	if v < 0 || v > 255 {
+		panic(fmt.Errorf("color channels must be from 0 to 255, got %d from '%s'", v, next.value))
+	}
+	return byte(v)
+}
+
+// channelsColor returns the color with channels that were read as numbers
+// before knowing they were a color, which must be integers from 0 to 255 just
+// like the ones nextChannel reads
+func channelsColor(rgb []float64) *image.Color {
+	var channels [3]byte
+	for i, v := range rgb {
+		if v != math.Trunc(v) || v < 0 || v > 255 {
+			panic(fmt.Errorf("color channels must be integers from 0 to 255, got %v", v))
+		}
+		channels[i] = byte(v)
+	}
+	return &image.Color{R: channels[0], G: channels[1], B: channels[2], A: 255}
+}
+
+// nextString returns the next token from the lexer.
+// Surrounding double quotes are removed, and the escape sequences inside them
+// replaced.
+func (p *Parser) nextString() string {
+	s := p.nextRequired(tString)
+	if len(s) >= 2 && s[0] == '"' && s[len(s)-1] == '"' {
+		unquoted, err := strconv.Unquote(s)
+		if err != nil {
+			panic(fmt.Errorf("invalid escape sequence in %s", s))
+		}
+		s = unquoted
+	}
+	return s
+}
+
+// nextMirror returns the axis across which mirror and symmetry reflect, and
+// the optional offset of the plane along it
+func (p *Parser) nextMirror() (axis string, offset float64) {
+	axis = p.nextIdent()
+	if _, err := geometry.MakeMirror(axis, 0); err != nil {
+		panic(fmt.Errorf("invalid mirror axis '%s'", axis))
+	}
+	if p.peekNumber() {
+		offset = p.nextFloat()
+	}
+	return axis, offset
+}
+
+// nextIdent returns the next identifier from the lexer as a string.
+func (p *Parser) nextIdent() string {
+	return p.nextRequired(tIdent)
+}
+
+// unread adds the token to the list of backup tokens.
+// Since channels cannot be "unread", we use a list to backup these tokens
+func (p *Parser) unread(token Token) {
+	p.backup = append(p.backup, token)
+}
+
+// peek returns the next token without consuming it
+func (p *Parser) peek() Token {
+	token := p.nextToken()
+	p.unread(token)
+	return token
+}
+
+// Job is a struct that tells a worker thread which frames to render
+type Job struct {
+	frame    int  // frame to render
+	animated bool // whether the frame is part of an animation
+}
+
+// FrameError is an error that stopped a frame of an animation from being
+// rendered
+type FrameError struct {
+	frame int
+	err   error
+}
+
+func (e FrameError) Error() string {
+	return fmt.Sprintf("frame %d: %v", e.frame, e.err)
+}
+
+// worker is a worker thread that renders frames
+// If encoder is non-nil, animation frames are sent to it instead of being saved
+// Saved animation frames are recorded in checkpoint, if it is non-nil
+// Frames that fail are sent to errs, and the worker moves on to the next one.
+// Workers stop once ctx is cancelled, without saving the frame they were on.
+func worker(ctx context.Context, drawer *render.Drawer, tables *SymbolTables, commands []Command, jobs chan Job, encoder *render.VideoEncoder, checkpoint *Checkpoint, errs chan<- error, wg *sync.WaitGroup) {
+	defer wg.Done()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case job, ok := <-jobs:
+			if !ok {
+				return
+			}
+			if job.animated {
+				fmt.Println("Rendering frame", job.frame)
+			}
+
+			err := renderJob(ctx, drawer, tables, commands, job, encoder, checkpoint)
+			if ctx.Err() != nil {
+				return
+			}
+			if err != nil && job.animated {
+				errs <- FrameError{frame: job.frame, err: err}
+			} else if err != nil {
+				errs <- err
+			}
+			if job.animated {
+				drawer.Reset()
+			}
+		}
+	}
+}
+
+// renderJob renders a frame and saves it, or sends it to encoder if it is
+// non-nil
+func renderJob(ctx context.Context, drawer *render.Drawer, tables *SymbolTables, commands []Command, job Job, encoder *render.VideoEncoder, checkpoint *Checkpoint) error {
+	drawer.BeginFrame(job.frame)
+	if err := drawFrame(ctx, drawer, tables, commands, job.frame); err != nil {
+		return err
+	}
+	if err := drawer.ShowPreview(); err != nil {
+		return err
+	}
+	if !job.animated {
+		return nil
+	}
+	if encoder != nil {
+		return encoder.WriteFrame(job.frame, drawer.Output(false))
+	}
+	if err := drawer.Save(fmt.Sprintf(tables.formatString, job.frame)); err != nil {
+		return err
+	}
+	if checkpoint != nil {
+		return checkpoint.Complete(job.frame)
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+)
+
+// parseError parses a script with a new Parser and returns the error it
+// fails with, failing the test if it parses
+func parseError(t *testing.T, script string) error {
+	t.Helper()
+	_, err := NewParser().ParseScene(script)
+	if err == nil {
+		t.Fatalf("parsing %q succeeded, want an error", script)
+	}
+	return err
+}
+
+func TestCurveColorsMustBeChannels(t *testing.T) {
+	for _, script := range []string{
+		"hermite 0 0 100 100 50 0 0 50 300 0 0\n",
+		"bezier 0 0 10 10 20 10 30 0 0 -1 0\n",
+		"bezier 0 0 0 10 10 0 20 10 0 30 0 0 0 0 12.5\n",
+	} {
+		if err := parseError(t, script); !strings.Contains(err.Error(), "color channels") {
+			t.Errorf("parsing %q: got %v, want an error about color channels", script, err)
+		}
+	}
+	if _, err := NewParser().ParseScene("hermite 0 0 100 100 50 0 0 50 255 0 10\n"); err != nil {
+		t.Errorf("curve with a color in range: %v", err)
+	}
+}
+
+func TestMetaballColorsMustBeChannels(t *testing.T) {
+	for _, script := range []string{
+		"metaballs 0 0 0 50 1 256 0 0\n",
+		"metaballs 0 0 0 50 1 20 0 -5 0\n",
+	} {
+		if err := parseError(t, script); !strings.Contains(err.Error(), "color channels") {
+			t.Errorf("parsing %q: got %v, want an error about color channels", script, err)
+		}
+	}
+	if _, err := NewParser().ParseScene("metaballs 0 0 0 50 1 20 255 0 0\n"); err != nil {
+		t.Errorf("metaballs with a color in range: %v", err)
+	}
+}
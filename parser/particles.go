@@ -0,0 +1,112 @@
+package parser
+
+import (
+	"math"
+
+	"github.com/james9909/graphics-engine/geometry"
+)
+
+// DefaultParticleLife is the number of frames particles live for, unless
+// their emitter gives its own
+const DefaultParticleLife = 30
+
+// MaxParticles keeps an emitter from drawing so many particles at once that a
+// frame takes minutes to render
+const MaxParticles = 100000
+
+// Emitter sends out particles from a point, which fly off in a cone around its
+// velocity and fall with gravity until they die
+// Distances are in the coordinate system the particles are drawn in, and times
+// are in frames.
+type Emitter struct {
+	origin   geometry.Vec3
+	rate     float64 // particles emitted per frame
+	knob     string  // knob the rate is scaled by in each frame, if any
+	velocity geometry.Vec3
+	spread   float64 // angle in degrees of the cone particles fly off in
+	jitter   float64 // fraction that the speed of particles varies by
+	gravity  geometry.Vec3
+	life     int    // frames each particle lives for
+	seed     uint64 // picks the random directions and speeds of the particles
+}
+
+// Particles returns where each living particle is in a frame
+// Particles are emitted steadily through each frame, so streams don't clump
+// together, and are placed by their age instead of by stepping through each
+// frame, so frames can be rendered in any order.
+func (e *Emitter) Particles(tables *SymbolTables, frame int) ([]geometry.Vec3, error) {
+	// emitted is the number of particles emitted by the end of a frame
+	emitted := make([]float64, frame+1)
+	total := 0.0
+	for f := 0; f <= frame; f++ {
+		rate := e.rate
+		if e.knob != "" {
+			knob, err := tables.Knob(e.knob, f)
+			if err != nil {
+				return nil, err
+			}
+			rate *= math.Max(knob, 0)
+		}
+		total += rate
+		emitted[f] = total
+	}
+
+	var particles []geometry.Vec3
+	first := frame - e.life + 1
+	if first < 0 {
+		first = 0
+	}
+	for f := first; f <= frame; f++ {
+		before := 0.0
+		if f > 0 {
+			before = emitted[f-1]
+		}
+		for i := int(math.Ceil(before)); float64(i) < emitted[f]; i++ {
+			// The particle is born partway through the frame, in the order
+			// it is emitted
+			born := float64(f-1) + (float64(i)-before)/(emitted[f]-before)
+			age := float64(frame) - born
+			if age >= float64(e.life) {
+				continue
+			}
+			if len(particles) == MaxParticles {
+				return particles, nil
+			}
+			velocity := e.particleVelocity(i)
+			particles = append(particles, e.origin.Add(velocity.Scale(age)).Add(e.gravity.Scale(age*age/2)))
+		}
+	}
+	return particles, nil
+}
+
+// particleVelocity returns the velocity of the ith particle emitted, which is
+// always the same for the same particle
+func (e *Emitter) particleVelocity(i int) geometry.Vec3 {
+	speed := e.velocity.Length()
+	if speed == 0 {
+		return geometry.Vec3{}
+	}
+	// Pick a direction evenly spread over the cap of the cone around the z
+	// axis, and then turn it towards the velocity
+	cosSpread := math.Cos(geometry.DegreesToRadians(e.spread))
+	z := 1 - particleRandom(e.seed, i, 0)*(1-cosSpread)
+	theta := 2 * math.Pi * particleRandom(e.seed, i, 1)
+	r := math.Sqrt(math.Max(1-z*z, 0))
+	speed *= 1 + e.jitter*(2*particleRandom(e.seed, i, 2)-1)
+
+	w := e.velocity.Normalize()
+	u, v := geometry.PlaneAxes(w)
+	return u.Scale(r * math.Cos(theta)).Add(v.Scale(r * math.Sin(theta))).Add(w.Scale(z)).Scale(speed)
+}
+
+// particleRandom returns a random number from 0 up to 1 for the kth choice made
+// for the ith particle of an emitter, which is the same every time it is asked
+// for, without stepping through the choices made for every other particle
+func particleRandom(seed uint64, i, k int) float64 {
+	// splitmix64, which scrambles every bit of its input
+	x := seed + uint64(i)*0x9e3779b97f4a7c15 + uint64(k)*0xbf58476d1ce4e5b9
+	x = (x ^ x>>30) * 0xbf58476d1ce4e5b9
+	x = (x ^ x>>27) * 0x94d049bb133111eb
+	x ^= x >> 31
+	return float64(x>>11) / (1 << 53)
+}
@@ -0,0 +1,92 @@
+package parser
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/james9909/graphics-engine/render"
+)
+
+const (
+	// Prompt is shown when interactive mode is ready for a statement
+	Prompt = "mdl> "
+	// ContinuationPrompt is shown when interactive mode is waiting for the end
+	// of a block
+	ContinuationPrompt = "...> "
+)
+
+// Interactive reads statements from in and runs each as soon as it is
+// entered, drawing onto the same image until in ends
+// Blocks are run once they are ended. Errors are written to out without
+// ending the session, and drawing continues from where the last statement
+// that succeeded left off.
+func (p *Parser) Interactive(in io.Reader, out io.Writer) error {
+	p.reset()
+	// Any statement can pick, so what covers each pixel is always kept
+	p.picking = true
+	drawer, err := p.newDrawer()
+	if err != nil {
+		return err
+	}
+	scanner := bufio.NewScanner(in)
+	var pending strings.Builder // lines of blocks that haven't been ended
+	fmt.Fprint(out, Prompt)
+	for scanner.Scan() {
+		pending.WriteString(scanner.Text())
+		pending.WriteByte('\n')
+		if blockDepth(pending.String()) > 0 {
+			fmt.Fprint(out, ContinuationPrompt)
+			continue
+		}
+		if err := p.runStatement(drawer, pending.String()); err != nil {
+			fmt.Fprintln(out, "error:", err)
+		}
+		pending.Reset()
+		fmt.Fprint(out, Prompt)
+	}
+	fmt.Fprintln(out)
+	return scanner.Err()
+}
+
+// runStatement parses input, keeping what earlier statements defined, and
+// draws its commands onto drawer
+func (p *Parser) runStatement(drawer *render.Drawer, input string) error {
+	p.lexer = Lex(input)
+	p.backup = p.backup[:0]
+	p.includes = nil
+	// Pushes that aren't popped carry on to later statements, unless the
+	// statement they are in fails
+	pushes := append([]string(nil), p.pushes...)
+	commands, err := p.parseChecked()
+	if err != nil {
+		p.pushes = pushes
+		return err
+	}
+	drawer.SetBackground(p.tables.background)
+	if err := renderFrame(p.ctx, drawer, p.tables, commands, 0); err != nil {
+		return err
+	}
+	return drawer.ShowPreview()
+}
+
+// blockDepth returns the number of blocks started in input that are not ended
+func blockDepth(input string) int {
+	lexer := Lex(input)
+	depth := 0
+	for {
+		t := lexer.NextToken()
+		switch t.tt {
+		case tEOF, tError:
+			return depth
+		case tIdent:
+			switch tt := LookupIdent(t.value); {
+			case StartsBlock(tt), tt == DEFINE:
+				depth++
+			case tt == END:
+				depth--
+			}
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package parser
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+
+	"github.com/james9909/graphics-engine/geometry"
+	"github.com/james9909/graphics-engine/image"
+	"github.com/james9909/graphics-engine/render"
+)
+
+// SymbolTables holds everything a script defines while it is parsed that its
+// commands refer to while they are rendered
+// Each parsed script has its own SymbolTables, so scripts can be parsed and
+// rendered concurrently.
+type SymbolTables struct {
+	variables    *SymbolTable                  // variables defined with let
+	knobs        map[string][]float64          // knob table
+	overrides    map[string]float64            // knob values set by a live controller
+	ambient      geometry.Vec3                 // ambient lighting
+	background   image.Color                   // color of the image where nothing is drawn
+	sky          render.Sky                    // what the image shows where nothing is drawn instead of the background, if anything
+	lightSources map[string]image.LightSource  // light table
+	constants    map[string]image.Material     // constants table
+	curves       map[string]Curve              // timing curves defined with curve
+	paths        map[string]geometry.Cubic     // 3D curves defined with curve
+	profiles     map[string]geometry.Profile   // cross-sections defined with profile
+	emitters     map[string]*Emitter           // particle emitters defined with emitter
+	knobValues   map[string]float64            // knob values given to set and setknobs so far
+	knobLists    map[string]map[string]float64 // knob values saved with save_knobs
+	objects      map[string][]Command          // commands of each object
+	formatString string                        // format string for each frame of the animation
+	frames       int                           // number of frames of the animation
+	frameRate    float64                       // frames per second of the animation
+	shadowSize   int                           // resolution of shadow maps, or 0 if nothing casts shadows
+}
+
+// NewSymbolTables returns empty SymbolTables
+func NewSymbolTables() *SymbolTables {
+	return &SymbolTables{
+		variables:    NewSymbolTable(),
+		knobs:        make(map[string][]float64),
+		overrides:    make(map[string]float64),
+		lightSources: make(map[string]image.LightSource),
+		constants:    make(map[string]image.Material),
+		curves:       make(map[string]Curve),
+		paths:        make(map[string]geometry.Cubic),
+		profiles:     make(map[string]geometry.Profile),
+		emitters:     make(map[string]*Emitter),
+		knobValues:   make(map[string]float64),
+		knobLists:    make(map[string]map[string]float64),
+		objects:      make(map[string][]Command),
+	}
+}
+
+// Knob returns the value of a knob in a frame
+// A value set by a live controller takes precedence over the script.
+func (t *SymbolTables) Knob(name string, frame int) (float64, error) {
+	if value, found := t.overrides[name]; found {
+		return value, nil
+	}
+	if knob, found := t.knobs[name]; found {
+		return knob[frame], nil
+	}
+	return 0, fmt.Errorf("undefined knob '%s'", name)
+}
+
+// Lights returns the light sources as they are in a frame, with the knobs
+// they refer to applied
+func (t *SymbolTables) Lights(frame int) (map[string]image.LightSource, error) {
+	lights := make(map[string]image.LightSource, len(t.lightSources))
+	for name, light := range t.lightSources {
+		if light.ColorKnob != "" {
+			knob, err := t.Knob(light.ColorKnob, frame)
+			if err != nil {
+				return nil, err
+			}
+			light.Color = image.Color{
+				R: byte(geometry.Clamp(float64(light.Color.R)*knob, 0, 255)),
+				G: byte(geometry.Clamp(float64(light.Color.G)*knob, 0, 255)),
+				B: byte(geometry.Clamp(float64(light.Color.B)*knob, 0, 255)),
+				A: 255,
+			}
+		}
+		location := light.Location
+		for i, name := range light.LocationKnobs {
+			if name == "" {
+				continue
+			}
+			knob, err := t.Knob(name, frame)
+			if err != nil {
+				return nil, err
+			}
+			location[i] *= knob
+		}
+		light.Location = location
+		lights[name] = light
+	}
+	return lights, nil
+}
+
+// Constants returns the material with the given name
+func (t *SymbolTables) Constants(name string) (image.Material, error) {
+	if constant, found := t.constants[name]; found {
+		return constant, nil
+	}
+	return image.Material{}, fmt.Errorf("undefined constant '%s'", name)
+}
+
+// SurfaceMapped returns whether any constants have a normal map or a texture,
+// which are laid over shapes before they are transformed
+func (t *SymbolTables) SurfaceMapped() bool {
+	for _, constant := range t.constants {
+		if constant.NormalMapFile != "" || constant.Texture != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// Curve returns the timing curve with the given name, defined by the script or
+// built in
+func (t *SymbolTables) Curve(name string) (Curve, error) {
+	if curve, found := t.curves[name]; found {
+		return curve, nil
+	}
+	if curve, found := namedCurves[name]; found {
+		return curve, nil
+	}
+	return nil, fmt.Errorf("undefined curve '%s'", name)
+}
+
+// WriteKnobs writes the value of every knob in each of the frames as CSV, with
+// a row for each frame and a column for each knob in alphabetical order
+func (t *SymbolTables) WriteKnobs(w io.Writer, frames int) error {
+	names := make([]string, 0, len(t.knobs))
+	for name := range t.knobs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	writer := csv.NewWriter(w)
+	writer.Write(append([]string{"frame"}, names...))
+	for frame := 0; frame < frames; frame++ {
+		row := []string{strconv.Itoa(frame)}
+		for _, name := range names {
+			value, err := t.Knob(name, frame)
+			if err != nil {
+				return err
+			}
+			row = append(row, strconv.FormatFloat(value, 'g', -1, 64))
+		}
+		writer.Write(row)
+	}
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,289 @@
+package parser
+
+import (
+	"fmt"
+)
+
+// TokenType is used for representing different tokens
+type TokenType int
+
+const (
+	tEOF        TokenType = iota // end of file
+	tError                       // error has occurred
+	tComment                     // comment
+	tInt                         // integer
+	tFloat                       // floating point
+	tIdent                       // identifier
+	tString                      // string
+	tNewline                     // new line
+	tMacroEnd                    // end of the commands of a called macro
+	tIncludeEnd                  // end of the commands of an included script
+	tIllegal
+
+	keywordBeginning
+	LINE
+	SCALE
+	MOVE
+	ROTATE
+	XAXIS
+	YAXIS
+	ZAXIS
+	SAVE
+	DISPLAY
+	CIRCLE
+	HERMITE
+	BEZIER
+	BOX
+	CLEAR
+	SPHERE
+	TORUS
+	PUSH
+	POP
+	VARY
+	BASENAME
+	FRAMES
+	SET
+	SETKNOBS
+	MESH
+	LIGHT
+	AMBIENT
+	CONSTANTS
+	ZEPSILON
+	ZOFFSET
+	GROUP
+	END
+	HIDE
+	SHOW
+	SNAPSHOT
+	RESTORE
+	LAYER
+	CLIP
+	LOD
+	SCENE
+	AUDIO
+	VIEWPORT
+	SHADING
+	RENDERMODE
+	LET
+	DEFINE
+	CALL
+	INCLUDE
+	RESOLUTION
+	SHADOWS
+	CURVE
+	CULLING
+	WINDING
+	SAVEDEPTH
+	SAVECS
+	SAVE_KNOBS
+	TWEEN
+	OBJECT
+	INSTANCE
+	QUALITY
+	BACKGROUND
+	COLOR
+	EXPOSURE
+	TONEMAP
+	GAMMA
+	FPS
+	DURATION
+	SAVEFRAME
+	ZCLIP
+	LINEWIDTH
+	ELLIPSE
+	ARC
+	PROFILE
+	SWEEP
+	STAMP
+	SEED
+	TERRAIN
+	DISPLACE
+	METABALLS
+	LSYSTEM
+	EMITTER
+	PARTICLES
+	VARY_SPRING
+	VARY_BOUNCE
+	VARY_DAMP
+	KNOBDATA
+	PROJECTION
+	SSAO
+	ENVMAP
+	SKYBOX
+	SKYGRADIENT
+	RENDERTARGET
+	USETEXTURE
+	PICK
+	DEBUG
+	AXES
+	GRID
+	ROTATEQ
+	MATRIX
+	MIRROR
+	SYMMETRY
+	CAMERA
+	CAMERAPATH
+	keywordEnd
+)
+
+var tokens = map[TokenType]string{
+	tEOF:        "EOF",
+	tError:      "ERROR",
+	tComment:    "COMMENT",
+	tInt:        "INT",
+	tFloat:      "FLOAT",
+	tIdent:      "IDENTIFIER",
+	tString:     "STRING",
+	tIllegal:    "ILLEGAL",
+	tNewline:    "NEWLINE",
+	tMacroEnd:   "MACROEND",
+	tIncludeEnd: "INCLUDEEND",
+
+	LINE:         "line",
+	SCALE:        "scale",
+	MOVE:         "move",
+	ROTATE:       "rotate",
+	XAXIS:        "x",
+	YAXIS:        "y",
+	ZAXIS:        "z",
+	SAVE:         "save",
+	DISPLAY:      "display",
+	CIRCLE:       "circle",
+	HERMITE:      "hermite",
+	BEZIER:       "bezier",
+	BOX:          "box",
+	CLEAR:        "clear",
+	SPHERE:       "sphere",
+	TORUS:        "torus",
+	PUSH:         "push",
+	POP:          "pop",
+	VARY:         "vary",
+	BASENAME:     "basename",
+	FRAMES:       "frames",
+	SET:          "set",
+	SETKNOBS:     "setknobs",
+	MESH:         "mesh",
+	LIGHT:        "light",
+	AMBIENT:      "ambient",
+	CONSTANTS:    "constants",
+	ZEPSILON:     "zepsilon",
+	ZOFFSET:      "zoffset",
+	GROUP:        "group",
+	END:          "end",
+	HIDE:         "hide",
+	SHOW:         "show",
+	SNAPSHOT:     "snapshot",
+	RESTORE:      "restore",
+	LAYER:        "layer",
+	CLIP:         "clip",
+	LOD:          "lod",
+	SCENE:        "scene",
+	AUDIO:        "audio",
+	VIEWPORT:     "viewport",
+	SHADING:      "shading",
+	RENDERMODE:   "rendermode",
+	LET:          "let",
+	DEFINE:       "define",
+	CALL:         "call",
+	INCLUDE:      "include",
+	RESOLUTION:   "resolution",
+	SHADOWS:      "shadows",
+	CURVE:        "curve",
+	CULLING:      "culling",
+	WINDING:      "winding",
+	SAVEDEPTH:    "savedepth",
+	SAVECS:       "savecs",
+	SAVE_KNOBS:   "save_knobs",
+	TWEEN:        "tween",
+	OBJECT:       "object",
+	INSTANCE:     "instance",
+	QUALITY:      "quality",
+	BACKGROUND:   "background",
+	COLOR:        "color",
+	EXPOSURE:     "exposure",
+	TONEMAP:      "tonemap",
+	GAMMA:        "gamma",
+	FPS:          "fps",
+	DURATION:     "duration",
+	SAVEFRAME:    "saveframe",
+	ZCLIP:        "zclip",
+	LINEWIDTH:    "linewidth",
+	ELLIPSE:      "ellipse",
+	ARC:          "arc",
+	PROFILE:      "profile",
+	SWEEP:        "sweep",
+	STAMP:        "stamp",
+	SEED:         "seed",
+	TERRAIN:      "terrain",
+	DISPLACE:     "displace",
+	METABALLS:    "metaballs",
+	LSYSTEM:      "lsystem",
+	EMITTER:      "emitter",
+	PARTICLES:    "particles",
+	VARY_SPRING:  "vary_spring",
+	VARY_BOUNCE:  "vary_bounce",
+	VARY_DAMP:    "vary_damp",
+	KNOBDATA:     "knobdata",
+	PROJECTION:   "projection",
+	SSAO:         "ssao",
+	ENVMAP:       "envmap",
+	SKYBOX:       "skybox",
+	SKYGRADIENT:  "skygradient",
+	RENDERTARGET: "rendertarget",
+	USETEXTURE:   "usetexture",
+	PICK:         "pick",
+	DEBUG:        "debug",
+	AXES:         "axes",
+	GRID:         "grid",
+	ROTATEQ:      "rotateq",
+	MATRIX:       "matrix",
+	MIRROR:       "mirror",
+	SYMMETRY:     "symmetry",
+	CAMERA:       "camera",
+	CAMERAPATH:   "camerapath",
+}
+
+var keywords map[string]TokenType
+
+func init() {
+	keywords = make(map[string]TokenType)
+	for i := keywordBeginning; i < keywordEnd; i++ {
+		keywords[tokens[i]] = i
+	}
+}
+
+// Token is a lexical token
+type Token struct {
+	tt    TokenType // type of token
+	value string    // value of token
+	line  int       // line of the script the token starts on, from 1
+}
+
+func (tt TokenType) String() string {
+	if s, isToken := tokens[tt]; isToken {
+		return s
+	}
+	return fmt.Sprint("token(", int(tt), ")")
+}
+
+func (t Token) String() string {
+	return fmt.Sprintf("{%s %s}", t.tt, t.value)
+}
+
+// StartsBlock returns whether a keyword starts a block of statements that is
+// ended by end, not counting define, whose blocks can't be nested
+func StartsBlock(tt TokenType) bool {
+	switch tt {
+	case GROUP, SCENE, OBJECT, RENDERTARGET, SYMMETRY:
+		return true
+	}
+	return false
+}
+
+// LookupIdent returns the corresponding token type for an identifier
+func LookupIdent(ident string) TokenType {
+	if tok, isKeyword := keywords[ident]; isKeyword {
+		return tok
+	}
+	return tIllegal
+}
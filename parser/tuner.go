@@ -0,0 +1,219 @@
+package parser
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+)
+
+// tunerPage lists every knob with a slider, overriding the knob as the slider
+// moves and showing the preview as it is rendered again
+const tunerPage = `<!DOCTYPE html>
+<html>
+<head><title>Tuner</title>
+<style>
+body { margin: 0; background: #222; color: #ddd; font: 14px sans-serif; display: flex; height: 100vh }
+#knobs { width: 320px; padding: 12px; overflow-y: auto; background: #2b2b2b }
+#knobs label { display: block; margin-top: 10px }
+#knobs input[type=range] { width: 100% }
+#knobs button { float: right; font-size: 11px }
+#view { flex: 1; display: flex; justify-content: center; align-items: center }
+</style>
+</head>
+<body>
+<div id="knobs"></div>
+<div id="view"><img id="frame" style="image-rendering:pixelated"></div>
+<script>
+const panel = document.getElementById("knobs");
+function post(path, params) {
+	return fetch(path, {method: "POST", body: new URLSearchParams(params)});
+}
+function slider(name, min, max, step, value, onInput, onReset) {
+	const label = document.createElement("label");
+	const text = document.createElement("span");
+	const input = document.createElement("input");
+	input.type = "range";
+	input.min = min;
+	input.max = max;
+	input.step = step;
+	input.value = value;
+	text.textContent = name + " = " + value;
+	input.oninput = () => {
+		text.textContent = name + " = " + input.value;
+		onInput(input.value);
+	};
+	label.appendChild(text);
+	if (onReset) {
+		const reset = document.createElement("button");
+		reset.textContent = "reset";
+		reset.onclick = async (e) => {
+			e.preventDefault();
+			await onReset();
+			load();
+		};
+		label.appendChild(reset);
+	}
+	label.appendChild(input);
+	panel.appendChild(label);
+}
+async function load() {
+	const state = await (await fetch("/knobs")).json();
+	panel.replaceChildren();
+	if (state.frames > 1) {
+		slider("frame", 0, state.frames - 1, 1, state.frame, async (value) => {
+			await post("/set", {frame: value});
+			load();
+		});
+	}
+	for (const knob of state.knobs) {
+		slider(knob.name, knob.min, knob.max, (knob.max - knob.min) / 1000, knob.value,
+			(value) => post("/set", {knob: knob.name, value: value}),
+			knob.overridden ? () => post("/reset", {knob: knob.name}) : null);
+	}
+}
+let version = -1;
+const img = document.getElementById("frame");
+async function poll() {
+	try {
+		const response = await fetch("/frame?after=" + version);
+		version = parseInt(response.headers.get("X-Version"));
+		const url = URL.createObjectURL(await response.blob());
+		img.onload = () => URL.revokeObjectURL(url);
+		img.src = url;
+	} catch (e) {
+		await new Promise(resolve => setTimeout(resolve, 1000));
+	}
+	poll();
+}
+load();
+poll();
+</script>
+</body>
+</html>
+`
+
+// tunerKnob is a knob as it is listed by the tuner
+type tunerKnob struct {
+	Name       string  `json:"name"`
+	Min        float64 `json:"min"`
+	Max        float64 `json:"max"`
+	Value      float64 `json:"value"`
+	Overridden bool    `json:"overridden"`
+}
+
+// tunerState is everything the tuner page needs to list its sliders
+type tunerState struct {
+	Frame  int         `json:"frame"`
+	Frames int         `json:"frames"`
+	Knobs  []tunerKnob `json:"knobs"`
+}
+
+// ListenTuner serves a web page with a slider for each knob on address,
+// re-rendering the preview whenever a slider moves, until the server fails
+// The rendered frames are shown through the drawer's preview.
+func (s *ControlServer) ListenTuner(address string) error {
+	if s.drawer.Preview() == nil {
+		return fmt.Errorf("tuner needs a preview to show frames in")
+	}
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return err
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", s.serveTuner)
+	mux.HandleFunc("/frame", s.drawer.Preview().ServeFrame)
+	mux.HandleFunc("/knobs", s.serveKnobs)
+	mux.HandleFunc("/set", s.serveSet)
+	mux.HandleFunc("/reset", s.serveReset)
+	fmt.Printf("Tuning at http://%s\n", listener.Addr())
+	return http.Serve(listener, mux)
+}
+
+func (s *ControlServer) serveTuner(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	fmt.Fprint(w, tunerPage)
+}
+
+// serveKnobs responds with the frame being previewed and the range and current
+// value of every knob
+func (s *ControlServer) serveKnobs(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	state := tunerState{Frame: s.frame, Frames: s.frames, Knobs: []tunerKnob{}}
+	for name, values := range s.tables.knobs {
+		knob := tunerKnob{Name: name, Min: math.Inf(1), Max: math.Inf(-1)}
+		for _, value := range values {
+			knob.Min = math.Min(knob.Min, value)
+			knob.Max = math.Max(knob.Max, value)
+		}
+		if len(values) == 0 {
+			knob.Min, knob.Max = 0, 0
+		}
+		// A knob that never changes can still be tuned around its value
+		if knob.Min == knob.Max {
+			spread := math.Max(math.Abs(knob.Min), 1)
+			knob.Min -= spread
+			knob.Max += spread
+		}
+		knob.Value, _ = s.tables.Knob(name, s.frame)
+		_, knob.Overridden = s.tables.overrides[name]
+		// Keep overridden values within reach of the slider
+		knob.Min = math.Min(knob.Min, knob.Value)
+		knob.Max = math.Max(knob.Max, knob.Value)
+		state.Knobs = append(state.Knobs, knob)
+	}
+	s.mu.Unlock()
+	sort.Slice(state.Knobs, func(i, j int) bool {
+		return state.Knobs[i].Name < state.Knobs[j].Name
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(state)
+}
+
+// serveSet overrides a knob, given by the knob and value parameters, or
+// chooses the frame to preview, given by the frame parameter
+func (s *ControlServer) serveSet(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if frame := r.FormValue("frame"); frame != "" {
+		n, err := strconv.Atoi(frame)
+		if err == nil {
+			err = s.SetFrame(n)
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		}
+		return
+	}
+	name := r.FormValue("knob")
+	if _, found := s.tables.knobs[name]; !found {
+		http.Error(w, fmt.Sprintf("undefined knob '%s'", name), http.StatusBadRequest)
+		return
+	}
+	value, err := strconv.ParseFloat(r.FormValue("value"), 64)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	s.SetKnob(name, value)
+}
+
+// serveReset forgets the override of the knob given by the knob parameter
+func (s *ControlServer) serveReset(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	s.ResetKnob(r.FormValue("knob"))
+}
@@ -0,0 +1,90 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// WatchSettle is how long a watch waits after a change for more changes
+// before rendering again, since editors often save a file in several steps
+const WatchSettle = 100 * time.Millisecond
+
+// Watch renders a script with a parser from newParser, then renders it again
+// whenever it or a file it includes or loads changes, until interrupted
+// Errors in the script are printed rather than stopping the watch, so they can
+// be fixed and saved. Only failing to watch the files stops it.
+func Watch(filename string, newParser func() *Parser) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+	for {
+		parser := newParser()
+		started := time.Now()
+		if err := parser.ParseFile(filename); err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+		} else {
+			fmt.Printf("Rendered %s in %.1fms\n", filename, time.Since(started).Seconds()*1000)
+		}
+		files := append([]string{filename}, parser.Dependencies()...)
+		changed, err := waitForChange(watcher, files)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s changed, rendering again\n", changed)
+	}
+}
+
+// waitForChange blocks until one of the files is written, created, removed,
+// or renamed, and returns its name
+// The directories holding the files are watched rather than the files
+// themselves, since editors often save by replacing a file, which ends any
+// watch on it.
+func waitForChange(watcher *fsnotify.Watcher, files []string) (string, error) {
+	watched := make(map[string]string) // name of each file as given, by absolute path
+	for _, file := range files {
+		path, err := filepath.Abs(file)
+		if err != nil {
+			return "", err
+		}
+		watched[path] = file
+		if err := watcher.Add(filepath.Dir(path)); err != nil {
+			return "", fmt.Errorf("watching %s: %w", file, err)
+		}
+	}
+	for {
+		select {
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return "", errors.New("watch was closed")
+			}
+			file, ok := watched[filepath.Clean(event.Name)]
+			if !ok || event.Op == fsnotify.Chmod {
+				continue
+			}
+			// The rest of the save, to this file or others, is part of the
+			// same change
+			settled := time.After(WatchSettle)
+		settle:
+			for {
+				select {
+				case <-watcher.Events:
+				case <-settled:
+					break settle
+				}
+			}
+			return file, nil
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return "", errors.New("watch was closed")
+			}
+			return "", err
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package render
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// FrameCheckpoint is a file that what has been drawn of a frame is saved to
+// every so often, so that an expensive frame that is interrupted can be resumed
+// instead of rendered again from the start
+// The file starts with a line identifying what is rendered and a line with the
+// number of shapes drawn, followed by the state of the base layer. Only frames
+// drawn on the base layer alone, without panes, are saved.
+type FrameCheckpoint struct {
+	Path     string
+	Key      string        // identifies the script and settings, which must match to resume
+	Interval time.Duration // how often what has been drawn is saved
+}
+
+// SetCheckpoint makes the drawer save what has been drawn of each frame to a
+// checkpoint every so often, or not at all if checkpoint is nil
+func (d *Drawer) SetCheckpoint(checkpoint *FrameCheckpoint) {
+	d.checkpoint = checkpoint
+	d.resumed = 0
+}
+
+// Resume makes the next frame carry on from the checkpoint, if it was saved for
+// the same key, returning the number of shapes it already has or 0 for none
+// The shapes up to the checkpoint are still counted, so that every setting
+// they are drawn with is kept, but not drawn, and the image is put back as it
+// was saved once the last of them comes up.
+func (d *Drawer) Resume() (int, error) {
+	d.resumed = 0
+	if d.checkpoint == nil {
+		return 0, nil
+	}
+	f, err := os.Open(d.checkpoint.Path)
+	if os.IsNotExist(err) {
+		return 0, nil
+	} else if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	d.resumed, err = d.readCheckpointHeader(bufio.NewReader(f))
+	return d.resumed, err
+}
+
+// readCheckpointHeader reads the lines that the checkpoint file starts with,
+// returning the number of shapes it has, or 0 if it was saved for another key
+func (d *Drawer) readCheckpointHeader(r *bufio.Reader) (int, error) {
+	key, err := r.ReadString('\n')
+	if err != nil || strings.TrimSuffix(key, "\n") != d.checkpoint.Key {
+		// A checkpoint cut short before its key was written is as good as none
+		return 0, nil
+	}
+	var shapes int
+	if _, err := fmt.Fscanf(r, "shapes %d\n", &shapes); err != nil {
+		return 0, fmt.Errorf("reading checkpoint %s: %v", d.checkpoint.Path, err)
+	}
+	return shapes, nil
+}
+
+// saveCheckpoint saves what has been drawn to the checkpoint, if there is one
+// and it is time to
+// The checkpoint is written next to the previous one and then replaces it, so
+// an interruption while it is written leaves the previous one intact.
+func (d *Drawer) saveCheckpoint() error {
+	if d.checkpoint == nil || d.shapes == 0 || d.offscreen() || d.layout != LayoutSingle || len(d.layers) > 1 ||
+		time.Since(d.checkpointed) < d.checkpoint.Interval {
+		return nil
+	}
+	path := d.checkpoint.Path
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	f, err := os.Create(path + ".tmp")
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	fmt.Fprintf(w, "%s\nshapes %d\n", d.checkpoint.Key, d.shapes)
+	err = d.layers[0].Image.WriteState(w)
+	if err == nil {
+		err = w.Flush()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path + ".tmp")
+		return fmt.Errorf("saving checkpoint %s: %v", path, err)
+	}
+	d.checkpointed = time.Now()
+	return os.Rename(path+".tmp", path)
+}
+
+// restoreCheckpoint puts the image back as the checkpoint being resumed saved
+// it, after which drawing carries on as usual
+func (d *Drawer) restoreCheckpoint() error {
+	d.resumed = 0
+	f, err := os.Open(d.checkpoint.Path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	r := bufio.NewReader(f)
+	if _, err := d.readCheckpointHeader(r); err != nil {
+		return err
+	}
+	if err := d.layers[0].Image.ReadState(r); err != nil {
+		return fmt.Errorf("reading checkpoint %s: %v", d.checkpoint.Path, err)
+	}
+	return nil
+}
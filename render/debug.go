@@ -0,0 +1,184 @@
+package render
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/james9909/graphics-engine/geometry"
+	"github.com/james9909/graphics-engine/image"
+)
+
+// DebugNormalLength is the length of the face normals drawn by the normals
+// overlay
+const DebugNormalLength = 10
+
+var (
+	// DebugNormalColor is the color of the face normals drawn by the normals
+	// overlay
+	DebugNormalColor = image.Color{R: 255, G: 255, B: 0, A: 255}
+	// DebugBoundsColor is the color of the boxes drawn by the bounds overlay
+	DebugBoundsColor = image.Color{R: 255, G: 0, B: 255, A: 255}
+	// AxisColors are the colors of the x, y, and z axes drawn by axes
+	AxisColors = [3]image.Color{{R: 255, G: 0, B: 0, A: 255}, {R: 0, G: 255, B: 0, A: 255}, {R: 0, G: 0, B: 255, A: 255}}
+	// GridColor is the color of the lines of grids
+	GridColor = image.Color{R: 96, G: 96, B: 96, A: 255}
+)
+
+// DebugOverlay is a set of lines drawn over each shape to show how it is put
+// together
+type DebugOverlay int
+
+const (
+	// DebugNormals draws the normal of each triangle from its centroid,
+	// pointing the way it faces
+	DebugNormals DebugOverlay = 1 << iota
+	// DebugBounds draws the box around each shape along the axes
+	DebugBounds
+)
+
+var debugOverlays = map[string]DebugOverlay{
+	"normals": DebugNormals,
+	"bounds":  DebugBounds,
+}
+
+// ParseDebugOverlay returns the debug overlay with the given name
+func ParseDebugOverlay(name string) (DebugOverlay, error) {
+	if overlay, found := debugOverlays[name]; found {
+		return overlay, nil
+	}
+	return 0, fmt.Errorf("unknown debug overlay '%s'", name)
+}
+
+// SetDebug sets whether an overlay is drawn over the shapes drawn afterwards
+func (d *Drawer) SetDebug(overlay DebugOverlay, on bool) {
+	if on {
+		d.debug |= overlay
+	} else {
+		d.debug &^= overlay
+	}
+}
+
+// overlays returns the lines of the overlays of the shape in the edge matrix,
+// which are drawn once the shape is, or nil for each overlay that is off
+// The edge matrix holds triangles unless lines is set.
+func (d *Drawer) overlays(lines bool) (normals, bounds *geometry.Matrix) {
+	if d.debug&DebugNormals != 0 && !lines {
+		normals = geometry.NewMatrix(4, 0)
+		for i := 0; i+2 < d.em.Cols; i += 3 {
+			p0, p1, p2 := geometry.Vec3Of(d.em.GetColumn(i)), geometry.Vec3Of(d.em.GetColumn(i+1)), geometry.Vec3Of(d.em.GetColumn(i+2))
+			n := geometry.Normal(p0, p1, p2)
+			if n.Length() == 0 {
+				continue
+			}
+			if d.frontWinding() == geometry.WindingClockwise {
+				n = n.Scale(-1)
+			}
+			centroid := p0.Add(p1).Add(p2).Scale(1.0 / 3)
+			tip := centroid.Add(n.Normalize().Scale(DebugNormalLength))
+			normals.AddEdge(centroid[0], centroid[1], centroid[2], tip[0], tip[1], tip[2])
+		}
+	}
+	if d.debug&DebugBounds != 0 && d.em.Cols > 0 {
+		low := geometry.Vec3{math.Inf(1), math.Inf(1), math.Inf(1)}
+		high := geometry.Vec3{math.Inf(-1), math.Inf(-1), math.Inf(-1)}
+		for c := 0; c < d.em.Cols; c++ {
+			for i := range low {
+				low[i] = math.Min(low[i], d.em.Get(i, c))
+				high[i] = math.Max(high[i], d.em.Get(i, c))
+			}
+		}
+		// Each edge joins two corners that differ along a single axis
+		bounds = geometry.NewMatrix(4, 0)
+		for corner := 0; corner < 8; corner++ {
+			for axis := 0; axis < 3; axis++ {
+				if corner&(1<<uint(axis)) != 0 {
+					continue
+				}
+				var a, b geometry.Vec3
+				for i := range a {
+					a[i], b[i] = low[i], low[i]
+					if corner&(1<<uint(i)) != 0 {
+						a[i], b[i] = high[i], high[i]
+					}
+				}
+				b[axis] = high[axis]
+				bounds.AddEdge(a[0], a[1], a[2], b[0], b[1], b[2])
+			}
+		}
+	}
+	return normals, bounds
+}
+
+// withOverlays draws a shape with draw, followed by the overlays that are on
+func (d *Drawer) withOverlays(lines bool, draw func() error) error {
+	if d.debug == 0 {
+		return draw()
+	}
+	normals, bounds := d.overlays(lines)
+	if err := draw(); err != nil {
+		return err
+	}
+	return d.drawOverlays(normals, bounds)
+}
+
+// drawOverlays draws the lines of the overlays of a shape, which have no
+// overlays of their own
+func (d *Drawer) drawOverlays(normals, bounds *geometry.Matrix) error {
+	debug := d.debug
+	d.debug = 0
+	defer func() {
+		d.debug = debug
+	}()
+	if normals != nil && normals.Cols > 0 {
+		d.em = normals
+		if err := d.DrawLines(DebugNormalColor); err != nil {
+			return err
+		}
+	}
+	if bounds != nil {
+		d.em = bounds
+		return d.DrawLines(DebugBoundsColor)
+	}
+	return nil
+}
+
+// Axes draws the x, y, and z axes from the origin, each length long, in red,
+// green, and blue
+func (d *Drawer) Axes(length float64) error {
+	// Draw the axes twice as thick and pull them in front, so that they
+	// cover the lines of grids that run along them
+	offset, width := d.frame.ZOffset, d.lineWidth
+	d.frame.SetDepthOffset(offset + OutlineDepthOffset)
+	d.lineWidth = 2 * width
+	defer func() {
+		d.frame.SetDepthOffset(offset)
+		d.lineWidth = width
+	}()
+	for axis, c := range AxisColors {
+		var tip geometry.Vec3
+		tip[axis] = length
+		if err := d.Line(0, 0, 0, tip[0], tip[1], tip[2]); err != nil {
+			return err
+		}
+		if err := d.DrawLines(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Grid draws a square grid on the ground, size across and centered on the
+// origin in the plane where y is 0, with a line every spacing from the origin
+func (d *Drawer) Grid(size, spacing float64) error {
+	half := size / 2
+	steps := int(math.Floor(half / spacing))
+	for i := -steps; i <= steps; i++ {
+		offset := float64(i) * spacing
+		d.em.AddEdge(offset, 0, -half, offset, 0, half)
+		d.em.AddEdge(-half, 0, offset, half, 0, offset)
+	}
+	if err := d.apply(); err != nil {
+		return err
+	}
+	return d.DrawLines(GridColor)
+}
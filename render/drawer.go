@@ -0,0 +1,1540 @@
+// Package render draws shapes onto the layers of an image through a Drawer,
+// which keeps the coordinate system stack and every drawing setting
+package render
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/james9909/graphics-engine/geometry"
+	"github.com/james9909/graphics-engine/image"
+)
+
+const (
+	// MinCircularSteps is the fewest segments level of detail reduces curves to
+	MinCircularSteps = 6
+	// MaxCircularSteps is the most segments level of detail increases curves to
+	MaxCircularSteps = 100
+)
+
+// OutlineDepthOffset is how far outlines are pulled in front of the polygons
+// they outline when both are drawn
+const OutlineDepthOffset = 1
+
+// DrawMode defines the type of each drawing mode
+type DrawMode int
+
+// RenderMode defines how polygons are drawn
+type RenderMode int
+
+const (
+	// RenderAuto fills shaded polygons and outlines the others
+	RenderAuto RenderMode = iota
+	// RenderWireframe only draws the outlines of polygons
+	RenderWireframe
+	// RenderSolid only fills polygons
+	RenderSolid
+	// RenderBoth fills polygons and draws their outlines over them
+	RenderBoth
+)
+
+var renderModes = map[string]RenderMode{
+	"wireframe": RenderWireframe,
+	"solid":     RenderSolid,
+	"both":      RenderBoth,
+}
+
+// ParseRenderMode returns the render mode with the given name
+func ParseRenderMode(name string) (RenderMode, error) {
+	if mode, found := renderModes[name]; found {
+		return mode, nil
+	}
+	return RenderAuto, fmt.Errorf("unknown render mode '%s'", name)
+}
+
+// Drawer is a struct that draws on an image
+type Drawer struct {
+	frame          *image.Image     // image of the current layer
+	layers         []*image.Layer   // layers in the order they are composited
+	em             *geometry.Matrix // edge/polygon matrix
+	cs             *geometry.Stack  // coordinate system stack
+	viewport       geometry.Mat4    // transformation from script coordinates to image coordinates
+	projection     geometry.Projection
+	camera         *geometry.Camera      // where everything is seen from in image coordinates, if not straight on
+	viewRect       geometry.Rect         // part of the image drawn into, or the zero Rect for all of it
+	clips          [][]geometry.Plane    // clipping planes of each coordinate system in the stack
+	near, far      float64               // depths that everything drawn is clipped between
+	lineWidth      float64               // width of lines in pixels
+	baseLineWidth  float64               // width of lines until the script sets one
+	snapLines      bool                  // whether the ends of lines are snapped to whole pixels
+	displaceSize   float64               // size of the features of noise that surfaces are displaced by
+	displaceAmount float64               // how far surfaces are displaced, or 0 for not at all
+	aoRadius       float64               // distance in pixels that surfaces hide each other within
+	aoStrength     float64               // how much hidden surfaces are darkened, or 0 for not at all
+	environment    *image.EnvironmentMap // surroundings that reflective shapes mirror, if any
+	texture        *image.Image          // image that shaded shapes are covered with, if any
+	debug          DebugOverlay          // overlays drawn over shapes
+	surface        bool                  // whether points carry their coordinates before transformation, for normal maps and textures
+	lodPixels      float64               // target length in pixels of curved segments, or 0 to disable level of detail
+	segments       int                   // segments of curved primitives when level of detail is disabled
+	background     image.Color           // color of the base layer where nothing is drawn
+	sky            Sky                   // what the base layer shows where nothing is drawn instead of the background, if anything
+	skybox         *image.EnvironmentMap // environment map of the sky, if it has one
+	color          image.Color           // color of lines and polygons drawn without their own color or constants
+	shading        image.ShadingMode
+	renderMode     RenderMode
+	colorTransform image.ColorTransform // how the light reflected by shaded polygons becomes color
+	culling        geometry.CullMode
+	winding        geometry.Winding
+	mirrored       bool // whether the edge matrix was transformed by a reflection, which reverses its winding
+
+	dither   image.DitherMode // dithering applied when reducing the color depth
+	levels   int              // levels per color channel of saved images
+	paletted bool             // whether saved images end up in a palette-limited format
+
+	preview   *Preview     // preview that frames are shown in, if any
+	stats     bool         // whether to stamp render statistics onto saved images
+	stamp     image.Corner // corner the frame number and time are stamped in
+	stampSize int          // scale of the stamped frame number and time, or 0 for none
+	frameRate float64      // frames per second, for stamping the time of frames
+	frameNum  int          // frame being rendered
+	started   time.Time    // when rendering of the frame started
+	triangles int          // number of triangles drawn in the frame
+	shapes    int          // number of shapes drawn in the frame, which is the ID of the last
+	drawn     []drawnShape // each shape drawn in the frame, by ID from 1
+	source    string       // statement drawing the shapes drawn now
+	passes    bool         // whether depth, normal, and shape ID passes are saved with the image
+	ordered   bool         // whether lights are added up in order of their names, so that renders are reproducible
+	picking   bool         // whether what covers each pixel is kept for Pick
+
+	checkpoint   *FrameCheckpoint // file that what has been drawn is saved to every so often, if any
+	checkpointed time.Time        // when what has been drawn was last saved to the checkpoint
+	resumed      int              // shapes of the checkpoint being resumed, which are skipped until the last
+
+	shadows    map[string]*image.ShadowMap // shadow map of each light, if objects cast shadows
+	shadowPass bool                        // whether the shadow maps are being rendered
+
+	layout        Layout         // how each frame is drawn in panes
+	pane          *Pane          // pane being drawn, if any
+	offscreenPane bool           // whether the pane being drawn is kept off the image until the last is drawn
+	finishedPanes []finishedPane // panes drawn before the one being drawn
+
+	hidden            map[string]bool          // groups that are not drawn
+	snapshots         map[string]drawerState   // saved states of the drawer
+	coordinateSystems map[string]geometry.Mat4 // coordinate systems saved with savecs
+
+	pool         *image.ImagePool                        // images of layers from earlier frames, reused for new layers
+	geometry     map[geometryKey]*geometry.Matrix        // tessellated primitives and loaded meshes, reused across frames
+	environments map[string]*image.EnvironmentMap        // environment maps that have been read, reused across frames
+	normalMaps   map[image.NormalMapKey]*image.NormalMap // normal maps that have been read, reused across frames
+	targets      map[string]*image.Image                 // images drawn by render targets
+	objects      map[string][]recordedShape              // geometry of the objects instanced in the frame
+	recording    *[]recordedShape                        // shapes of the object being recorded, if any
+	recorded     map[string]bool                         // objects being recorded, innermost included
+}
+
+// recordedShape is geometry drawn by an object, in the object's coordinates
+type recordedShape struct {
+	em       *geometry.Matrix
+	draw     func() error // draws the edge matrix the way the shape was drawn
+	source   string       // statement in the object that drew the shape
+	mirrored bool         // whether the shape was reflected in the object's own coordinate system
+}
+
+// drawerState is a snapshot of the coordinate system stack and the image
+type drawerState struct {
+	frame *image.Image
+	cs    *geometry.Stack
+	clips [][]geometry.Plane
+}
+
+func NewDrawer(height, width int) *Drawer {
+	return NewDrawerOn(image.NewTiledImage(height, width))
+}
+
+// NewDrawerOn returns a Drawer whose base layer is image, which should be tiled
+func NewDrawerOn(img *image.Image) *Drawer {
+	base := &image.Layer{
+		Name:    image.BaseLayer,
+		Image:   img,
+		Opacity: 1,
+	}
+	return &Drawer{
+		frame:         base.Image,
+		layers:        []*image.Layer{base},
+		em:            geometry.NewMatrix(4, 0),
+		cs:            geometry.NewStack(),
+		viewport:      geometry.Identity(),
+		near:          math.Inf(1),
+		far:           math.Inf(-1),
+		lineWidth:     1,
+		baseLineWidth: 1,
+		segments:      geometry.DefaultCircularSteps,
+		color:         image.White,
+		levels:        256,
+
+		colorTransform: image.DefaultColorTransform,
+
+		hidden:    make(map[string]bool),
+		snapshots: make(map[string]drawerState),
+
+		coordinateSystems: make(map[string]geometry.Mat4),
+
+		pool:         &image.ImagePool{},
+		geometry:     make(map[geometryKey]*geometry.Matrix),
+		environments: make(map[string]*image.EnvironmentMap),
+		normalMaps:   make(map[image.NormalMapKey]*image.NormalMap),
+		targets:      make(map[string]*image.Image),
+		objects:      make(map[string][]recordedShape),
+		recorded:     make(map[string]bool),
+	}
+}
+
+func (d *Drawer) apply() error {
+	if (d.surface || d.texture != nil) && d.em.Rows == 4 {
+		// Keep the untransformed points, which normal maps and textures are
+		// laid over so that they move with the shape
+		rows := d.em.GetMatrix()
+		d.em = geometry.NewMatrixFromData(append(rows[:4:4], rows[0], rows[1], rows[2]))
+	}
+	transform := d.transform()
+	d.mirrored = transform.Mirrors()
+	d.em = transform.Apply(d.em)
+	return nil
+}
+
+// ApplySurface displaces the triangles of a surface by noise, if surfaces are
+// displaced, before transforming them like apply
+// Displacing them first keeps the noise fixed to the surface as it moves.
+func (d *Drawer) ApplySurface() error {
+	if d.displaceAmount != 0 {
+		d.em.Displace(d.displaceSize, d.displaceAmount)
+	}
+	return d.apply()
+}
+
+func (d *Drawer) DrawLines(c image.Color) error {
+	if d.recording != nil {
+		return d.recordShape(func() error {
+			return d.DrawLines(c)
+		})
+	}
+	return d.withOverlays(true, func() error {
+		em := geometry.ClipEdges(d.em, d.clipPlanes())
+		em = d.project(em, geometry.ClipEdges)
+		em = geometry.ClipEdges(em, d.viewPlanes())
+		d.clear()
+		if em.Cols == 0 || d.shadowPass {
+			// Everything was clipped away, and lines cast no shadows
+			return nil
+		}
+		if draw, err := d.nextShape(true); !draw {
+			return err
+		}
+		return d.frame.DrawLines(d.snapped(em), c, d.lineWidth)
+	})
+}
+
+// DrawPolygons draws the polygons with a single color, as outlines unless the
+// render mode says otherwise
+func (d *Drawer) DrawPolygons(c image.Color) error {
+	if d.recording != nil {
+		return d.recordShape(func() error {
+			return d.DrawPolygons(c)
+		})
+	}
+	mode := d.renderMode
+	if mode == RenderAuto {
+		mode = RenderWireframe
+	}
+	return d.withOverlays(false, func() error {
+		return d.drawPolygons(mode, c, func(em *geometry.Matrix) error {
+			return d.frame.FillPolygons(em, c)
+		})
+	})
+}
+
+// DrawShadedPolygons draws the polygons lit by the light sources, evaluating
+// the lighting as the shading mode defines
+// The polygons are filled unless the render mode says otherwise, and outlined
+// with c.
+func (d *Drawer) DrawShadedPolygons(ambient geometry.Vec3, material image.Material, lightSources map[string]image.LightSource, mode image.ShadingMode, c image.Color) error {
+	if d.recording != nil {
+		return d.recordShape(func() error {
+			return d.DrawShadedPolygons(ambient, material, lightSources, mode, c)
+		})
+	}
+	renderMode := d.renderMode
+	if renderMode == RenderAuto {
+		renderMode = RenderSolid
+	}
+	lightSources = d.viewLights(lightSources)
+	material.Environment = d.environment
+	material.LightOrder = d.lightOrder(lightSources)
+	if d.texture != nil && d.em.Rows >= 7 {
+		material.Image = d.texture
+		low, high := surfaceBounds(d.em)
+		material.ImageBounds = [2]geometry.Vec3{low, high}
+	}
+	if material.NormalMapFile != "" {
+		var err error
+		if material.NormalMap, err = d.normalMap(material.NormalMapFile, material.NormalMapSize); err != nil {
+			return err
+		}
+	}
+	return d.withOverlays(false, func() error {
+		return d.drawPolygons(renderMode, c, func(em *geometry.Matrix) error {
+			return d.frame.DrawShadedPolygons(em, ambient, material, lightSources, mode, d.shadows, d.colorTransform)
+		})
+	})
+}
+
+// DrawShadedLines draws the lines in the color that a surface of the material
+// facing the viewer is lit, since lines have no surface of their own to turn
+// towards the lights
+func (d *Drawer) DrawShadedLines(ambient geometry.Vec3, material image.Material, lightSources map[string]image.LightSource) error {
+	lightSources = d.viewLights(lightSources)
+	material.LightOrder = d.lightOrder(lightSources)
+	ambient, material, lightSources = d.colorTransform.Prepare(ambient, material, lightSources)
+	I := image.Lighting(image.DefaultViewVector, ambient, material, image.DefaultViewVector, lightSources)
+	alpha := byte(geometry.Clamp(material.Opacity*255+0.5, 0, 255))
+	return d.DrawLines(d.colorTransform.Encode(I, alpha))
+}
+
+// viewLights returns the lights turned the way the camera turns the scene, so
+// that they stay where they are in the scene as the camera moves
+func (d *Drawer) viewLights(lightSources map[string]image.LightSource) map[string]image.LightSource {
+	if d.camera == nil {
+		return lightSources
+	}
+	view := d.cameraView()
+	turned := make(map[string]image.LightSource, len(lightSources))
+	for name, light := range lightSources {
+		location := light.Location
+		for i := range light.Location {
+			light.Location[i] = view.At(i, 0)*location[0] + view.At(i, 1)*location[1] + view.At(i, 2)*location[2]
+		}
+		turned[name] = light
+	}
+	return turned
+}
+
+// lightOrder returns the names of the lights in the order that their light is
+// added up, which is sorted when rendering must be deterministic, or nil for
+// any order
+func (d *Drawer) lightOrder(lightSources map[string]image.LightSource) []string {
+	if !d.ordered {
+		return nil
+	}
+	order := make([]string, 0, len(lightSources))
+	for name := range lightSources {
+		order = append(order, name)
+	}
+	sort.Strings(order)
+	return order
+}
+
+// drawPolygons clips the polygons and draws them as the render mode defines,
+// filling them with fill and outlining them with c
+func (d *Drawer) drawPolygons(mode RenderMode, c image.Color, fill func(em *geometry.Matrix) error) error {
+	em := geometry.ClipPolygons(d.em, d.clipPlanes())
+	em = d.project(em, geometry.ClipPolygons)
+	em = geometry.ClipPolygons(em, d.viewPlanes())
+	d.clear()
+	if em.Cols == 0 {
+		return nil
+	}
+	if d.shadowPass {
+		// Only filled polygons cast shadows
+		if mode == RenderSolid || mode == RenderBoth {
+			for _, m := range d.shadows {
+				m.Cast(em)
+			}
+		}
+		return nil
+	}
+	d.triangles += em.Cols / 3
+	if draw, err := d.nextShape(false); !draw {
+		return err
+	}
+	if winding := d.frontWinding(); d.culling != geometry.CullBack || winding != geometry.WindingCounterClockwise {
+		em = geometry.Orient(em, d.culling, winding)
+		if em.Cols == 0 {
+			return nil
+		}
+	}
+	if mode == RenderSolid || mode == RenderBoth {
+		if err := fill(em); err != nil {
+			return err
+		}
+	}
+	if mode == RenderWireframe || mode == RenderBoth {
+		if mode == RenderBoth {
+			// Pull the outlines in front of the fill they are drawn over
+			offset := d.frame.ZOffset
+			d.frame.SetDepthOffset(offset + OutlineDepthOffset)
+			defer d.frame.SetDepthOffset(offset)
+		}
+		return d.frame.DrawPolygons(d.snapped(em), c, d.lineWidth)
+	}
+	return nil
+}
+
+// nextShape gives the shape about to be drawn the next ID of the frame,
+// returning whether it still needs to be drawn, which it doesn't if the
+// checkpoint being resumed already has it
+func (d *Drawer) nextShape(lines bool) (bool, error) {
+	if err := d.saveCheckpoint(); err != nil {
+		return false, err
+	}
+	d.shapes++
+	d.drawn = append(d.drawn, drawnShape{source: d.source, lines: lines})
+	d.frame.SetShapeID(d.shapes)
+	if d.shapes < d.resumed {
+		return false, nil
+	} else if d.shapes == d.resumed {
+		return false, d.restoreCheckpoint()
+	}
+	return true, nil
+}
+
+// snapped returns the points of em rounded to whole pixels if lines are
+// snapped, or em itself otherwise
+func (d *Drawer) snapped(em *geometry.Matrix) *geometry.Matrix {
+	if !d.snapLines {
+		return em
+	}
+	snapped := geometry.NewMatrix(em.Rows, 0)
+	for i := 0; i < em.Cols; i++ {
+		p := em.GetColumn(i)
+		p[0], p[1] = math.Round(p[0]), math.Round(p[1])
+		snapped.AddColumn(p)
+	}
+	return snapped
+}
+
+// BeginShadowPass starts rendering the shadow maps of the lights, during which
+// nothing is drawn onto the image or saved
+func (d *Drawer) BeginShadowPass(lights map[string]image.LightSource, size int) {
+	d.shadows = make(map[string]*image.ShadowMap, len(lights))
+	for name, light := range d.viewLights(lights) {
+		d.shadows[name] = image.NewShadowMap(light, d.frame.Height, d.frame.Width, size)
+	}
+	d.shadowPass = true
+}
+
+// EndShadowPass finishes rendering the shadow maps, which shade everything lit
+// afterwards until the next shadow pass or ClearShadows
+func (d *Drawer) EndShadowPass() {
+	d.shadowPass = false
+}
+
+// InShadowPass returns whether the shadow maps are being rendered, during
+// which nothing is drawn onto the image
+func (d *Drawer) InShadowPass() bool {
+	return d.shadowPass
+}
+
+// ClearShadows discards the shadow maps, so nothing is shadowed
+func (d *Drawer) ClearShadows() {
+	d.shadows = nil
+}
+
+// SetRenderMode sets whether polygons drawn afterwards are outlined, filled, or
+// both
+func (d *Drawer) SetRenderMode(mode RenderMode) {
+	d.renderMode = mode
+}
+
+// SetCulling sets which faces of polygons drawn afterwards are skipped
+func (d *Drawer) SetCulling(mode geometry.CullMode) {
+	d.culling = mode
+}
+
+// SetWinding sets the vertex order of the front faces of polygons drawn
+// afterwards
+func (d *Drawer) SetWinding(winding geometry.Winding) {
+	d.winding = winding
+}
+
+// frontWinding returns the vertex order of the front faces of the polygons in
+// the edge matrix, which a reflection reverses
+func (d *Drawer) frontWinding() geometry.Winding {
+	if !d.mirrored {
+		return d.winding
+	}
+	if d.winding == geometry.WindingClockwise {
+		return geometry.WindingCounterClockwise
+	}
+	return geometry.WindingClockwise
+}
+
+// Clip clips everything drawn afterwards against a plane ax + by + cz + d = 0
+// in the current coordinate system, keeping the side the normal points to
+// The plane is discarded when the coordinate system is popped.
+func (d *Drawer) Clip(a, b, c, dist float64) error {
+	if len(d.clips) == 0 {
+		return errors.New("clip requires a coordinate system: push first")
+	}
+	plane := geometry.TransformPlane(geometry.Plane{a, b, c, dist}, d.transform())
+	last := len(d.clips) - 1
+	d.clips[last] = append(d.clips[last], plane)
+	return nil
+}
+
+// ClearClip removes the clipping planes of the current coordinate system
+func (d *Drawer) ClearClip() {
+	if len(d.clips) > 0 {
+		d.clips[len(d.clips)-1] = nil
+	}
+}
+
+// SetDepthClip clips everything drawn afterwards to the depths between near
+// and far, where z increases towards the viewer, so infinite depths clip
+// nothing
+func (d *Drawer) SetDepthClip(near, far float64) error {
+	if near <= far {
+		return errors.New("the near depth must be greater than the far depth")
+	}
+	d.near, d.far = near, far
+	return nil
+}
+
+// viewPlanes returns the planes bounding what can be seen of the image
+// Shadows are cast by everything within the clipped depths, including what is
+// off screen.
+func (d *Drawer) viewPlanes() []geometry.Plane {
+	r := d.drawRect()
+	if r == (geometry.Rect{}) || d.shadowPass {
+		return geometry.ViewPlanes(d.frame.Height, d.frame.Width, d.near, d.far, !d.shadowPass)
+	}
+	// Nothing is drawn outside of the viewport, so its sides are clipped
+	// against exactly
+	return append(geometry.ViewPlanes(d.frame.Height, d.frame.Width, d.near, d.far, false),
+		geometry.Plane{1, 0, 0, -r.X},
+		geometry.Plane{-1, 0, 0, r.X + r.W},
+		geometry.Plane{0, 1, 0, -r.Y},
+		geometry.Plane{0, -1, 0, r.Y + r.H},
+	)
+}
+
+// project returns geometry in image coordinates as the projection sees it,
+// scaled onto the viewport, clipping it with clip to the depths a perspective
+// projection can see first
+// Geometry is seen from the camera, if any, then turned to face the pane being
+// drawn, if any, which may project it differently than the script.
+func (d *Drawer) project(em *geometry.Matrix, clip func(*geometry.Matrix, []geometry.Plane) *geometry.Matrix) *geometry.Matrix {
+	projection := d.paneProjection()
+	if d.camera != nil {
+		em = d.cameraView().Apply(em)
+	}
+	if d.pane != nil {
+		em = d.pane.view.Apply(em)
+	}
+	r := d.drawRect()
+	if !projection.Perspective && r == (geometry.Rect{}) {
+		return em
+	}
+	height, width := d.frame.Height, d.frame.Width
+	if projection.Perspective {
+		em = clip(em, projection.Planes(height))
+	}
+	sx, sy, sz := 1.0, 1.0, 1.0
+	if r != (geometry.Rect{}) {
+		sx, sy = r.W/float64(width), r.H/float64(height)
+		sz = math.Sqrt(sx * sy)
+	}
+	projected := geometry.NewMatrix(em.Rows, 0)
+	for i := 0; i < em.Cols; i++ {
+		p := em.GetColumn(i)
+		if projection.Perspective {
+			p = projection.Project(p, height, width)
+		}
+		p[0], p[1], p[2] = r.X+p[0]*sx, r.Y+p[1]*sy, p[2]*sz
+		projected.AddColumn(p)
+	}
+	return projected
+}
+
+// SetProjection sets how everything drawn afterwards is projected onto the
+// image
+func (d *Drawer) SetProjection(projection geometry.Projection) {
+	d.projection = projection
+	if d.skybox != nil {
+		// The sky is seen through the projection's field of view
+		d.paintSky()
+	}
+}
+
+// SetCamera makes everything drawn afterwards seen from the eye of the camera,
+// looking towards its aim, both given in the coordinates of the viewport, or
+// straight on as without a camera if camera is nil
+func (d *Drawer) SetCamera(camera *geometry.Camera) error {
+	if camera == nil {
+		d.camera = nil
+		if d.skybox != nil {
+			d.paintSky()
+		}
+		return nil
+	}
+	return d.lookFrom(geometry.Camera{Eye: d.toImage(camera.Eye), Aim: d.toImage(camera.Aim)})
+}
+
+// MoveCamera moves the eye of the camera to eye, given in the coordinates of
+// the viewport, keeping the camera looking towards its aim
+func (d *Drawer) MoveCamera(eye geometry.Vec3) error {
+	if d.camera == nil {
+		return errors.New("there is no camera to move")
+	}
+	return d.lookFrom(geometry.Camera{Eye: d.toImage(eye), Aim: d.camera.Aim})
+}
+
+// lookFrom makes everything drawn afterwards seen from a camera in image
+// coordinates
+func (d *Drawer) lookFrom(camera geometry.Camera) error {
+	if _, err := camera.View(d.projection, d.frame.Height, d.frame.Width); err != nil {
+		return err
+	}
+	d.camera = &camera
+	if d.skybox != nil {
+		// The sky turns with the camera
+		d.paintSky()
+	}
+	return nil
+}
+
+// toImage returns a point in the coordinates of the viewport in image
+// coordinates
+func (d *Drawer) toImage(point geometry.Vec3) geometry.Vec3 {
+	return d.viewport.MulVec4(geometry.Vec4{point[0], point[1], point[2], 1}).XYZ()
+}
+
+// cameraView returns the transformation that moves what the camera sees to
+// where the projection of the pane being drawn sees it, or the identity
+// without a camera
+func (d *Drawer) cameraView() geometry.Mat4 {
+	if d.camera == nil {
+		return geometry.Identity()
+	}
+	// SetCamera has already made sure that the camera has a view
+	view, _ := d.camera.View(d.paneProjection(), d.frame.Height, d.frame.Width)
+	return view
+}
+
+// SetViewportRect sets the part of the image that everything drawn afterwards
+// is scaled into, where the zero Rect is the whole image
+func (d *Drawer) SetViewportRect(r geometry.Rect) {
+	d.viewRect = r
+}
+
+// clipPlanes returns the clipping planes of the current coordinate system
+func (d *Drawer) clipPlanes() []geometry.Plane {
+	if len(d.clips) == 0 {
+		return nil
+	}
+	return d.clips[len(d.clips)-1]
+}
+
+// transform returns the transformation from the current coordinate system to
+// image coordinates
+func (d *Drawer) transform() geometry.Mat4 {
+	return d.viewport.Mul(d.cs.Peek())
+}
+
+// SetViewport sets the coordinate convention of everything drawn afterwards
+func (d *Drawer) SetViewport(v geometry.Viewport) {
+	d.viewport = v.Matrix(d.frame.Height, d.frame.Width)
+}
+
+// BeginFrame starts rendering a new frame, resetting its statistics
+func (d *Drawer) BeginFrame(frame int) {
+	d.objects = make(map[string][]recordedShape)
+	d.frameNum = frame
+	d.started = time.Now()
+	d.checkpointed = d.started
+	d.triangles = 0
+	d.shapes = 0
+	d.drawn = d.drawn[:0]
+}
+
+// SetDeterministic sets whether every render of a script comes out the same
+// bit for bit, by adding up the light of each light source in the order of
+// their names rather than in any order
+func (d *Drawer) SetDeterministic(deterministic bool) {
+	d.ordered = deterministic
+}
+
+// SetPaletted sets whether saved images end up in a palette-limited format,
+// such as the frames of an animated gif
+func (d *Drawer) SetPaletted(paletted bool) {
+	d.paletted = paletted
+}
+
+// SetStats sets whether render statistics are stamped onto saved images
+func (d *Drawer) SetStats(stats bool) {
+	d.stats = stats
+}
+
+// SetStamp stamps the number and time of the frame onto saved images, in a
+// corner at the given scale, where the time is found from the frame rate
+func (d *Drawer) SetStamp(corner image.Corner, scale int, frameRate float64) {
+	d.stamp = corner
+	d.stampSize = scale
+	d.frameRate = frameRate
+}
+
+// SetLineWidth sets the width in pixels of lines and wireframes drawn
+// afterwards
+func (d *Drawer) SetLineWidth(width float64) {
+	d.lineWidth = width
+}
+
+// SetDefaultLineWidth sets the width in pixels of lines and wireframes drawn
+// before the script sets one, which they return to when the drawer is reset
+func (d *Drawer) SetDefaultLineWidth(width float64) {
+	d.lineWidth = width
+	d.baseLineWidth = width
+}
+
+// SetDisplacement sets how far surfaces drawn from now on are displaced along
+// their normals by noise with features about size apart, where an amount of 0
+// leaves them alone
+func (d *Drawer) SetDisplacement(size, amount float64) {
+	d.displaceSize = size
+	d.displaceAmount = amount
+}
+
+// SetEnvironmentMap sets the image of the surroundings that reflective shapes
+// drawn afterwards mirror, where "" is none
+func (d *Drawer) SetEnvironmentMap(filename string) error {
+	if filename == "" {
+		d.environment = nil
+		return nil
+	}
+	environment, found := d.environments[filename]
+	if !found {
+		var err error
+		if environment, err = image.ReadEnvironmentMap(filename); err != nil {
+			return err
+		}
+		d.environments[filename] = environment
+	}
+	d.environment = environment
+	return nil
+}
+
+// SetSurfaceCoordinates sets whether shapes drawn afterwards keep their
+// coordinates from before they were transformed, which normal maps and
+// textures need
+func (d *Drawer) SetSurfaceCoordinates(surface bool) {
+	d.surface = surface
+}
+
+// normalMap returns the normal map read from a file, reading it only once
+func (d *Drawer) normalMap(filename string, size float64) (*image.NormalMap, error) {
+	key := image.NormalMapKey{Filename: filename, Size: size}
+	normalMap, found := d.normalMaps[key]
+	if !found {
+		var err error
+		if normalMap, err = image.ReadNormalMap(filename, size); err != nil {
+			return nil, err
+		}
+		d.normalMaps[key] = normalMap
+	}
+	return normalMap, nil
+}
+
+// SetAmbientOcclusion sets how much the image is darkened where its surfaces
+// are hidden by the surfaces within radius pixels of them when it is written
+// out, where a strength of 0 leaves it alone
+func (d *Drawer) SetAmbientOcclusion(radius, strength float64) {
+	d.aoRadius = radius
+	d.aoStrength = strength
+}
+
+// SetSnapLines sets whether the ends of lines and wireframes are snapped to
+// whole pixels, as integer line drawing does, instead of being drawn with
+// sub-pixel accuracy so that slowly moving lines don't jitter
+func (d *Drawer) SetSnapLines(snap bool) {
+	d.snapLines = snap
+}
+
+// SetShading sets how lighting is evaluated across shaded polygons drawn
+// afterwards
+func (d *Drawer) SetShading(mode image.ShadingMode) {
+	d.shading = mode
+}
+
+// Shading returns how lighting is evaluated across shaded polygons
+func (d *Drawer) Shading() image.ShadingMode {
+	return d.shading
+}
+
+// SetExposure sets the multiplier applied to the light reflected by shaded
+// polygons drawn afterwards, before it is tone mapped
+func (d *Drawer) SetExposure(exposure float64) {
+	d.colorTransform.Exposure = exposure
+}
+
+// SetToneMap sets how light brighter than the image can show is brought into
+// range for shaded polygons drawn afterwards
+func (d *Drawer) SetToneMap(toneMap image.ToneMap) {
+	d.colorTransform.ToneMap = toneMap
+}
+
+// SetLinearLighting sets whether shaded polygons drawn afterwards are lit in
+// linear space and encoded as sRGB
+func (d *Drawer) SetLinearLighting(linear bool) {
+	d.colorTransform.Linear = linear
+}
+
+// SetDepthEpsilon sets the minimum depth difference needed to overwrite a pixel
+func (d *Drawer) SetDepthEpsilon(epsilon float64) {
+	d.frame.SetDepthEpsilon(epsilon)
+}
+
+// SetDepthOffset sets the depth offset of everything drawn afterwards
+func (d *Drawer) SetDepthOffset(offset float64) {
+	d.frame.SetDepthOffset(offset)
+}
+
+// SetLevelOfDetail makes spheres and tori pick their number of segments from
+// their size on screen, aiming for segments of the given length in pixels
+// A length of 0 always uses the number of segments set by SetQuality.
+func (d *Drawer) SetLevelOfDetail(pixels float64) {
+	d.lodPixels = pixels
+}
+
+// SetColor sets the color of lines and polygons drawn afterwards without their
+// own color or constants
+func (d *Drawer) SetColor(c image.Color) {
+	d.color = c
+}
+
+// Color returns the color of lines and polygons drawn without their own color
+// or constants
+func (d *Drawer) Color() image.Color {
+	return d.color
+}
+
+// SetBackground sets the color of the base layer where nothing is drawn,
+// filling it if nothing has been drawn yet
+func (d *Drawer) SetBackground(c image.Color) {
+	if d.background == c {
+		return
+	}
+	d.background = c
+	base := d.layers[0].Image
+	for y := 0; y < base.Height; y++ {
+		for x := 0; x < base.Width; x++ {
+			if !base.Covered(x, y) {
+				base.Frame[y][x] = c
+			}
+		}
+	}
+	d.paintSky()
+}
+
+// Clear erases everything drawn on every layer and the edge matrix, keeping
+// the coordinate system stack
+func (d *Drawer) Clear() {
+	d.clear()
+	for i, layer := range d.layers {
+		if i == 0 {
+			layer.Image.Clear(d.background)
+		} else {
+			layer.Image.Clear(image.Black)
+		}
+	}
+	d.paintSky()
+}
+
+// SetQuality sets the number of segments spheres and tori are divided into
+// when level of detail is disabled and they don't specify their own
+func (d *Drawer) SetQuality(segments int) {
+	d.segments = segments
+}
+
+// circularSteps returns the number of segments to divide a curved primitive of
+// the given radius into, unless the primitive specifies its own segments
+func (d *Drawer) circularSteps(radius float64, segments int) int {
+	if segments > 0 {
+		return segments
+	}
+	if d.lodPixels <= 0 || d.cs.IsEmpty() {
+		return d.segments
+	}
+	// Estimate the size on screen by the largest scale of the transformation
+	top := d.cs.Peek()
+	scale := 0.0
+	for c := 0; c < 3; c++ {
+		scale = math.Max(scale, math.Sqrt(top.At(0, c)*top.At(0, c)+top.At(1, c)*top.At(1, c)+top.At(2, c)*top.At(2, c)))
+	}
+	circumference := 2 * math.Pi * radius * scale
+	steps := int(math.Ceil(circumference / d.lodPixels))
+	return int(geometry.Clamp(float64(steps), MinCircularSteps, MaxCircularSteps))
+}
+
+func (d *Drawer) clear() {
+	d.em = geometry.NewMatrix(4, 0)
+}
+
+// Reset clears the image and edge matrix
+func (d *Drawer) Reset() {
+	d.clear()
+	d.cs = geometry.NewStack()
+	d.viewport = geometry.Identity()
+	d.projection = geometry.Projection{}
+	d.camera = nil
+	d.viewRect = geometry.Rect{}
+	d.shading = image.ShadingFlat
+	d.colorTransform = image.DefaultColorTransform
+	d.renderMode = RenderAuto
+	d.segments = geometry.DefaultCircularSteps
+	d.lineWidth = d.baseLineWidth
+	d.stampSize = 0
+	d.displaceAmount = 0
+	d.aoStrength = 0
+	d.environment = nil
+	d.texture = nil
+	d.debug = 0
+	d.color = image.White
+	d.culling = geometry.CullBack
+	d.winding = geometry.WindingCounterClockwise
+	d.clips = nil
+	d.near, d.far = math.Inf(1), math.Inf(-1)
+	for _, layer := range d.layers {
+		d.release(layer.Image)
+	}
+	base := &image.Layer{
+		Name:    image.BaseLayer,
+		Image:   d.pool.Get(d.frame.Height, d.frame.Width),
+		Opacity: 1,
+	}
+	base.Image.Fill(d.background)
+	d.frame = base.Image
+	d.layers = []*image.Layer{base}
+	d.enableGBuffers()
+	d.paintSky()
+	d.hidden = make(map[string]bool)
+	d.snapshots = make(map[string]drawerState)
+	d.coordinateSystems = make(map[string]geometry.Mat4)
+	d.objects = make(map[string][]recordedShape)
+}
+
+func (d *Drawer) Line(x0, y0, z0, x1, y1, z1 float64) error {
+	d.em.AddEdge(x0, y0, z0, x1, y1, z1)
+	err := d.apply()
+	return err
+}
+
+// LineBetween adds a line whose endpoints are each in a named coordinate
+// system, where "" is the current one
+func (d *Drawer) LineBetween(x0, y0, z0 float64, cs0 string, x1, y1, z1 float64, cs1 string) error {
+	points := [][]float64{{x0, y0, z0}, {x1, y1, z1}}
+	for i, cs := range []string{cs0, cs1} {
+		transform, err := d.coordinateTransform(cs)
+		if err != nil {
+			return err
+		}
+		point := transform.MulVec4(geometry.Vec4{points[i][0], points[i][1], points[i][2], 1})
+		d.em.AddColumn(point[:])
+	}
+	return nil
+}
+
+// Instance draws an object in the current coordinate system
+// The first instance of an object in a frame records the geometry that record
+// draws, which later instances reuse.
+func (d *Drawer) Instance(name string, record func() error) error {
+	shapes, found := d.objects[name]
+	if !found {
+		if d.recorded[name] {
+			return fmt.Errorf("object %s instances itself", name)
+		}
+		var err error
+		shapes, err = d.record(name, record)
+		if err != nil {
+			return err
+		}
+		d.objects[name] = shapes
+	}
+	transform := d.transform()
+	mirrored := transform.Mirrors()
+	source := d.source
+	defer func() {
+		d.source = source
+	}()
+	for _, shape := range shapes {
+		d.em = transform.Apply(shape.em)
+		d.mirrored = shape.mirrored != mirrored
+		d.source = fmt.Sprintf("%s in object %s", shape.source, name)
+		if err := shape.draw(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// record returns the shapes that draw draws in an object's own coordinate
+// system, without drawing them
+func (d *Drawer) record(name string, draw func() error) ([]recordedShape, error) {
+	cs, clips, viewport, recording := d.cs, d.clips, d.viewport, d.recording
+	d.cs = geometry.NewStack()
+	d.cs.Push(geometry.Identity())
+	d.clips = [][]geometry.Plane{nil}
+	d.viewport = geometry.Identity()
+	d.recording = &[]recordedShape{}
+	d.recorded[name] = true
+	defer func() {
+		d.cs, d.clips, d.viewport, d.recording = cs, clips, viewport, recording
+		delete(d.recorded, name)
+	}()
+	err := draw()
+	return *d.recording, err
+}
+
+// recordShape records the edge matrix as a shape of the object being recorded
+func (d *Drawer) recordShape(draw func() error) error {
+	*d.recording = append(*d.recording, recordedShape{em: d.em, draw: draw, source: d.source, mirrored: d.mirrored})
+	d.clear()
+	return nil
+}
+
+// SaveCoordinateSystem saves a copy of the current coordinate system under a
+// name, so that shapes can be drawn in it later
+func (d *Drawer) SaveCoordinateSystem(name string) error {
+	if d.cs.IsEmpty() {
+		return errors.New("savecs requires a coordinate system: push first")
+	}
+	d.coordinateSystems[name] = d.cs.Peek()
+	return nil
+}
+
+// InCoordinateSystem adds a shape in a named coordinate system, where "" is
+// the current one
+func (d *Drawer) InCoordinateSystem(name string, add func() error) error {
+	if name == "" {
+		return add()
+	}
+	cs, found := d.coordinateSystems[name]
+	if !found {
+		return fmt.Errorf("undefined coordinate system '%s'", name)
+	}
+	d.cs.Push(cs)
+	defer d.cs.Pop()
+	return add()
+}
+
+// coordinateTransform returns the transformation from a named coordinate
+// system, where "" is the current one, to the image
+func (d *Drawer) coordinateTransform(name string) (geometry.Mat4, error) {
+	if name == "" {
+		return d.transform(), nil
+	}
+	cs, found := d.coordinateSystems[name]
+	if !found {
+		return geometry.Mat4{}, fmt.Errorf("undefined coordinate system '%s'", name)
+	}
+	return d.viewport.Mul(cs), nil
+}
+
+func (d *Drawer) Scale(sx, sy, sz float64) error {
+	dilation := geometry.MakeDilation(sx, sy, sz)
+
+	top := d.cs.Pop()
+	d.cs.Push(top.Mul(dilation))
+	return nil
+}
+
+func (d *Drawer) Move(x, y, z float64) error {
+	translation := geometry.MakeTranslation(x, y, z)
+	top := d.cs.Pop()
+	d.cs.Push(top.Mul(translation))
+	return nil
+}
+
+// AboutPivot applies a transformation of the current coordinate system so
+// that it leaves the pivot point in place instead of the origin
+// A nil pivot applies the transformation about the origin.
+func (d *Drawer) AboutPivot(pivot []float64, transform func() error) error {
+	if pivot == nil {
+		return transform()
+	}
+	if err := d.Move(pivot[0], pivot[1], pivot[2]); err != nil {
+		return err
+	}
+	if err := transform(); err != nil {
+		return err
+	}
+	return d.Move(-pivot[0], -pivot[1], -pivot[2])
+}
+
+func (d *Drawer) Rotate(axis string, theta float64) error {
+	theta = geometry.DegreesToRadians(theta)
+	var rotation geometry.Mat4
+	switch axis {
+	case "x":
+		rotation = geometry.MakeRotX(theta)
+	case "y":
+		rotation = geometry.MakeRotY(theta)
+	case "z":
+		rotation = geometry.MakeRotZ(theta)
+	default:
+		return errors.New("axis must be \"x\", \"y\", or \"z\"")
+	}
+
+	top := d.cs.Pop()
+	d.cs.Push(top.Mul(rotation))
+	return nil
+}
+
+// RotateBy rotates the current coordinate system by a quaternion
+func (d *Drawer) RotateBy(q geometry.Quaternion) {
+	top := d.cs.Pop()
+	d.cs.Push(top.Mul(q.Matrix()))
+}
+
+// RotateAbout rotates the current coordinate system by theta degrees about an
+// arbitrary axis through its origin
+func (d *Drawer) RotateAbout(ax, ay, az, theta float64) error {
+	rotation, err := geometry.MakeRotArbitrary(ax, ay, az, geometry.DegreesToRadians(theta))
+	if err != nil {
+		return err
+	}
+	top := d.cs.Pop()
+	d.cs.Push(top.Mul(rotation))
+	return nil
+}
+
+// Transform applies an arbitrary transformation matrix to the current
+// coordinate system
+func (d *Drawer) Transform(m geometry.Mat4) error {
+	top := d.cs.Pop()
+	d.cs.Push(top.Mul(m))
+	return nil
+}
+
+// Mirror reflects the current coordinate system across the plane where the x,
+// y, or z coordinate is offset
+func (d *Drawer) Mirror(axis string, offset float64) error {
+	reflection, err := geometry.MakeMirror(axis, offset)
+	if err != nil {
+		return err
+	}
+	return d.Transform(reflection)
+}
+
+func (d *Drawer) Save(filename string) error {
+	return d.save(filename, d.paletted)
+}
+
+// SaveSVG saves the lines and polygons drawn onto every layer as SVG paths,
+// over the background color
+// Polygons are filled with a single color, so those lit per pixel are lit
+// once at their center, and later paths cover earlier ones regardless of depth.
+func (d *Drawer) SaveSVG(filename string) error {
+	if d.offscreen() {
+		return nil
+	}
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	base := d.layers[0].Image
+	w := bufio.NewWriter(f)
+	fmt.Fprintf(w, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`+"\n",
+		base.Width, base.Height, base.Width, base.Height)
+	paint, _ := image.SvgColor(d.background)
+	fmt.Fprintf(w, `<rect width="%d" height="%d" fill="%s"/>`+"\n", base.Width, base.Height, paint)
+	for i, layer := range d.layers {
+		if i == 0 {
+			image.WriteSVGPaths(w, layer.Image)
+			continue
+		}
+		fmt.Fprintf(w, `<g opacity="%.3g"`, layer.Opacity)
+		if mode, found := image.SvgBlendModes[layer.Mode]; found {
+			fmt.Fprintf(w, ` style="mix-blend-mode:%s"`, mode)
+		}
+		fmt.Fprintln(w, ">")
+		image.WriteSVGPaths(w, layer.Image)
+		fmt.Fprintln(w, "</g>")
+	}
+	fmt.Fprintln(w, "</svg>")
+	return w.Flush()
+}
+
+// SaveStill saves the image like Save, but in full color even when it is a
+// frame of an animation that is assembled into a gif
+func (d *Drawer) SaveStill(filename string) error {
+	return d.save(filename, false)
+}
+
+func (d *Drawer) save(filename string, paletted bool) error {
+	if d.offscreen() {
+		return nil
+	}
+	if strings.HasSuffix(filename, ".svg") {
+		return d.SaveSVG(filename)
+	}
+	frame := d.Output(paletted || strings.HasSuffix(filename, ".gif"))
+	if err := frame.Save(filename); err != nil {
+		return err
+	}
+	if d.passes {
+		return d.savePasses(filename)
+	}
+	return nil
+}
+
+// SaveDepth saves the depth of the closest pixels of every layer as a
+// grayscale image
+func (d *Drawer) SaveDepth(filename string) error {
+	if d.offscreen() {
+		return nil
+	}
+	merged := d.layers[0].Image.Copy()
+	for _, layer := range d.layers[1:] {
+		layer.Image.Flush()
+		for y, row := range layer.Image.ZBuffer {
+			for x, z := range row {
+				merged.ZBuffer[y][x] = math.Max(merged.ZBuffer[y][x], z)
+			}
+		}
+	}
+	return merged.Depth().Save(filename)
+}
+
+// Base returns the image of the base layer, which Output returns itself when
+// there is nothing to add to it
+func (d *Drawer) Base() *image.Image {
+	return d.layers[0].Image
+}
+
+// Output returns the image as it should be written out, with statistics
+// stamped on and its colors reduced as configured
+// paletted is whether the image is being written to a palette-limited format
+func (d *Drawer) Output(paletted bool) *image.Image {
+	frame := d.composite()
+	if frame == d.layers[0].Image && (d.stats || d.stampSize > 0 || d.layout != LayoutSingle) {
+		frame = frame.Copy()
+	}
+	if d.layout != LayoutSingle {
+		d.drawPanes(frame)
+	}
+	if d.stats {
+		elapsed := time.Since(d.started)
+		frame.DrawLabel(0, fmt.Sprintf("frame %d", d.frameNum), 1)
+		frame.DrawLabel(1, fmt.Sprintf("triangles %d", d.triangles), 1)
+		frame.DrawLabel(2, fmt.Sprintf("time %.1fms", elapsed.Seconds()*1000), 1)
+	}
+	if d.stampSize > 0 {
+		frameRate := d.frameRate
+		if frameRate <= 0 {
+			frameRate = DefaultFrameRate
+		}
+		seconds := float64(d.frameNum) / frameRate
+		timecode := fmt.Sprintf("%d:%05.2f", int(seconds)/60, math.Mod(seconds, 60))
+		frame.DrawCornerLabel(d.stamp, 0, fmt.Sprintf("frame %d  %s", d.frameNum, timecode), d.stampSize)
+	}
+	levels := d.levels
+	if d.dither != image.DitherNone && paletted && levels > image.PaletteLevels {
+		levels = image.PaletteLevels
+	}
+	if levels < 256 {
+		frame = frame.Dither(d.dither, levels)
+	}
+	return frame
+}
+
+// composite returns the layers of the image blended together, each darkened
+// by ambient occlusion if it is on, which is the base layer itself if there
+// is nothing to blend or darken
+func (d *Drawer) composite() *image.Image {
+	for _, layer := range d.layers {
+		layer.Image.Flush()
+	}
+	frame := d.layers[0].Image
+	if d.aoStrength > 0 {
+		frame = frame.AmbientOcclusion(d.aoRadius, d.aoStrength)
+	} else if len(d.layers) > 1 {
+		frame = frame.Copy()
+	}
+	for _, layer := range d.layers[1:] {
+		if d.aoStrength > 0 {
+			occluded := *layer
+			occluded.Image = layer.Image.AmbientOcclusion(d.aoRadius, d.aoStrength)
+			layer = &occluded
+		}
+		frame.Composite(layer)
+	}
+	return frame
+}
+
+// offscreen returns whether nothing drawn is seen yet, because the shadow
+// maps or a pane other than the last are being rendered, or the shapes of the
+// checkpoint being resumed are being skipped
+func (d *Drawer) offscreen() bool {
+	return d.shadowPass || d.offscreenPane || d.shapes < d.resumed
+}
+
+// SetDither sets how colors are dithered when saving to palette-limited formats
+// or to fewer than 8 bits per channel
+func (d *Drawer) SetDither(mode image.DitherMode, bits int) {
+	d.dither = mode
+	d.levels = 1 << uint(bits)
+}
+
+func (d *Drawer) Display() error {
+	if d.offscreen() {
+		return nil
+	}
+	if d.preview != nil {
+		return d.ShowPreview()
+	}
+	err := d.Output(false).Display()
+	return err
+}
+
+// SetPreview sets the preview that frames are shown in, or nil for none
+func (d *Drawer) SetPreview(preview *Preview) {
+	d.preview = preview
+}
+
+// Preview returns the preview that frames are shown in, if there is one
+func (d *Drawer) Preview() *Preview {
+	return d.preview
+}
+
+// ShowPreview shows the image in its current state in the preview, if there
+// is one
+func (d *Drawer) ShowPreview() error {
+	if d.preview == nil || d.offscreen() {
+		return nil
+	}
+	return d.preview.Show(d.Output(false))
+}
+
+func (d *Drawer) Circle(cx, cy, cz, radius float64) error {
+	d.em.AddCircle(cx, cy, cz, radius)
+	err := d.apply()
+	return err
+}
+
+// Arc adds an elliptical arc around (cx, cy, cz) in the plane facing normal,
+// from the start to the end angle in degrees
+func (d *Drawer) Arc(cx, cy, cz, rx, ry, start, end float64, normal geometry.Vec3) error {
+	d.em.AddArc(geometry.Vec3{cx, cy, cz}, rx, ry, start, end, normal)
+	return d.apply()
+}
+
+func (d *Drawer) Hermite(p0, p1, r0, r1 geometry.Vec3) error {
+	d.em.AddHermite(p0, p1, r0, r1)
+	err := d.apply()
+	return err
+}
+
+func (d *Drawer) Bezier(p0, p1, p2, p3 geometry.Vec3) error {
+	d.em.AddBezier(p0, p1, p2, p3)
+	err := d.apply()
+	return err
+}
+
+// Sweep adds the surface swept by a profile moving along a path
+func (d *Drawer) Sweep(profile geometry.Profile, path geometry.Cubic) error {
+	d.em.AddSweep(profile, path, geometry.SweepSteps)
+	return d.ApplySurface()
+}
+
+// Terrain adds the surface of a heightmap stretched over width and depth from
+// (x, y, z), rising up to height
+func (d *Drawer) Terrain(x, y, z, width, depth, height float64, heights geometry.Heightmap) error {
+	d.em.AddTerrain(x, y, z, width, depth, height, heights)
+	return d.ApplySurface()
+}
+
+// Metaballs adds the triangles of the surface of metaballs
+func (d *Drawer) Metaballs(surface *geometry.Matrix) error {
+	d.em.AddMatrix(surface)
+	return d.ApplySurface()
+}
+
+// LSystem adds the segments drawn by a turtle, as lines if radius is 0 or as
+// branches of that radius otherwise
+func (d *Drawer) LSystem(segments [][2]geometry.Vec3, radius float64) error {
+	d.em.AddBranches(segments, radius)
+	if radius == 0 {
+		return d.apply()
+	}
+	return d.ApplySurface()
+}
+
+// Particles adds particles, as points if size is 0 or as spheres of that
+// radius otherwise
+func (d *Drawer) Particles(particles []geometry.Vec3, size float64) error {
+	if size == 0 {
+		d.em.AddParticles(particles, 0, 0)
+		return d.apply()
+	}
+	d.em.AddParticles(particles, size, d.circularSteps(size, 0))
+	return d.ApplySurface()
+}
+
+func (d *Drawer) Box(x, y, z, width, height, depth float64) error {
+	key := geometryKey{shape: "box", params: [6]float64{x, y, z, width, height, depth}}
+	err := d.addGeometry(key, func(em *geometry.Matrix) error {
+		em.AddBox(x, y, z, width, height, depth)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return d.ApplySurface()
+}
+
+// Sphere adds a sphere divided into the given number of segments, where 0
+// picks them automatically
+func (d *Drawer) Sphere(cx, cy, cz, radius float64, segments int) error {
+	segments = d.circularSteps(radius, segments)
+	key := geometryKey{shape: "sphere", params: [6]float64{cx, cy, cz, radius}, segments: segments}
+	err := d.addGeometry(key, func(em *geometry.Matrix) error {
+		em.AddSphere(cx, cy, cz, radius, segments)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return d.ApplySurface()
+}
+
+// Torus adds a torus divided into the given number of segments, where 0 picks
+// them automatically
+func (d *Drawer) Torus(cx, cy, cz, r1, r2 float64, segments int) error {
+	segments = d.circularSteps(r1+r2, segments)
+	key := geometryKey{shape: "torus", params: [6]float64{cx, cy, cz, r1, r2}, segments: segments}
+	err := d.addGeometry(key, func(em *geometry.Matrix) error {
+		em.AddTorus(cx, cy, cz, r1, r2, segments)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return d.ApplySurface()
+}
+
+func (d *Drawer) Pop() {
+	d.cs.Pop()
+	if len(d.clips) > 0 {
+		d.clips = d.clips[:len(d.clips)-1]
+	}
+}
+
+func (d *Drawer) Push() {
+	d.cs.Push(d.cs.Peek())
+	// Clipping planes are inherited from the previous coordinate system
+	planes := make([]geometry.Plane, len(d.clipPlanes()))
+	copy(planes, d.clipPlanes())
+	d.clips = append(d.clips, planes)
+}
+
+// Snapshot saves the coordinate system stack and the image of the current layer
+// under a name
+func (d *Drawer) Snapshot(name string) {
+	d.snapshots[name] = drawerState{
+		frame: d.frame.Copy(),
+		cs:    d.cs.Copy(),
+		clips: copyClips(d.clips),
+	}
+}
+
+// Restore replaces the coordinate system stack and the image of the current
+// layer with a snapshot
+func (d *Drawer) Restore(name string) error {
+	state, found := d.snapshots[name]
+	if !found {
+		return fmt.Errorf("undefined snapshot '%s'", name)
+	}
+	// The snapshot replaces the image of the current layer
+	restored := state.frame.Copy()
+	for _, layer := range d.layers {
+		if layer.Image == d.frame {
+			layer.Image = restored
+		}
+	}
+	d.frame = restored
+	d.cs = state.cs.Copy()
+	d.clips = copyClips(state.clips)
+	return nil
+}
+
+// UseLayer makes subsequent drawing happen on the named layer, creating it
+// above the existing layers if needed, and sets how it is composited
+func (d *Drawer) UseLayer(name string, mode image.BlendMode, opacity float64) {
+	for _, layer := range d.layers {
+		if layer.Name == name {
+			layer.Mode = mode
+			layer.Opacity = opacity
+			d.frame = layer.Image
+			return
+		}
+	}
+	img := d.pool.Get(d.frame.Height, d.frame.Width)
+	img.SetDepthEpsilon(d.frame.ZEpsilon)
+	img.SetDepthOffset(d.frame.ZOffset)
+	if d.keepsGBuffer() && img.GBuffer == nil {
+		img.EnableGBuffer()
+	}
+	d.layers = append(d.layers, &image.Layer{
+		Name:    name,
+		Image:   img,
+		Mode:    mode,
+		Opacity: opacity,
+	})
+	d.frame = img
+}
+
+// Hide stops a group from being drawn
+func (d *Drawer) Hide(group string) {
+	d.hidden[group] = true
+}
+
+// Show allows a hidden group to be drawn again
+func (d *Drawer) Show(group string) {
+	delete(d.hidden, group)
+}
+
+// IsHidden returns whether a group is hidden
+func (d *Drawer) IsHidden(group string) bool {
+	return d.hidden[group]
+}
+
+func (d *Drawer) AddPoint(x, y, z float64) {
+	d.em.AddPoint(x, y, z)
+}
+
+func copyClips(clips [][]geometry.Plane) [][]geometry.Plane {
+	copied := make([][]geometry.Plane, len(clips))
+	for i, planes := range clips {
+		copied[i] = append([]geometry.Plane(nil), planes...)
+	}
+	return copied
+}
+
+// release puts the image of a layer that is no longer drawn on into the pool,
+// unless a pane that is still to be copied into the image holds it
+func (d *Drawer) release(img *image.Image) {
+	for _, finished := range d.finishedPanes {
+		if finished.image == img {
+			return
+		}
+	}
+	d.pool.Put(img)
+}
@@ -0,0 +1,48 @@
+package render
+
+import (
+	"github.com/james9909/graphics-engine/geometry"
+)
+
+// MaxCachedGeometry is the most tessellated primitives and meshes a drawer
+// keeps for reuse, so that animating their parameters doesn't grow the cache
+// without bound
+const MaxCachedGeometry = 256
+
+// geometryKey identifies a primitive by its shape and the parameters it was
+// tessellated with, or a mesh by its filename
+type geometryKey struct {
+	shape    string
+	params   [6]float64
+	segments int
+	filename string
+}
+
+// addGeometry adds the points of the geometry identified by key to the edge
+// matrix, calling generate to create them only if they aren't cached
+// Geometry is cached before it is transformed, so it can be reused by every
+// frame that draws the same primitive, however it is moved.
+func (d *Drawer) addGeometry(key geometryKey, generate func(em *geometry.Matrix) error) error {
+	em, found := d.geometry[key]
+	if !found {
+		em = geometry.NewMatrix(4, 0)
+		if err := generate(em); err != nil {
+			return err
+		}
+		if len(d.geometry) >= MaxCachedGeometry {
+			d.geometry = make(map[geometryKey]*geometry.Matrix)
+		}
+		d.geometry[key] = em
+	}
+	d.em.AddMatrix(em)
+	return nil
+}
+
+// Mesh adds the vertices of the triangles of a mesh file, loading the file
+// only the first time it is drawn
+func (d *Drawer) Mesh(filename string) error {
+	key := geometryKey{shape: "mesh", filename: filename}
+	return d.addGeometry(key, func(em *geometry.Matrix) error {
+		return em.AddMesh(filename)
+	})
+}
@@ -0,0 +1,257 @@
+package render
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/james9909/graphics-engine/geometry"
+	"github.com/james9909/graphics-engine/image"
+)
+
+// PaneFieldOfView is the vertical field of view in degrees of panes that see
+// the scene in perspective when the script doesn't
+const PaneFieldOfView = 45
+
+// StereoSeparation is the distance between the eyes of stereo views, as a
+// fraction of the width of the image
+const StereoSeparation = 1.0 / 30
+
+// Layout defines how each frame is drawn in panes, which each render the scene
+// seen a different way
+type Layout int
+
+const (
+	// LayoutSingle draws each frame once
+	LayoutSingle Layout = iota
+	// LayoutQuad draws the front, top, side, and a perspective of the scene
+	// in each quarter of the image
+	LayoutQuad
+	// LayoutSideBySide draws the scene seen by the left eye in the left half
+	// of the image and by the right eye in the right half, squeezed to fit
+	LayoutSideBySide
+	// LayoutAnaglyph draws the scene seen by the left eye in red over the
+	// scene seen by the right eye in green and blue
+	LayoutAnaglyph
+)
+
+// ParseStereoLayout returns the stereo layout with the given name
+func ParseStereoLayout(name string) (Layout, error) {
+	switch name {
+	case "sbs":
+		return LayoutSideBySide, nil
+	case "anaglyph":
+		return LayoutAnaglyph, nil
+	}
+	return LayoutSingle, fmt.Errorf("unknown stereo layout %q", name)
+}
+
+// Pane is a part of the image that the scene is drawn in, seen a different way
+// than in the other panes
+type Pane struct {
+	name       string               // label drawn on the pane, if any
+	view       geometry.Mat4        // turns the scene about the center of the image
+	projection *geometry.Projection // takes the place of the script's projection, if any
+	eye        float64              // how far right of the center of the image the eye is
+	rect       geometry.Rect
+	channels   [3]bool // color channels the pane is drawn in
+}
+
+// finishedPane is a pane that has already been drawn, which is copied into
+// the image when it is written out
+type finishedPane struct {
+	pane  Pane
+	image *image.Image
+}
+
+// Panes returns the panes that the layout draws an image in, or nil if it is
+// drawn once
+func (l Layout) Panes(height, width int) []Pane {
+	switch l {
+	case LayoutQuad:
+		return quadPanes(height, width)
+	case LayoutSideBySide, LayoutAnaglyph:
+		return stereoPanes(height, width, l)
+	}
+	return nil
+}
+
+// quadPanes returns the panes of the quad view of an image, laid out like a
+// technical drawing: the front in the bottom left, the top above it, the right
+// side beside it, and a perspective from above and to the right in the
+// remaining corner
+func quadPanes(height, width int) []Pane {
+	cx, cy := float64(width)/2, float64(height)/2
+	about := func(rotation geometry.Mat4) geometry.Mat4 {
+		return geometry.MakeTranslation(cx, cy, 0).Mul(rotation).Mul(geometry.MakeTranslation(-cx, -cy, 0))
+	}
+	// Split odd sizes unevenly, so the panes cover every pixel
+	left, bottom := float64(width/2), float64(height/2)
+	right, top := float64(width)-left, float64(height)-bottom
+	all := [3]bool{true, true, true}
+	return []Pane{
+		{
+			name:       "front",
+			view:       geometry.Identity(),
+			projection: &geometry.Projection{},
+			rect:       geometry.Rect{X: 0, Y: 0, W: left, H: bottom},
+			channels:   all,
+		},
+		{
+			name:       "top",
+			view:       about(geometry.MakeRotX(geometry.DegreesToRadians(90))),
+			projection: &geometry.Projection{},
+			rect:       geometry.Rect{X: 0, Y: bottom, W: left, H: top},
+			channels:   all,
+		},
+		{
+			name:       "side",
+			view:       about(geometry.MakeRotY(geometry.DegreesToRadians(-90))),
+			projection: &geometry.Projection{},
+			rect:       geometry.Rect{X: left, Y: 0, W: right, H: bottom},
+			channels:   all,
+		},
+		{
+			name: "perspective",
+			// Stand back from the scene, so that less of it is too close
+			// to see whole
+			view: geometry.MakeTranslation(0, 0, -cy).Mul(about(geometry.MakeRotX(geometry.DegreesToRadians(25)).Mul(geometry.MakeRotY(geometry.DegreesToRadians(-35))))),
+			projection: &geometry.Projection{
+				Perspective: true,
+				FOV:         PaneFieldOfView,
+				Near:        1,
+				Far:         math.Inf(1),
+			},
+			rect:     geometry.Rect{X: left, Y: bottom, W: right, H: top},
+			channels: all,
+		},
+	}
+}
+
+// stereoPanes returns the panes seen by the left and right eyes, which both
+// look at the image from either side of its center
+func stereoPanes(height, width int, l Layout) []Pane {
+	eye := float64(width) * StereoSeparation / 2
+	panes := []Pane{
+		{view: geometry.Identity(), eye: -eye, channels: [3]bool{true, true, true}},
+		{view: geometry.Identity(), eye: eye, channels: [3]bool{true, true, true}},
+	}
+	if l == LayoutSideBySide {
+		// Split odd widths unevenly, so the panes cover every pixel
+		left := float64(width / 2)
+		panes[0].rect = geometry.Rect{X: 0, Y: 0, W: left, H: float64(height)}
+		panes[1].rect = geometry.Rect{X: left, Y: 0, W: float64(width) - left, H: float64(height)}
+	} else {
+		panes[0].channels = [3]bool{true, false, false}
+		panes[1].channels = [3]bool{false, true, true}
+	}
+	return panes
+}
+
+// SetLayout sets how each frame is drawn in panes
+func (d *Drawer) SetLayout(layout Layout) {
+	d.layout = layout
+}
+
+// Panes returns the number of panes each frame is drawn in, or 0 if it is drawn
+// once
+func (d *Drawer) Panes() int {
+	return len(d.layout.Panes(d.frame.Height, d.frame.Width))
+}
+
+// BeginPane starts drawing the ith pane of each frame
+// Every pane but the last is drawn offscreen, so saving and displaying the
+// image waits for the last pane.
+func (d *Drawer) BeginPane(i int) {
+	panes := d.layout.Panes(d.frame.Height, d.frame.Width)
+	if i == 0 {
+		// The panes of the last frame have been copied into its image
+		panes := d.finishedPanes
+		d.finishedPanes = nil
+		for _, finished := range panes {
+			d.release(finished.image)
+		}
+	}
+	d.pane = &panes[i]
+	d.offscreenPane = i < len(panes)-1
+	d.paintSky()
+}
+
+// EndPane finishes drawing a pane, keeping it to be copied into the image
+// unless it was drawn onto the image itself
+func (d *Drawer) EndPane() {
+	if d.offscreenPane {
+		d.finishedPanes = append(d.finishedPanes, finishedPane{pane: *d.pane, image: d.composite()})
+	}
+	d.pane = nil
+	d.offscreenPane = false
+}
+
+// paneProjection returns the projection of the pane being drawn
+// Panes seen by an eye off the center of the image are always seen in
+// perspective, or both eyes would see the same thing.
+func (d *Drawer) paneProjection() geometry.Projection {
+	projection := d.projection
+	if d.pane == nil {
+		return projection
+	}
+	if d.pane.projection != nil {
+		projection = *d.pane.projection
+	}
+	if d.pane.eye != 0 {
+		if !projection.Perspective {
+			projection = geometry.Projection{Perspective: true, FOV: PaneFieldOfView, Near: 1, Far: math.Inf(1)}
+		}
+		projection.Offset = d.pane.eye
+	}
+	return projection
+}
+
+// drawRect returns the part of the image everything is drawn into, which is
+// the viewport placed inside the pane being drawn, if any
+func (d *Drawer) drawRect() geometry.Rect {
+	if d.pane == nil || d.pane.rect == (geometry.Rect{}) {
+		return d.viewRect
+	}
+	if d.viewRect == (geometry.Rect{}) {
+		return d.pane.rect
+	}
+	p, r := d.pane.rect, d.viewRect
+	sx, sy := p.W/float64(d.frame.Width), p.H/float64(d.frame.Height)
+	return geometry.Rect{X: p.X + r.X*sx, Y: p.Y + r.Y*sy, W: r.W * sx, H: r.H * sy}
+}
+
+// drawPanes copies the channels that the finished panes are drawn in into an
+// image, whose last pane is already drawn, and labels every pane with a name
+func (d *Drawer) drawPanes(frame *image.Image) {
+	for _, finished := range d.finishedPanes {
+		pane := finished.pane
+		if pane.rect == (geometry.Rect{}) {
+			pane.rect = geometry.Rect{X: 0, Y: 0, W: float64(frame.Width), H: float64(frame.Height)}
+		}
+		x0, y0 := int(pane.rect.X), int(pane.rect.Y)
+		for y := y0; y < y0+int(pane.rect.H); y++ {
+			row, from := frame.Frame[y], finished.image.Frame[y]
+			for x := x0; x < x0+int(pane.rect.W); x++ {
+				if pane.channels[0] {
+					row[x].R = from[x].R
+				}
+				if pane.channels[1] {
+					row[x].G = from[x].G
+				}
+				if pane.channels[2] {
+					row[x].B = from[x].B
+				}
+			}
+		}
+	}
+	const padding = 2
+	lineHeight := image.GlyphHeight + 2
+	for _, pane := range d.layout.Panes(frame.Height, frame.Width) {
+		if pane.name == "" {
+			continue
+		}
+		left, top := int(pane.rect.X), int(pane.rect.Y)+lineHeight-1
+		frame.FillRect(left, top-lineHeight+1, image.TextWidth(pane.name, 1)+2*padding, lineHeight+padding, image.Black)
+		frame.DrawText(left+padding, top, pane.name, 1, image.White)
+	}
+}
@@ -0,0 +1,69 @@
+package render
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// passFilename returns the name of the image a pass of an image is written to,
+// which is the image's name with the pass before its extension
+// Passes of vector and animated images are written as png.
+func passFilename(filename, pass string) string {
+	ext := filepath.Ext(filename)
+	base := strings.TrimSuffix(filename, ext)
+	if ext == ".svg" || ext == ".gif" || ext == "" {
+		ext = ".png"
+	}
+	return base + "_" + pass + ext
+}
+
+// savePasses writes the depth, normal, and shape ID passes of the image
+// alongside it, taking each pixel from the layer drawn closest there
+func (d *Drawer) savePasses(filename string) error {
+	merged := d.layers[0].Image.Copy()
+	for _, layer := range d.layers[1:] {
+		layer.Image.Flush()
+		for y, row := range layer.Image.ZBuffer {
+			for x, z := range row {
+				if z > merged.ZBuffer[y][x] {
+					merged.ZBuffer[y][x] = z
+					merged.GBuffer.Normals[y][x] = layer.Image.GBuffer.Normals[y][x]
+					merged.GBuffer.IDs[y][x] = layer.Image.GBuffer.IDs[y][x]
+				}
+			}
+		}
+	}
+	if err := merged.Depth().Save(passFilename(filename, "depth")); err != nil {
+		return err
+	}
+	if err := merged.Normals().Save(passFilename(filename, "normal")); err != nil {
+		return err
+	}
+	return merged.IDs().Save(passFilename(filename, "id"))
+}
+
+// SetPasses sets whether the depth, normal, and shape ID passes are written
+// alongside every image saved
+func (d *Drawer) SetPasses(passes bool) {
+	d.passes = passes
+	d.enableGBuffers()
+}
+
+// keepsGBuffer returns whether the layers keep a G-buffer, for saving passes
+// or picking
+func (d *Drawer) keepsGBuffer() bool {
+	return d.passes || d.picking
+}
+
+// enableGBuffers makes the layers keep a G-buffer if passes are saved or
+// pixels are picked
+func (d *Drawer) enableGBuffers() {
+	if !d.keepsGBuffer() {
+		return
+	}
+	for _, layer := range d.layers {
+		if layer.Image.GBuffer == nil {
+			layer.Image.EnableGBuffer()
+		}
+	}
+}
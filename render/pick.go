@@ -0,0 +1,83 @@
+package render
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Picked is what covers a pixel of the image
+type Picked struct {
+	Shape     int     // ID of the shape, counting the shapes drawn in the frame from 1, or 0 for nothing
+	Source    string  // statement that drew the shape
+	Primitive int     // triangle, or segment of lines, of the shape, counting from 0 in the order they were drawn after clipping
+	Lines     bool    // whether the shape is made of lines rather than triangles
+	Z         float64 // depth of the shape at the pixel
+}
+
+func (p Picked) String() string {
+	if p.Shape == 0 {
+		return "nothing"
+	}
+	primitive := "triangle"
+	if p.Lines {
+		primitive = "segment"
+	}
+	shape := fmt.Sprintf("shape %d", p.Shape)
+	if p.Source != "" {
+		shape += " (" + p.Source + ")"
+	}
+	return fmt.Sprintf("%s, %s %d, z %.4g", shape, primitive, p.Primitive, p.Z)
+}
+
+// drawnShape is a shape drawn in the frame
+type drawnShape struct {
+	source string // statement that drew it
+	lines  bool   // whether it is made of lines rather than triangles
+}
+
+// SetPicking sets whether what covers each pixel is kept, so that Pick can
+// report it
+func (d *Drawer) SetPicking(picking bool) {
+	d.picking = picking
+	d.enableGBuffers()
+}
+
+// SetSource sets the statement that draws the shapes drawn afterwards, which
+// Pick reports
+func (d *Drawer) SetSource(source string) {
+	d.source = source
+}
+
+// Pick returns what was drawn closest at a pixel of the image, across every
+// layer, where (0, 0) is the bottom left corner
+// Translucent shapes don't cover what is behind them, so they are never
+// picked.
+func (d *Drawer) Pick(x, y int) (Picked, error) {
+	if !d.keepsGBuffer() {
+		return Picked{}, errors.New("pick needs picking to be enabled before drawing")
+	}
+	base := d.layers[0].Image
+	if x < 0 || x >= base.Width || y < 0 || y >= base.Height {
+		return Picked{}, fmt.Errorf("pixel (%d, %d) is outside of the %dx%d image", x, y, base.Width, base.Height)
+	}
+	var picked Picked
+	closest := base.ZBuffer[y][x]
+	for i, layer := range d.layers {
+		image := layer.Image
+		image.Flush()
+		if image.GBuffer == nil || (i > 0 && image.ZBuffer[y][x] <= closest) {
+			continue
+		}
+		closest = image.ZBuffer[y][x]
+		picked = Picked{
+			Shape:     image.GBuffer.IDs[y][x],
+			Primitive: image.GBuffer.Primitives[y][x],
+			Z:         closest,
+		}
+	}
+	if picked.Shape > 0 && picked.Shape <= len(d.drawn) {
+		picked.Source = d.drawn[picked.Shape-1].source
+		picked.Lines = d.drawn[picked.Shape-1].lines
+	}
+	return picked, nil
+}
@@ -0,0 +1,121 @@
+package render
+
+import (
+	"bytes"
+	"fmt"
+	"image/png"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/james9909/graphics-engine/image"
+)
+
+// previewPage shows the latest frame, fetching each new one as soon as it is
+// rendered
+const previewPage = `<!DOCTYPE html>
+<html>
+<head><title>Preview</title></head>
+<body style="margin:0;background:#222;display:flex;justify-content:center;align-items:center;height:100vh">
+<img id="frame" style="image-rendering:pixelated">
+<script>
+let version = -1;
+const img = document.getElementById("frame");
+async function poll() {
+	try {
+		const response = await fetch("/frame?after=" + version);
+		version = parseInt(response.headers.get("X-Version"));
+		const url = URL.createObjectURL(await response.blob());
+		img.onload = () => URL.revokeObjectURL(url);
+		img.src = url;
+	} catch (e) {
+		await new Promise(resolve => setTimeout(resolve, 1000));
+	}
+	poll();
+}
+poll();
+</script>
+</body>
+</html>
+`
+
+// Preview shows rendered frames in a web browser as they are rendered, so that
+// it works without a display attached to the machine doing the rendering
+type Preview struct {
+	mu      sync.Mutex
+	frame   []byte        // latest frame encoded as a png
+	version int           // number of frames shown so far
+	updated chan struct{} // closed when the next frame is shown
+}
+
+// NewPreview returns a Preview with no frame shown yet
+func NewPreview() *Preview {
+	return &Preview{updated: make(chan struct{})}
+}
+
+// Listen starts serving the preview over HTTP on address, returning once the
+// address is being listened on
+func (p *Preview) Listen(address string) error {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return err
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", p.servePage)
+	mux.HandleFunc("/frame", p.ServeFrame)
+	fmt.Printf("Previewing at http://%s\n", listener.Addr())
+	go http.Serve(listener, mux)
+	return nil
+}
+
+// Show replaces the frame being previewed with img
+func (p *Preview) Show(img *image.Image) error {
+	var encoded bytes.Buffer
+	if err := png.Encode(&encoded, img); err != nil {
+		return err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.frame = encoded.Bytes()
+	p.version++
+	close(p.updated)
+	p.updated = make(chan struct{})
+	return nil
+}
+
+func (p *Preview) servePage(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	fmt.Fprint(w, previewPage)
+}
+
+// ServeFrame responds with the latest frame once it is newer than the version
+// given by the after parameter, waiting for it to be rendered if needed
+func (p *Preview) ServeFrame(w http.ResponseWriter, r *http.Request) {
+	after, err := strconv.Atoi(r.URL.Query().Get("after"))
+	if err != nil {
+		after = -1
+	}
+	p.mu.Lock()
+	for p.frame == nil || p.version <= after {
+		updated := p.updated
+		p.mu.Unlock()
+		select {
+		case <-updated:
+		case <-r.Context().Done():
+			return
+		}
+		p.mu.Lock()
+	}
+	frame, version := p.frame, p.version
+	p.mu.Unlock()
+
+	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("X-Version", strconv.Itoa(version))
+	w.Write(frame)
+}
@@ -0,0 +1,88 @@
+package render
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/james9909/graphics-engine/geometry"
+	"github.com/james9909/graphics-engine/image"
+)
+
+// RenderTarget draws into an offscreen image of the given size instead of the
+// image, keeping it under a name for UseTexture
+// The target is drawn with the settings in effect, but with its own layers and
+// coordinate system stack, and nothing changed while drawing into it leaks out
+// of it. Nothing is drawn into targets while the shadow maps are rendered.
+func (d *Drawer) RenderTarget(name string, width, height int, draw func() error) error {
+	if d.shadowPass {
+		return nil
+	}
+	saved := *d
+	base := &image.Layer{
+		Name:    image.BaseLayer,
+		Image:   d.pool.Get(height, width),
+		Opacity: 1,
+	}
+	base.Image.SetDepthEpsilon(d.frame.ZEpsilon)
+	base.Image.Fill(d.background)
+	d.frame = base.Image
+	d.layers = []*image.Layer{base}
+	d.enableGBuffers()
+	d.clear()
+	d.cs = geometry.NewStack()
+	d.clips = nil
+	d.viewport = geometry.Identity()
+	d.viewRect = geometry.Rect{}
+	d.pane = nil
+	d.offscreenPane = false
+	d.shadows = nil
+	d.recording = nil
+	// Targets are drawn again when resuming, since only the image is saved
+	d.checkpoint = nil
+	d.resumed = 0
+	d.paintSky()
+	err := draw()
+	target := d.composite()
+	for _, layer := range d.layers {
+		if layer.Image != target {
+			d.release(layer.Image)
+		}
+	}
+	triangles := d.triangles
+	*d = saved
+	d.triangles = triangles
+	if err != nil {
+		return err
+	}
+	d.targets[name] = target
+	return nil
+}
+
+// UseTexture stretches the image drawn by a render target over the shapes
+// shaded afterwards, where "" is none
+func (d *Drawer) UseTexture(name string) error {
+	if name == "" {
+		d.texture = nil
+		return nil
+	}
+	texture, found := d.targets[name]
+	if !found {
+		return fmt.Errorf("undefined render target '%s'", name)
+	}
+	d.texture = texture
+	return nil
+}
+
+// surfaceBounds returns the smallest and largest untransformed points of the
+// shapes in an edge matrix whose points carry them
+func surfaceBounds(em *geometry.Matrix) (geometry.Vec3, geometry.Vec3) {
+	low := geometry.Vec3{math.Inf(1), math.Inf(1), math.Inf(1)}
+	high := geometry.Vec3{math.Inf(-1), math.Inf(-1), math.Inf(-1)}
+	for i := range low {
+		for _, v := range em.Data[4+i] {
+			low[i] = math.Min(low[i], v)
+			high[i] = math.Max(high[i], v)
+		}
+	}
+	return low, high
+}
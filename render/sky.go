@@ -0,0 +1,94 @@
+package render
+
+import (
+	"math"
+
+	"github.com/james9909/graphics-engine/geometry"
+	"github.com/james9909/graphics-engine/image"
+)
+
+// SkyFieldOfView is the vertical field of view in degrees that skyboxes are
+// seen through when the scene isn't seen in perspective
+const SkyFieldOfView = 60
+
+// Sky is what the image shows where nothing is drawn, in place of the
+// background color: either an environment map seen from the middle of the
+// scene, or a gradient from the top of the image to the bottom
+type Sky struct {
+	Filename    string      // skybox image, or "" for a gradient
+	Top, Bottom image.Color // colors of the gradient
+}
+
+// SetSky sets what the base layer shows where nothing is drawn, in place of
+// the background color, filling it if nothing has been drawn yet
+// The zero Sky is the background color.
+func (d *Drawer) SetSky(sky Sky) error {
+	if d.sky == sky {
+		return nil
+	}
+	d.sky = sky
+	d.skybox = nil
+	if sky.Filename != "" {
+		skybox, found := d.environments[sky.Filename]
+		if !found {
+			var err error
+			if skybox, err = image.ReadEnvironmentMap(sky.Filename); err != nil {
+				return err
+			}
+			d.environments[sky.Filename] = skybox
+		}
+		d.skybox = skybox
+	}
+	d.paintSky()
+	return nil
+}
+
+// paintSky fills the pixels of the base layer where nothing has been drawn
+// with the sky, if there is one, as it is seen in the pane being drawn
+func (d *Drawer) paintSky() {
+	if d.sky == (Sky{}) {
+		return
+	}
+	base := d.layers[0].Image
+	r := geometry.Rect{X: 0, Y: 0, W: float64(base.Width), H: float64(base.Height)}
+	if d.pane != nil && d.pane.rect != (geometry.Rect{}) {
+		r = d.pane.rect
+	}
+
+	// Each pixel of a skybox looks out from the middle of the pane, turned
+	// the opposite way that the camera and the pane turn the scene
+	fov := float64(SkyFieldOfView)
+	if projection := d.paneProjection(); projection.Perspective {
+		fov = projection.FOV
+	}
+	scale := math.Tan(geometry.DegreesToRadians(fov)/2) / (r.H / 2)
+	view := d.cameraView()
+	if d.pane != nil {
+		view = d.pane.view.Mul(view)
+	}
+	cx, cy := r.X+r.W/2, r.Y+r.H/2
+
+	for y := int(r.Y); y < int(r.Y+r.H); y++ {
+		for x := int(r.X); x < int(r.X+r.W); x++ {
+			if base.Covered(x, y) {
+				continue
+			}
+			if d.skybox == nil {
+				t := (float64(y) + 0.5 - r.Y) / r.H
+				mix := func(bottom, top byte) byte {
+					return byte(geometry.Lerp(t, float64(bottom), float64(top)) + 0.5)
+				}
+				b, u := d.sky.Bottom, d.sky.Top
+				base.Frame[y][x] = image.Color{R: mix(b.R, u.R), G: mix(b.G, u.G), B: mix(b.B, u.B), A: 255}
+				continue
+			}
+			look := geometry.Vec3{(float64(x) + 0.5 - cx) * scale, (float64(y) + 0.5 - cy) * scale, -1}
+			var direction geometry.Vec3
+			for i := range direction {
+				direction[i] = view[i]*look[0] + view[4+i]*look[1] + view[8+i]*look[2]
+			}
+			c := d.skybox.Sample(direction)
+			base.Frame[y][x] = image.Color{R: byte(geometry.Clamp(c[0]+0.5, 0, 255)), G: byte(geometry.Clamp(c[1]+0.5, 0, 255)), B: byte(geometry.Clamp(c[2]+0.5, 0, 255)), A: 255}
+		}
+	}
+}
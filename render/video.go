@@ -0,0 +1,101 @@
+package render
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sync"
+
+	"github.com/james9909/graphics-engine/image"
+)
+
+// DefaultFrameRate is the frame rate of encoded videos
+const DefaultFrameRate = 30
+
+// VideoEncoder streams raw frames over a pipe to ffmpeg
+type VideoEncoder struct {
+	cmd     *exec.Cmd
+	stdin   io.WriteCloser
+	next    int            // next frame to be written to ffmpeg
+	pending map[int][]byte // frames that finished rendering out of order
+	err     error          // first error encountered while writing
+	mu      sync.Mutex
+}
+
+// NewVideoEncoder starts an ffmpeg process that encodes frames of the given
+// dimensions into filename
+func NewVideoEncoder(filename string, height, width int, frameRate float64) (*VideoEncoder, error) {
+	cmd := exec.Command("ffmpeg",
+		"-y", "-loglevel", "error",
+		"-f", "rawvideo",
+		"-pixel_format", "rgb24",
+		"-video_size", fmt.Sprintf("%dx%d", width, height),
+		"-framerate", fmt.Sprint(frameRate),
+		"-i", "-",
+		"-pix_fmt", "yuv420p",
+		filename)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err = cmd.Start(); err != nil {
+		return nil, err
+	}
+	return &VideoEncoder{
+		cmd:     cmd,
+		stdin:   stdin,
+		pending: make(map[int][]byte),
+	}, nil
+}
+
+// WriteFrame queues a rendered frame for encoding
+// Frames may arrive in any order, but are written to ffmpeg sequentially
+func (v *VideoEncoder) WriteFrame(frame int, img *image.Image) error {
+	var buffer bytes.Buffer
+	buffer.Grow(3 * img.Width * img.Height)
+	img.WriteRaw(&buffer)
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.err != nil {
+		return v.err
+	}
+	v.pending[frame] = buffer.Bytes()
+	for {
+		data, found := v.pending[v.next]
+		if !found {
+			break
+		}
+		delete(v.pending, v.next)
+		if _, err := v.stdin.Write(data); err != nil {
+			v.err = err
+			return err
+		}
+		v.next++
+	}
+	return nil
+}
+
+// Written returns the number of frames written to ffmpeg so far
+func (v *VideoEncoder) Written() int {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.next
+}
+
+// Close flushes the pipe and waits for ffmpeg to finish encoding
+func (v *VideoEncoder) Close() error {
+	v.stdin.Close()
+	err := v.cmd.Wait()
+	if v.err != nil {
+		return v.err
+	}
+	if len(v.pending) > 0 {
+		return fmt.Errorf("%d frames were never written", len(v.pending))
+	}
+	return err
+}